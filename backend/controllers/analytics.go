@@ -514,3 +514,61 @@ func GetPlatformComparison(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(platformStats)
 }
+
+// GetBestTimesToPost returns a weekday × hour engagement heatmap per account and its top
+// N recommended posting slots, expressed in the requested (or workspace/user) time zone
+func GetBestTimesToPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		log.Println("Unauthorized:", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := utils.DefaultBestTimeLimit
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 1 || limit > 168 {
+			http.Error(w, "limit must be between 1 and 168", http.StatusBadRequest)
+			return
+		}
+	}
+	days := 90
+	if daysStr := q.Get("days"); daysStr != "" {
+		if days, err = strconv.Atoi(daysStr); err != nil || days < 1 || days > 365 {
+			http.Error(w, "days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var workspaceID *string
+	if ws := q.Get("workspace_id"); ws != "" {
+		workspaceID = &ws
+	}
+	zone, loc, err := utils.ResolveTimeZone(lib.DB, userID, workspaceID, q.Get("timezone"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	accounts, err := utils.GetBestTimesToPost(lib.DB, userID, utils.BestTimesFilter{
+		AccountIDs: q["account_id"],
+		Platforms:  q["platform"],
+		Since:      since,
+		Location:   loc,
+		Limit:      limit,
+	})
+	if err != nil {
+		log.Printf("Error computing best times to post: %v", err)
+		http.Error(w, "Failed to compute best times to post", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.BestTimesResponse{
+		TimeZone: zone,
+		Since:    since,
+		Accounts: accounts,
+	})
+}
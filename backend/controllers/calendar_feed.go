@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"social-sync-backend/lib"
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// requireCalendarPermission checks that the caller can read the workspace's posts and writes
+// the error response on failure
+func requireCalendarPermission(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+	ok, err := middleware.CheckUserPermission(userID, workspaceID, models.PermPostRead)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return "", "", false
+	}
+	if !ok {
+		http.Error(w, "You don't have permission to view this workspace's calendar", http.StatusForbidden)
+		return "", "", false
+	}
+	return userID, workspaceID, true
+}
+
+// calendarFeedURL is the subscription URL of a feed token, on the host the request came to
+func calendarFeedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + "/api/calendar/" + token + ".ics"
+}
+
+// GetCalendarFeed tells whether the caller has a calendar feed of the workspace. The URL is
+// only shown when the feed is created.
+func GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireCalendarPermission(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := utils.GetCalendarFeed(lib.DB, workspaceID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch calendar feed: %v", err)
+		http.Error(w, "Failed to fetch calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if feed == nil {
+		http.Error(w, "No calendar feed; create one first", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// CreateCalendarFeed issues the caller's secret .ics subscription URL of the workspace,
+// revoking the previous one. Add ?drafts=true and/or ?tasks=true to the URL to include
+// scheduled drafts and task due dates.
+func CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireCalendarPermission(w, r)
+	if !ok {
+		return
+	}
+
+	token, createdAt, err := utils.CreateCalendarFeedToken(lib.DB, workspaceID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create calendar feed: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CalendarFeed{
+		WorkspaceID: workspaceID,
+		URL:         calendarFeedURL(r, token),
+		CreatedAt:   createdAt,
+	})
+}
+
+// RevokeCalendarFeed stops the caller's calendar feed of the workspace from working
+func RevokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireCalendarPermission(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := utils.RevokeCalendarFeedToken(lib.DB, workspaceID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke calendar feed: %v", err)
+		http.Error(w, "Failed to revoke calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "No calendar feed to revoke", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Calendar feed revoked"})
+}
+
+// ServeCalendarFeed serves a workspace's content calendar as iCalendar to whoever holds the
+// token; calendar apps can't send a login. The token's owner must still be able to read the
+// workspace's posts, and its drafts or tasks for ?drafts=true or ?tasks=true to add them.
+func ServeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID, err := utils.LookupCalendarFeedToken(lib.DB, mux.Vars(r)["token"])
+	if err == utils.ErrCalendarFeedNotFound {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to look up calendar feed: %v", err)
+		http.Error(w, "Failed to load calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	can := func(permission string) bool {
+		ok, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+		if err != nil {
+			log.Printf("[ERROR] Failed to verify calendar feed permission %s: %v", permission, err)
+		}
+		return ok
+	}
+	if !can(models.PermPostRead) {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	opts := utils.CalendarFeedOptions{
+		Drafts: query.Get("drafts") == "true" && can(models.PermDraftRead),
+		Tasks:  query.Get("tasks") == "true" && can(models.PermTaskRead),
+	}
+
+	var name string
+	if err := lib.DB.QueryRow(`SELECT name FROM workspaces WHERE id = $1`, workspaceID).Scan(&name); err != nil {
+		log.Printf("[ERROR] Failed to load workspace of calendar feed: %v", err)
+		http.Error(w, "Failed to load calendar feed", http.StatusInternalServerError)
+		return
+	}
+	events, err := utils.CalendarEvents(lib.DB, workspaceID, opts)
+	if err != nil {
+		log.Printf("[ERROR] Failed to build calendar feed: %v", err)
+		http.Error(w, "Failed to load calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="content-calendar.ics"`)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(utils.RenderCalendar(name+" content calendar", events))
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"social-sync-backend/lib"
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// broadcastDraftStatus tells workspace clients that a draft moved through the approval workflow
+func broadcastDraftStatus(workspaceID, draftID, status, userID string) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":       "draft_status_changed",
+		"draft_id":   draftID,
+		"status":     status,
+		"changed_by": userID,
+		"changed_at": time.Now(),
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// GetApprovalSettings returns whether the workspace requires approvals and who the approvers are
+func GetApprovalSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+
+	if ok, err := middleware.CheckUserPermission(userID, workspaceID, models.PermDraftRead); err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "You don't have permission to view this workspace", http.StatusForbidden)
+		return
+	}
+
+	settings, err := utils.GetApprovalSettings(lib.DB, workspaceID)
+	if err != nil {
+		log.Printf("approval settings fetch failed: %v", err)
+		http.Error(w, "Failed to fetch approval settings", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateApprovalSettings enables or disables approvals and replaces the required approvers
+func UpdateApprovalSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+
+	if ok, err := middleware.CheckUserPermission(userID, workspaceID, models.PermWorkspaceUpdate); err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "You don't have permission to change approval settings", http.StatusForbidden)
+		return
+	}
+
+	var req models.UpdateApprovalSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := utils.UpdateApprovalSettings(lib.DB, workspaceID, req)
+	if err != nil {
+		http.Error(w, "Failed to update approval settings: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":     "approval_settings_updated",
+		"settings": settings,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// GetDraftApprovals returns a draft's approval requirements and its approval log
+func GetDraftApprovals(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	draftID := mux.Vars(r)["draftId"]
+
+	var workspaceID, status string
+	err := lib.DB.QueryRow(`SELECT workspace_id, status FROM draft_posts WHERE id = $1`, draftID).Scan(&workspaceID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Draft not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to find draft", http.StatusInternalServerError)
+		}
+		return
+	}
+	if ok, err := middleware.CheckUserPermission(userID, workspaceID, models.PermDraftRead); err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "You don't have permission to view drafts", http.StatusForbidden)
+		return
+	}
+
+	approval, err := utils.GetDraftApprovalStatus(lib.DB, draftID, workspaceID, status)
+	if err != nil {
+		log.Printf("draft approval fetch failed: %v", err)
+		http.Error(w, "Failed to fetch approvals", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(approval)
+}
+
+// SubmitDraftForReview moves a draft (or one with requested changes) into review
+func SubmitDraftForReview(w http.ResponseWriter, r *http.Request) {
+	reviewDraft(w, r, models.ApprovalActionSubmit, models.PermDraftUpdate)
+}
+
+// ApproveDraft records an approval; the draft is approved once every required approver has signed off
+func ApproveDraft(w http.ResponseWriter, r *http.Request) {
+	reviewDraft(w, r, models.ApprovalActionApprove, models.PermDraftRead)
+}
+
+// RequestDraftChanges sends a draft back to its author with a (required) comment
+func RequestDraftChanges(w http.ResponseWriter, r *http.Request) {
+	reviewDraft(w, r, models.ApprovalActionRequestChanges, models.PermDraftRead)
+}
+
+// reviewDraft applies an approval action after checking the caller's draft permission
+func reviewDraft(w http.ResponseWriter, r *http.Request, action, permission string) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	draftID := mux.Vars(r)["draftId"]
+
+	var workspaceID string
+	err := lib.DB.QueryRow(`SELECT workspace_id FROM draft_posts WHERE id = $1`, draftID).Scan(&workspaceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Draft not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to find draft", http.StatusInternalServerError)
+		}
+		return
+	}
+	if ok, err := middleware.CheckUserPermission(userID, workspaceID, permission); err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "You don't have permission to review drafts", http.StatusForbidden)
+		return
+	}
+
+	var req models.DraftReviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Without configured approvers, anyone allowed to publish may review
+	canPublish, _ := middleware.CheckUserPermission(userID, workspaceID, models.PermPostPublish)
+
+	status, err := utils.ReviewDraft(lib.DB, draftID, userID, action, req.Comment, canPublish)
+	switch err {
+	case nil:
+	case utils.ErrNotApprover:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case utils.ErrInvalidApprovalTransition, utils.ErrAlreadyApproved, utils.ErrApprovalsDisabled:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, "Failed to review draft: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"draft_id": draftID,
+		"action":   action,
+		"status":   status,
+	})
+
+	broadcastDraftStatus(workspaceID, draftID, status, userID)
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"io"
+	"log"
 	"net/http"
 	"social-sync-backend/lib"
 	"social-sync-backend/middleware"
 	"social-sync-backend/models"
+	"social-sync-backend/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -46,6 +48,24 @@ func CreateDraftPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	draft, err := insertDraftPost(workspaceID, userID, req.Content, req.Media, req.Platforms, req.ScheduledTime)
+	if err != nil {
+		http.Error(w, "Failed to create draft", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(draft)
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":  "draft_created",
+		"draft": draft,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// insertDraftPost stores a new draft of the workspace, authored by userID
+func insertDraftPost(workspaceID, userID, content string, media, platforms []string, scheduledTime *time.Time) (models.DraftPost, error) {
 	draftID := uuid.NewString()
 	now := time.Now()
 	status := "draft"
@@ -53,33 +73,23 @@ func CreateDraftPost(w http.ResponseWriter, r *http.Request) {
 	_, err := lib.DB.Exec(`
         INSERT INTO draft_posts (id, workspace_id, created_by, content, media, platforms, status, scheduled_time, created_at, updated_at, last_updated_by)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-    `, draftID, workspaceID, userID, req.Content, pqStringArrayToJSONB(req.Media), pqStringArray(req.Platforms), status, req.ScheduledTime, now, now, userID)
+    `, draftID, workspaceID, userID, content, pqStringArrayToJSONB(media), pqStringArray(platforms), status, scheduledTime, now, now, userID)
 	if err != nil {
-		http.Error(w, "Failed to create draft", http.StatusInternalServerError)
-		return
+		return models.DraftPost{}, err
 	}
 
-	draft := models.DraftPost{
+	return models.DraftPost{
 		ID:            draftID,
 		WorkspaceID:   workspaceID,
 		CreatedBy:     userID,
-		Content:       req.Content,
-		Media:         req.Media,
-		Platforms:     req.Platforms,
+		Content:       content,
+		Media:         media,
+		Platforms:     platforms,
 		Status:        status,
-		ScheduledTime: req.ScheduledTime,
+		ScheduledTime: scheduledTime,
 		CreatedAt:     now,
 		UpdatedAt:     now,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(draft)
-
-	msg, _ := json.Marshal(map[string]interface{}{
-		"type":  "draft_created",
-		"draft": draft,
-	})
-	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+	}, nil
 }
 
 // ListDraftPosts lists all draft posts for a workspace
@@ -90,10 +100,12 @@ func ListDraftPosts(w http.ResponseWriter, r *http.Request) {
 	rows, err := lib.DB.Query(`
         SELECT d.id, d.workspace_id, d.created_by, d.content, d.media, d.platforms, d.status, d.scheduled_time, d.published_time, d.created_at, d.updated_at, d.last_updated_by,
                u.id, u.name, u.email, u.profile_picture,
-               lu.name as last_updated_by_name, lu.profile_picture as last_updated_by_avatar
+               lu.name as last_updated_by_name, lu.profile_picture as last_updated_by_avatar,
+               d.scheduled_post_id, sp.status, sp.scheduled_time, sp.error_message
         FROM draft_posts d
         LEFT JOIN users u ON d.created_by = u.id
         LEFT JOIN users lu ON d.last_updated_by = lu.id
+        LEFT JOIN scheduled_posts sp ON d.scheduled_post_id = sp.id
         WHERE d.workspace_id = $1 ORDER BY d.created_at DESC
     `, workspaceID)
 	if err != nil {
@@ -117,7 +129,9 @@ func ListDraftPosts(w http.ResponseWriter, r *http.Request) {
 		var authorID, authorName, authorEmail, authorAvatar *string
 		var lastUpdatedBy *string
 		var lastUpdatedByName, lastUpdatedByAvatar *string
-		if err := rows.Scan(&d.ID, &d.WorkspaceID, &d.CreatedBy, &d.Content, &mediaJSON, &platforms, &d.Status, &d.ScheduledTime, &d.PublishedTime, &d.CreatedAt, &d.UpdatedAt, &lastUpdatedBy, &authorID, &authorName, &authorEmail, &authorAvatar, &lastUpdatedByName, &lastUpdatedByAvatar); err != nil {
+		var postStatus, postError *string
+		var postTime *time.Time
+		if err := rows.Scan(&d.ID, &d.WorkspaceID, &d.CreatedBy, &d.Content, &mediaJSON, &platforms, &d.Status, &d.ScheduledTime, &d.PublishedTime, &d.CreatedAt, &d.UpdatedAt, &lastUpdatedBy, &authorID, &authorName, &authorEmail, &authorAvatar, &lastUpdatedByName, &lastUpdatedByAvatar, &d.ScheduledPostID, &postStatus, &postTime, &postError); err != nil {
 			continue
 		}
 		d.Media = jsonBytesToStringSlice(mediaJSON)
@@ -149,6 +163,15 @@ func ListDraftPosts(w http.ResponseWriter, r *http.Request) {
 		if lastUpdatedByAvatar != nil {
 			m["last_updated_by_avatar"] = *lastUpdatedByAvatar
 		}
+		// Delivery state of the scheduled post the draft was published through
+		if d.ScheduledPostID != nil && postStatus != nil {
+			m["scheduled_post"] = map[string]interface{}{
+				"id":             *d.ScheduledPostID,
+				"status":         *postStatus,
+				"scheduled_time": postTime,
+				"error_message":  postError,
+			}
+		}
 		drafts = append(drafts, m)
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -186,8 +209,8 @@ func UpdateDraftPost(w http.ResponseWriter, r *http.Request) {
 	draftID := vars["draftId"]
 
 	// Find workspace for permission check
-	var workspaceID string
-	if err := lib.DB.QueryRow(`SELECT workspace_id FROM draft_posts WHERE id = $1`, draftID).Scan(&workspaceID); err != nil {
+	var workspaceID, currentStatus string
+	if err := lib.DB.QueryRow(`SELECT workspace_id, status FROM draft_posts WHERE id = $1`, draftID).Scan(&workspaceID, &currentStatus); err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Draft not found", http.StatusNotFound)
 		} else {
@@ -219,6 +242,13 @@ func UpdateDraftPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// With approvals enabled the status only moves through the review endpoints
+	approvals := utils.ApprovalsEnabled(lib.DB, workspaceID)
+	if approvals && req.Status != nil && *req.Status != currentStatus {
+		http.Error(w, "Draft status is managed by the approval workflow", http.StatusConflict)
+		return
+	}
+
 	setClauses := []string{}
 	args := []interface{}{}
 	argIdx := 1
@@ -265,6 +295,19 @@ func UpdateDraftPost(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update draft", http.StatusInternalServerError)
 		return
 	}
+
+	// Edited content has to be reviewed again
+	if approvals && (req.Content != nil || req.Media != nil || req.Platforms != nil) {
+		newStatus, err := utils.ResetDraftReview(lib.DB, draftID, workspaceID, userID, currentStatus)
+		if err != nil {
+			http.Error(w, "Failed to reset draft review: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if newStatus != currentStatus {
+			broadcastDraftStatus(workspaceID, draftID, newStatus, userID)
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Draft updated successfully"})
 
@@ -327,15 +370,22 @@ func DeleteDraftPost(w http.ResponseWriter, r *http.Request) {
 	hub.broadcast(vars["workspaceId"], websocket.TextMessage, msg)
 }
 
-// PublishDraftPost publishes a draft post (only for admin/editor)
+// PublishDraftPost publishes a draft through a scheduled post, immediately or at its
+// scheduled_time (requires post:publish). A draft breaking a platform's rules fails with 422
+// and the validation report; one already posted is only published again with republish.
 func PublishDraftPost(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 	vars := mux.Vars(r)
 	draftID := vars["draftId"]
 
-	// Get workspace ID for permission check
-	var workspaceID string
-	err := lib.DB.QueryRow(`SELECT workspace_id FROM draft_posts WHERE id = $1`, draftID).Scan(&workspaceID)
+	// Load the draft; its workspace drives the permission check
+	var draft models.DraftPost
+	var mediaJSON []byte
+	var platforms pqStringArray
+	err := lib.DB.QueryRow(`
+		SELECT id, workspace_id, created_by, content, media, platforms, status, scheduled_time
+		FROM draft_posts WHERE id = $1
+	`, draftID).Scan(&draft.ID, &draft.WorkspaceID, &draft.CreatedBy, &draft.Content, &mediaJSON, &platforms, &draft.Status, &draft.ScheduledTime)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Draft not found", http.StatusNotFound)
@@ -344,6 +394,9 @@ func PublishDraftPost(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	draft.Media = jsonBytesToStringSlice(mediaJSON)
+	draft.Platforms = []string(platforms)
+	workspaceID := draft.WorkspaceID
 
 	// Check if user has permission to publish posts
 	hasPermission, err := middleware.CheckUserPermission(userID, workspaceID, models.PermPostPublish)
@@ -356,20 +409,46 @@ func PublishDraftPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now := time.Now()
-	_, err = lib.DB.Exec(`
-		UPDATE draft_posts SET status = 'published', published_time = $1, updated_at = $1 WHERE id = $2
-	`, now, draftID)
+	// The body is optional
+	var req models.PublishDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.CheckDraftPublish(draft, &req); err != nil {
+		http.Error(w, "Failed to publish draft: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post, report, err := utils.PublishDraft(lib.DB, userID, draft, req)
+	if report != nil && !report.Valid {
+		writeValidationFailure(w, *report)
+		return
+	}
+	if err == utils.ErrDraftInFlight || err == utils.ErrDraftNotApproved || err == utils.ErrDraftAlreadyPublished {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
+		log.Printf("[ERROR] Publishing draft %s failed: %v", draftID, err)
 		http.Error(w, "Failed to publish draft", http.StatusInternalServerError)
 		return
 	}
+	post.Validation = warningsOnly(*report)
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Draft published successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Draft scheduled for publishing",
+		"scheduled_post": post,
+	})
 
 	msg, _ := json.Marshal(map[string]interface{}{
-		"type":    "draft_published",
-		"draftId": draftID,
+		"type":              "draft_published",
+		"draftId":           draftID,
+		"scheduled_post_id": post.ID,
+		"scheduled_time":    post.ScheduledTime,
 	})
 	hub.broadcast(workspaceID, websocket.TextMessage, msg)
 }
@@ -6,11 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
-	"time"
 
 	"social-sync-backend/middleware"
+	"social-sync-backend/utils"
 )
 
 type FacebookPostRequest struct {
@@ -39,47 +38,13 @@ func PostToFacebookHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		type fbAccount struct {
-			AccessToken string
-			PageID      string
-		}
-		var targets []fbAccount
-
-		// Determine target accounts
-		fmt.Printf("DEBUG: Facebook post request - AccountIDs: %v, All: %v, Message: %s\n", req.AccountIDs, req.All, req.Message)
-		if len(req.AccountIDs) > 0 {
-			for _, id := range req.AccountIDs {
-				var at, pid string
-				qErr := db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND id=$2::uuid AND (platform='facebook' OR provider='facebook')`, userID, id).Scan(&at, &pid)
-				fmt.Printf("DEBUG: Account ID %s - AccessToken: %s, PageID: %s, Error: %v\n", id, at, pid, qErr)
-				if qErr == nil && at != "" && pid != "" {
-					targets = append(targets, fbAccount{AccessToken: at, PageID: pid})
-				}
-			}
-		} else if req.All {
-			rows, qErr := db.Query(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='facebook' OR provider='facebook')`, userID)
-			if qErr == nil {
-				defer rows.Close()
-				for rows.Next() {
-					var at, pid string
-					if scanErr := rows.Scan(&at, &pid); scanErr == nil {
-						targets = append(targets, fbAccount{AccessToken: at, PageID: pid})
-					}
-				}
-			}
-		} else {
-			// Try default, else first any
-			var at, pid string
-			qErr := db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='facebook' OR provider='facebook') AND is_default=true LIMIT 1`, userID).Scan(&at, &pid)
-			if qErr != nil {
-				qErr = db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='facebook' OR provider='facebook') LIMIT 1`, userID).Scan(&at, &pid)
-			}
-			if qErr == nil && at != "" && pid != "" {
-				targets = append(targets, fbAccount{AccessToken: at, PageID: pid})
-			}
+		fmt.Printf("DEBUG: Facebook post request - AccountIDs: %v, All: %v\n", req.AccountIDs, req.All)
+		accounts, err := utils.ResolvePublishAccounts(db, userID, "facebook", req.AccountIDs, req.All)
+		if err != nil {
+			http.Error(w, "Failed to get Facebook accounts", http.StatusInternalServerError)
+			return
 		}
-		fmt.Printf("DEBUG: Total targets found: %d\n", len(targets))
-		if len(targets) == 0 {
+		if len(accounts) == 0 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -88,11 +53,15 @@ func PostToFacebookHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		urlEncode := func(s string) string {
-			return url.QueryEscape(s)
+		outcomes, err := utils.PublishToAccounts(r.Context(), "facebook", accounts, utils.PublishRequest{
+			Content:   req.Message,
+			MediaURLs: req.MediaUrls,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		// For each target account, perform the post and collect results
 		type fbResult struct {
 			AccountID string `json:"accountId"`
 			OK        bool   `json:"ok"`
@@ -100,208 +69,16 @@ func PostToFacebookHandler(db *sql.DB) http.HandlerFunc {
 			Error     string `json:"error,omitempty"`
 		}
 		var results []fbResult
-
-		// CASE 1: Text only
-		if len(req.MediaUrls) == 0 {
-			fmt.Printf("DEBUG: Posting text to %d Facebook pages\n", len(targets))
-			for _, t := range targets {
-				fmt.Printf("DEBUG: Posting to Facebook page %s\n", t.PageID)
-				postURL := fmt.Sprintf("https://graph.facebook.com/%s/feed", t.PageID)
-				payload := strings.NewReader(fmt.Sprintf("message=%s&access_token=%s", urlEncode(req.Message), urlEncode(t.AccessToken)))
-				resp, err := http.Post(postURL, "application/x-www-form-urlencoded", payload)
-				if err != nil {
-					fmt.Printf("DEBUG: Facebook post error for page %s: %v\n", t.PageID, err)
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: err.Error()})
-					continue
-				}
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				if resp.StatusCode != http.StatusOK {
-					fmt.Printf("DEBUG: Facebook post failed for page %s: %d - %s\n", t.PageID, resp.StatusCode, string(body))
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: string(body)})
-					continue
-				}
-				fmt.Printf("DEBUG: Facebook post successful for page %s\n", t.PageID)
-				results = append(results, fbResult{AccountID: t.PageID, OK: true, PostID: "fb_post_" + t.PageID + "_" + fmt.Sprintf("%d", time.Now().Unix())})
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
-			return
-		}
-
-		// Separate images and videos
-		var imageUrls, videoUrls []string
-		for _, url := range req.MediaUrls {
-			if strings.Contains(url, ".mp4") || strings.Contains(url, "/video/") {
-				videoUrls = append(videoUrls, url)
-			} else {
-				imageUrls = append(imageUrls, url)
-			}
-		}
-
-		// CASE 2: Single video only
-		if len(videoUrls) > 0 && len(imageUrls) == 0 {
-			if len(videoUrls) > 1 {
-				http.Error(w, "Facebook only supports posting one video at a time", http.StatusBadRequest)
-				return
-			}
-
-			fmt.Printf("DEBUG: Posting video to %d Facebook pages\n", len(targets))
-			for _, t := range targets {
-				fmt.Printf("DEBUG: Posting video to Facebook page %s\n", t.PageID)
-				videoURL := fmt.Sprintf("https://graph.facebook.com/%s/videos", t.PageID)
-				payload := strings.NewReader(fmt.Sprintf("file_url=%s&description=%s&access_token=%s",
-					urlEncode(videoUrls[0]), urlEncode(req.Message), urlEncode(t.AccessToken)))
-				resp, err := http.Post(videoURL, "application/x-www-form-urlencoded", payload)
-				if err != nil {
-					fmt.Printf("DEBUG: Facebook video post error for page %s: %v\n", t.PageID, err)
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: err.Error()})
-					continue
-				}
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				if resp.StatusCode != http.StatusOK {
-					fmt.Printf("DEBUG: Facebook video post failed for page %s: %d - %s\n", t.PageID, resp.StatusCode, string(body))
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: string(body)})
-					continue
-				}
-				fmt.Printf("DEBUG: Facebook video post successful for page %s\n", t.PageID)
-				results = append(results, fbResult{AccountID: t.PageID, OK: true, PostID: "fb_post_" + t.PageID + "_" + fmt.Sprintf("%d", time.Now().Unix())})
+		for _, o := range outcomes {
+			res := fbResult{AccountID: o.Account.ExternalID, OK: o.Err == nil}
+			if o.Err != nil {
+				res.Error = o.Err.Error()
+			} else if o.Result != nil {
+				res.PostID = o.Result.RemotePostID
 			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
-			return
+			results = append(results, res)
 		}
 
-		// CASE 3: Multiple images only
-		if len(imageUrls) > 0 && len(videoUrls) == 0 {
-			fmt.Printf("DEBUG: Posting images to %d Facebook pages\n", len(targets))
-
-			// For each target page, post images individually
-			for _, t := range targets {
-				fmt.Printf("DEBUG: Posting images to Facebook page %s\n", t.PageID)
-
-				// If single image, use simple photo endpoint
-				if len(imageUrls) == 1 {
-					photoURL := fmt.Sprintf("https://graph.facebook.com/%s/photos", t.PageID)
-					payload := strings.NewReader(fmt.Sprintf("url=%s&message=%s&access_token=%s",
-						urlEncode(imageUrls[0]), urlEncode(req.Message), urlEncode(t.AccessToken)))
-					resp, err := http.Post(photoURL, "application/x-www-form-urlencoded", payload)
-					if err != nil {
-						fmt.Printf("DEBUG: Facebook image post error for page %s: %v\n", t.PageID, err)
-						results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: err.Error()})
-						continue
-					}
-					body, _ := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					if resp.StatusCode != http.StatusOK {
-						fmt.Printf("DEBUG: Facebook image post failed for page %s: %d - %s\n", t.PageID, resp.StatusCode, string(body))
-						results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: string(body)})
-						continue
-					}
-					fmt.Printf("DEBUG: Facebook image post successful for page %s\n", t.PageID)
-					results = append(results, fbResult{AccountID: t.PageID, OK: true, PostID: "fb_post_" + t.PageID + "_" + fmt.Sprintf("%d", time.Now().Unix())})
-				} else {
-					// Multiple images - use carousel approach for this specific page
-					var attachedMediaIDs []string
-
-					// Upload each image to this specific page
-					for _, mediaURL := range imageUrls {
-						uploadURL := fmt.Sprintf("https://graph.facebook.com/%s/photos?access_token=%s", t.PageID, urlEncode(t.AccessToken))
-						payload := fmt.Sprintf("url=%s&published=false", urlEncode(mediaURL))
-
-						resp, err := http.Post(uploadURL, "application/x-www-form-urlencoded", strings.NewReader(payload))
-						if err != nil {
-							fmt.Printf("DEBUG: Failed to upload image to page %s: %v\n", t.PageID, err)
-							results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: err.Error()})
-							goto nextTarget
-						}
-						body, _ := io.ReadAll(resp.Body)
-						resp.Body.Close()
-
-						if resp.StatusCode != http.StatusOK {
-							fmt.Printf("DEBUG: Image upload failed for page %s: %d - %s\n", t.PageID, resp.StatusCode, string(body))
-							results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: string(body)})
-							goto nextTarget
-						}
-
-						var fbRes struct {
-							ID string `json:"id"`
-						}
-						if err := json.Unmarshal(body, &fbRes); err != nil || fbRes.ID == "" {
-							fmt.Printf("DEBUG: Failed to parse media ID for page %s\n", t.PageID)
-							results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: "Failed to parse media ID"})
-							goto nextTarget
-						}
-						attachedMediaIDs = append(attachedMediaIDs, fbRes.ID)
-					}
-
-					// Post with attached images
-					var mediaParams []string
-					for i, id := range attachedMediaIDs {
-						mediaParams = append(mediaParams, fmt.Sprintf(`attached_media[%d]={"media_fbid":"%s"}`, i, id))
-					}
-
-					postURL := fmt.Sprintf("https://graph.facebook.com/%s/feed", t.PageID)
-					finalPayload := fmt.Sprintf(
-						"message=%s&%s&access_token=%s",
-						urlEncode(req.Message),
-						strings.Join(mediaParams, "&"),
-						urlEncode(t.AccessToken),
-					)
-
-					resp, err := http.Post(postURL, "application/x-www-form-urlencoded", strings.NewReader(finalPayload))
-					if err != nil {
-						fmt.Printf("DEBUG: Facebook carousel post error for page %s: %v\n", t.PageID, err)
-						results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: err.Error()})
-						continue
-					}
-					body, _ := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					if resp.StatusCode != http.StatusOK {
-						fmt.Printf("DEBUG: Facebook carousel post failed for page %s: %d - %s\n", t.PageID, resp.StatusCode, string(body))
-						results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: string(body)})
-						continue
-					}
-					fmt.Printf("DEBUG: Facebook carousel post successful for page %s\n", t.PageID)
-					results = append(results, fbResult{AccountID: t.PageID, OK: true, PostID: "fb_post_" + t.PageID + "_" + fmt.Sprintf("%d", time.Now().Unix())})
-				}
-
-			nextTarget:
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
-			return
-		}
-
-		// CASE 4: Mixed media (images + videos) - post separately
-		fmt.Printf("DEBUG: Posting mixed media to %d Facebook pages (separate posts)\n", len(targets))
-		for _, t := range targets {
-			fmt.Printf("DEBUG: Posting mixed media to Facebook page %s\n", t.PageID)
-
-			// Post images first
-			if len(imageUrls) > 0 {
-				err := postImagesToFacebookPage(t.PageID, t.AccessToken, req.Message, imageUrls)
-				if err != nil {
-					fmt.Printf("DEBUG: Facebook images post failed for page %s: %v\n", t.PageID, err)
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: fmt.Sprintf("images: %v", err)})
-					continue
-				}
-			}
-
-			// Post videos
-			if len(videoUrls) > 0 {
-				err := postVideosToFacebookPage(t.PageID, t.AccessToken, req.Message, videoUrls)
-				if err != nil {
-					fmt.Printf("DEBUG: Facebook videos post failed for page %s: %v\n", t.PageID, err)
-					results = append(results, fbResult{AccountID: t.PageID, OK: false, Error: fmt.Sprintf("videos: %v", err)})
-					continue
-				}
-			}
-
-			fmt.Printf("DEBUG: Facebook mixed media post successful for page %s\n", t.PageID)
-			results = append(results, fbResult{AccountID: t.PageID, OK: true})
-		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
 	}
@@ -461,106 +238,3 @@ func GetFacebookPostsHandler(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
-
-// postImagesToFacebookPage posts multiple images to a Facebook page
-func postImagesToFacebookPage(pageID, accessToken, message string, imageUrls []string) error {
-	if len(imageUrls) == 1 {
-		// Single image
-		imageURL := fmt.Sprintf("https://graph.facebook.com/%s/photos", pageID)
-		payload := strings.NewReader(fmt.Sprintf("url=%s&message=%s&access_token=%s",
-			urlEncode(imageUrls[0]), urlEncode(message), urlEncode(accessToken)))
-		resp, err := http.Post(imageURL, "application/x-www-form-urlencoded", payload)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("image post failed: %s", string(body))
-		}
-		return nil
-	} else {
-		// Multiple images - create carousel
-		var attachedMediaIDs []string
-
-		// Upload each image
-		for _, mediaURL := range imageUrls {
-			uploadURL := fmt.Sprintf("https://graph.facebook.com/%s/photos?access_token=%s", pageID, urlEncode(accessToken))
-			payload := fmt.Sprintf("url=%s&published=false", urlEncode(mediaURL))
-
-			resp, err := http.Post(uploadURL, "application/x-www-form-urlencoded", strings.NewReader(payload))
-			if err != nil {
-				return err
-			}
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				return fmt.Errorf("image upload failed: %s", string(body))
-			}
-
-			var fbRes struct {
-				ID string `json:"id"`
-			}
-			if err := json.Unmarshal(body, &fbRes); err != nil || fbRes.ID == "" {
-				return fmt.Errorf("failed to parse media ID")
-			}
-			attachedMediaIDs = append(attachedMediaIDs, fbRes.ID)
-		}
-
-		// Post with attached images
-		var mediaParams []string
-		for i, id := range attachedMediaIDs {
-			mediaParams = append(mediaParams, fmt.Sprintf(`attached_media[%d]={"media_fbid":"%s"}`, i, id))
-		}
-
-		postURL := fmt.Sprintf("https://graph.facebook.com/%s/feed", pageID)
-		finalPayload := fmt.Sprintf(
-			"message=%s&%s&access_token=%s",
-			urlEncode(message),
-			strings.Join(mediaParams, "&"),
-			urlEncode(accessToken),
-		)
-
-		resp, err := http.Post(postURL, "application/x-www-form-urlencoded", strings.NewReader(finalPayload))
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("carousel post failed: %s", string(body))
-		}
-		return nil
-	}
-}
-
-// postVideosToFacebookPage posts multiple videos to a Facebook page
-func postVideosToFacebookPage(pageID, accessToken, message string, videoUrls []string) error {
-	for i, videoURL := range videoUrls {
-		// Add video number to message for multiple videos
-		postMessage := message
-		if len(videoUrls) > 1 {
-			postMessage = fmt.Sprintf("%s\n\n[Video %d/%d]", message, i+1, len(videoUrls))
-		}
-
-		videoPostURL := fmt.Sprintf("https://graph.facebook.com/%s/videos", pageID)
-		payload := strings.NewReader(fmt.Sprintf("file_url=%s&description=%s&access_token=%s",
-			urlEncode(videoURL), urlEncode(postMessage), urlEncode(accessToken)))
-		resp, err := http.Post(videoPostURL, "application/x-www-form-urlencoded", payload)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("video post failed: %s", string(body))
-		}
-	}
-	return nil
-}
-
-// urlEncode encodes a string for URL parameters
-func urlEncode(s string) string {
-	return url.QueryEscape(s)
-}
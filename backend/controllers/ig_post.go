@@ -8,16 +8,13 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
 	"social-sync-backend/middleware" // Assuming this path is correct for your project
+	"social-sync-backend/utils"
 )
 
-// Define the Instagram Graph API version to use
-const instagramAPIVersion = "v19.0" // Using v19.0 for latest features
-
 type InstagramPostRequest struct {
 	Caption    string   `json:"caption"`
 	MediaUrls  []string `json:"mediaUrls"`
@@ -25,88 +22,6 @@ type InstagramPostRequest struct {
 	All        bool     `json:"all,omitempty"`
 }
 
-// isVideoURL determines if a URL points to a video by checking file extension and content type
-func isVideoURL(mediaURL string) bool {
-	lower := strings.ToLower(mediaURL)
-
-	// Check file extensions first
-	if strings.HasSuffix(lower, ".mp4") || strings.HasSuffix(lower, ".mov") || strings.HasSuffix(lower, ".webm") ||
-		strings.HasSuffix(lower, ".avi") || strings.HasSuffix(lower, ".mkv") || strings.HasSuffix(lower, ".flv") {
-		return true
-	}
-
-	// Check for video-related keywords in URL
-	if strings.Contains(lower, "video") || strings.Contains(lower, "mp4") || strings.Contains(lower, "mov") {
-		return true
-	}
-
-	// Make a HEAD request to check content type
-	resp, err := http.Head(mediaURL)
-	if err != nil {
-		// If HEAD request fails, default to image
-		return false
-	}
-	defer resp.Body.Close()
-
-	contentType := resp.Header.Get("Content-Type")
-	fmt.Printf("DEBUG: Content-Type for %s: %s\n", mediaURL, contentType)
-
-	// Check if content type indicates video
-	return strings.HasPrefix(contentType, "video/")
-}
-
-// waitForMediaReady polls Instagram media container status until ready or timeout
-// This function checks the status of individual media containers (images/videos)
-// and also the carousel container itself.
-func waitForMediaReady(mediaID, accessToken string) error {
-	// Construct the URL to check the media container's status
-	statusURL := fmt.Sprintf("https://graph.facebook.com/%s/%s?fields=status_code&access_token=%s", instagramAPIVersion, mediaID, accessToken)
-
-	const maxRetries = 30                // Increased retries for more robust waiting (from 10)
-	const delay = 5 * time.Second        // Increased delay (from 3s), total wait time now up to 150 seconds
-	const initialDelay = 3 * time.Second // Initial delay before the first retry
-
-	// Small initial delay before starting the loop to allow Instagram some initial processing time
-	time.Sleep(initialDelay)
-
-	for i := 0; i < maxRetries; i++ {
-		resp, err := http.Get(statusURL)
-		if err != nil {
-			return fmt.Errorf("failed to get media status: %w", err) // Return immediately on network error
-		}
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read media status response: %w", err) // Return immediately on read error
-		}
-
-		// Check if the response indicates an error
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("media status check failed with status %d: %s", resp.StatusCode, string(body))
-		}
-
-		// Parse the status response
-		var res struct {
-			StatusCode string `json:"status_code"`
-		}
-		if err := json.Unmarshal(body, &res); err != nil {
-			return fmt.Errorf("failed to parse media status response: %w", err)
-		}
-
-		if res.StatusCode == "FINISHED" {
-			return nil // Media is ready to publish
-		} else if res.StatusCode == "ERROR" {
-			return fmt.Errorf("media upload failed with status 'ERROR'")
-		}
-
-		// Media is not yet finished, wait and retry
-		time.Sleep(delay)
-	}
-
-	// If loop finishes, it means media wasn't ready within the max retries
-	return fmt.Errorf("media not ready for ID %s after %d retries (%s total wait)", mediaID, maxRetries, time.Duration(maxRetries)*delay)
-}
-
 func PostToInstagramHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := middleware.GetUserIDFromContext(r)
@@ -138,49 +53,13 @@ func PostToInstagramHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		type igAccount struct {
-			AccessToken string
-			InstagramID string
-		}
-		var targets []igAccount
-
-		// Determine target accounts
-		fmt.Printf("DEBUG: Instagram post request - AccountIDs: %v, All: %v, Caption: %s\n", req.AccountIDs, req.All, req.Caption)
-		if len(req.AccountIDs) > 0 {
-			for _, id := range req.AccountIDs {
-				var at, igID string
-				qErr := db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND id=$2::uuid AND (platform='instagram' OR provider='instagram')`, userID, id).Scan(&at, &igID)
-				fmt.Printf("DEBUG: Account ID %s - AccessToken: %s, InstagramID: %s, Error: %v\n", id, at, igID, qErr)
-				if qErr == nil && at != "" && igID != "" {
-					targets = append(targets, igAccount{AccessToken: at, InstagramID: igID})
-				}
-			}
-		} else if req.All {
-			fmt.Printf("DEBUG: Processing all Instagram accounts\n")
-			rows, qErr := db.Query(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='instagram' OR provider='instagram')`, userID)
-			if qErr == nil {
-				defer rows.Close()
-				for rows.Next() {
-					var at, igID string
-					if scanErr := rows.Scan(&at, &igID); scanErr == nil {
-						fmt.Printf("DEBUG: Found Instagram account - AccessToken: %s, InstagramID: %s\n", at, igID)
-						targets = append(targets, igAccount{AccessToken: at, InstagramID: igID})
-					}
-				}
-			}
-		} else {
-			// Try default, else first any
-			var at, igID string
-			qErr := db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='instagram' OR provider='instagram') AND is_default=true LIMIT 1`, userID).Scan(&at, &igID)
-			if qErr != nil {
-				qErr = db.QueryRow(`SELECT COALESCE(access_token_enc, access_token), COALESCE(external_account_id, social_id) FROM social_accounts WHERE user_id=$1 AND (platform='instagram' OR provider='instagram') LIMIT 1`, userID).Scan(&at, &igID)
-			}
-			if qErr == nil && at != "" && igID != "" {
-				targets = append(targets, igAccount{AccessToken: at, InstagramID: igID})
-			}
+		fmt.Printf("DEBUG: Instagram post request - AccountIDs: %v, All: %v\n", req.AccountIDs, req.All)
+		accounts, err := utils.ResolvePublishAccounts(db, userID, "instagram", req.AccountIDs, req.All)
+		if err != nil {
+			http.Error(w, "Failed to get Instagram accounts", http.StatusInternalServerError)
+			return
 		}
-		fmt.Printf("DEBUG: Total Instagram targets found: %d\n", len(targets))
-		if len(targets) == 0 {
+		if len(accounts) == 0 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -189,7 +68,16 @@ func PostToInstagramHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// For each target account, perform the post and collect results
+		outcomes, err := utils.PublishToAccounts(r.Context(), "instagram", accounts, utils.PublishRequest{
+			Content:   req.Caption,
+			MediaURLs: req.MediaUrls,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// For each target account, collect results
 		type igResult struct {
 			AccountID string `json:"accountId"`
 			OK        bool   `json:"ok"`
@@ -197,83 +85,14 @@ func PostToInstagramHandler(db *sql.DB) http.HandlerFunc {
 			Error     string `json:"error,omitempty"`
 		}
 		var results []igResult
-
-		fmt.Printf("DEBUG: Posting to %d Instagram accounts\n", len(targets))
-		for _, t := range targets {
-			fmt.Printf("DEBUG: Posting to Instagram account %s\n", t.InstagramID)
-
-			// Check if the token is valid by making a test request
-			testURL := fmt.Sprintf("https://graph.facebook.com/%s/%s?fields=id&access_token=%s", instagramAPIVersion, t.InstagramID, t.AccessToken)
-			testResp, err := http.Get(testURL)
-			if err != nil {
-				fmt.Printf("DEBUG: Instagram token validation error for account %s: %v\n", t.InstagramID, err)
-				results = append(results, igResult{AccountID: t.InstagramID, OK: false, Error: err.Error()})
-				continue
-			}
-			defer testResp.Body.Close()
-
-			// If token is invalid, try to refresh it from Facebook
-			if testResp.StatusCode == 401 || testResp.StatusCode == 403 {
-				fmt.Printf("DEBUG: Instagram token expired for account %s, attempting to refresh from Facebook\n", t.InstagramID)
-
-				// Get Facebook access token
-				var fbAccessToken string
-				err = db.QueryRow(`
-				SELECT access_token FROM social_accounts
-				WHERE user_id = $1 AND platform = 'facebook'`, userID).Scan(&fbAccessToken)
-				if err != nil {
-					fmt.Printf("DEBUG: No Facebook token found for Instagram refresh\n")
-					results = append(results, igResult{AccountID: t.InstagramID, OK: false, Error: "Instagram token expired and no Facebook token available"})
-					continue
-				}
-
-				// Try to get a new long-lived token from Facebook
-				refreshURL := fmt.Sprintf("https://graph.facebook.com/%s/oauth/access_token?grant_type=fb_exchange_token&client_id=%s&client_secret=%s&fb_exchange_token=%s",
-					instagramAPIVersion, os.Getenv("FACEBOOK_APP_ID"), os.Getenv("FACEBOOK_APP_SECRET"), fbAccessToken)
-
-				refreshResp, err := http.Get(refreshURL)
-				if err != nil {
-					fmt.Printf("DEBUG: Failed to refresh token for account %s: %v\n", t.InstagramID, err)
-					results = append(results, igResult{AccountID: t.InstagramID, OK: false, Error: "Failed to refresh token"})
-					continue
-				}
-				defer refreshResp.Body.Close()
-
-				if refreshResp.StatusCode == 200 {
-					var refreshData struct {
-						AccessToken string `json:"access_token"`
-						TokenType   string `json:"token_type"`
-						ExpiresIn   int    `json:"expires_in"`
-					}
-					if err := json.NewDecoder(refreshResp.Body).Decode(&refreshData); err == nil && refreshData.AccessToken != "" {
-						// Update Instagram token for this specific account
-						_, err = db.Exec(`
-							UPDATE social_accounts SET access_token = $1 WHERE user_id = $2 AND social_id = $3 AND platform = 'instagram'
-						`, refreshData.AccessToken, userID, t.InstagramID)
-						if err == nil {
-							t.AccessToken = refreshData.AccessToken
-							fmt.Printf("DEBUG: Successfully refreshed Instagram token for account %s\n", t.InstagramID)
-						}
-					}
-				}
-
-				// If refresh failed, skip this account
-				if t.AccessToken == "" {
-					fmt.Printf("DEBUG: Token refresh failed for account %s\n", t.InstagramID)
-					results = append(results, igResult{AccountID: t.InstagramID, OK: false, Error: "Token refresh failed"})
-					continue
-				}
-			}
-
-			// Post to this Instagram account
-			err = postToInstagramAccount(t.InstagramID, t.AccessToken, req.Caption, req.MediaUrls)
-			if err != nil {
-				fmt.Printf("DEBUG: Instagram post failed for account %s: %v\n", t.InstagramID, err)
-				results = append(results, igResult{AccountID: t.InstagramID, OK: false, Error: err.Error()})
-			} else {
-				fmt.Printf("DEBUG: Instagram post successful for account %s\n", t.InstagramID)
-				results = append(results, igResult{AccountID: t.InstagramID, OK: true, PostID: "ig_post_" + t.InstagramID + "_" + fmt.Sprintf("%d", time.Now().Unix())})
+		for _, o := range outcomes {
+			res := igResult{AccountID: o.Account.ExternalID, OK: o.Err == nil}
+			if o.Err != nil {
+				res.Error = o.Err.Error()
+			} else if o.Result != nil {
+				res.PostID = o.Result.RemotePostID
 			}
+			results = append(results, res)
 		}
 
 		// Return results
@@ -282,172 +101,6 @@ func PostToInstagramHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// postToInstagramAccount posts to a specific Instagram account
-func postToInstagramAccount(instagramID, accessToken, caption string, mediaUrls []string) error {
-	mediaCount := len(mediaUrls)
-	mediaContainerIDs := make([]string, 0, mediaCount)
-
-	// Declare 'body' here once for the entire function scope
-	var body []byte
-
-	for _, mediaURL := range mediaUrls {
-		form := url.Values{}
-		// Only mark as carousel item when posting a carousel
-		if mediaCount > 1 {
-			form.Set("is_carousel_item", "true")
-		}
-
-		// Determine media type by checking the URL and making a HEAD request
-		isVideo := isVideoURL(mediaURL)
-		fmt.Printf("DEBUG: Media URL: %s, isVideo: %v\n", mediaURL, isVideo)
-
-		if isVideo {
-			// For videos, use video_url parameter and set media_type to REELS (VIDEO is deprecated)
-			form.Set("video_url", mediaURL)
-			form.Set("media_type", "REELS")
-		} else {
-			// For images, use image_url parameter
-			form.Set("image_url", mediaURL)
-		}
-
-		// Add caption during media creation for single media posts
-		if mediaCount == 1 {
-			form.Set("caption", caption)
-		}
-
-		form.Set("access_token", accessToken)
-
-		// Debug: Log all form parameters
-		fmt.Printf("DEBUG: Form parameters for %s:\n", mediaURL)
-		for key, values := range form {
-			fmt.Printf("  %s: %v\n", key, values)
-		}
-
-		// Step 1: Create individual media container
-		createMediaURL := fmt.Sprintf("https://graph.facebook.com/%s/%s/media", instagramAPIVersion, instagramID)
-		resp, err := http.Post(
-			createMediaURL,
-			"application/x-www-form-urlencoded",
-			strings.NewReader(form.Encode()),
-		)
-		if err != nil {
-			return fmt.Errorf("failed to create media container: %w", err)
-		}
-		// Use '=' for reassignment, not ':='
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read media container creation response: %w", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Instagram: Media container creation failed with status %d: %s", resp.StatusCode, string(body))
-			return fmt.Errorf("media container creation failed: %s", body)
-		}
-
-		var result struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(body, &result); err != nil || result.ID == "" {
-			return fmt.Errorf("invalid response from media container creation: %w", err)
-		}
-
-		// Step 2: Wait for individual media container to be ready
-		if err := waitForMediaReady(result.ID, accessToken); err != nil {
-			return fmt.Errorf("media item failed to process: %w", err)
-		}
-
-		mediaContainerIDs = append(mediaContainerIDs, result.ID)
-	}
-
-	if mediaCount == 1 {
-		// Single media post - publish directly
-		publishForm := url.Values{}
-		publishForm.Set("creation_id", mediaContainerIDs[0])
-		// Caption already set during media creation, no need to set it again
-		publishForm.Set("access_token", accessToken)
-
-		publishURL := fmt.Sprintf("https://graph.facebook.com/%s/%s/media_publish", instagramAPIVersion, instagramID)
-		publishResp, err := http.Post(publishURL, "application/x-www-form-urlencoded", strings.NewReader(publishForm.Encode()))
-		if err != nil {
-			return fmt.Errorf("failed to publish post: %w", err)
-		}
-		// Use '=' for reassignment, not ':='
-		body, err = io.ReadAll(publishResp.Body)
-		publishResp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read publish response: %w", err)
-		}
-
-		if publishResp.StatusCode != http.StatusOK {
-			log.Printf("Instagram: Single media publish failed with status %d: %s", publishResp.StatusCode, string(body))
-			return fmt.Errorf("publish failed: %s", body)
-		}
-
-	} else {
-		// Carousel post creation and publish
-		carouselForm := url.Values{}
-		carouselForm.Set("media_type", "CAROUSEL")
-		carouselForm.Set("children", strings.Join(mediaContainerIDs, ","))
-		carouselForm.Set("caption", caption)
-		carouselForm.Set("access_token", accessToken)
-
-		// Step 3: Create carousel container
-		createCarouselURL := fmt.Sprintf("https://graph.facebook.com/%s/%s/media", instagramAPIVersion, instagramID)
-		carouselResp, err := http.Post(createCarouselURL, "application/x-www-form-urlencoded", strings.NewReader(carouselForm.Encode()))
-		if err != nil {
-			return fmt.Errorf("failed to create carousel container: %w", err)
-		}
-		// Use '=' for reassignment, not ':='
-		body, err = io.ReadAll(carouselResp.Body)
-		carouselResp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read carousel container creation response: %w", err)
-		}
-
-		if carouselResp.StatusCode != http.StatusOK {
-			return fmt.Errorf("carousel container creation failed: %s", body)
-		}
-
-		var carouselResult struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(body, &carouselResult); err != nil || carouselResult.ID == "" {
-			return fmt.Errorf("invalid carousel container creation response: %w", err)
-		}
-
-		// Step 4: Wait for carousel container to be ready
-		if err := waitForMediaReady(carouselResult.ID, accessToken); err != nil {
-			return fmt.Errorf("carousel post failed to process: %w", err)
-		}
-
-		// Step 5: Publish the carousel
-		publishForm := url.Values{}
-		publishForm.Set("creation_id", carouselResult.ID)
-		publishForm.Set("caption", caption)
-		publishForm.Set("access_token", accessToken)
-
-		publishURL := fmt.Sprintf("https://graph.facebook.com/%s/%s/media_publish", instagramAPIVersion, instagramID)
-		publishResp, err := http.Post(publishURL, "application/x-www-form-urlencoded", strings.NewReader(publishForm.Encode()))
-		if err != nil {
-			return fmt.Errorf("failed to publish carousel post: %w", err)
-		}
-		// Use '=' for reassignment, not ':='
-		body, err = io.ReadAll(publishResp.Body)
-		publishResp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to read publish response: %w", err)
-		}
-
-		if publishResp.StatusCode != http.StatusOK {
-			log.Printf("Instagram: Carousel publish failed with status %d: %s", publishResp.StatusCode, string(body))
-			return fmt.Errorf("carousel publish failed: %s", body)
-		}
-	}
-
-	return nil
-}
-
 // GetInstagramPostsHandler fetches the user's Instagram posts
 func GetInstagramPostsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1,16 +1,14 @@
 package controllers
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
 	"time"
 
 	"social-sync-backend/middleware"
+	"social-sync-backend/utils"
 
 	"github.com/lib/pq"
 )
@@ -35,7 +33,6 @@ func PostToMastodonHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "user not authenticated", http.StatusUnauthorized)
 			return
 		}
-		fmt.Printf("DEBUG: Mastodon post - user ID: %s\n", userID)
 
 		var req MastodonPostRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -43,134 +40,18 @@ func PostToMastodonHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "failed to parse JSON data", http.StatusBadRequest)
 			return
 		}
-		fmt.Printf("DEBUG: Mastodon post request: %+v\n", req)
 
 		if req.Status == "" {
 			http.Error(w, "status is required", http.StatusBadRequest)
 			return
 		}
 
-		// Get Mastodon accounts - try with instance_url first, fallback without it
-		rows, err := db.Query(`SELECT id::text, access_token, COALESCE(instance_url, 'https://mastodon.social') as instance_url FROM social_accounts WHERE user_id=$1 AND (platform='mastodon' OR provider='mastodon') AND id = ANY($2::uuid[])`, userID, pq.Array(req.AccountIds))
+		accounts, err := utils.ResolvePublishAccounts(db, userID, "mastodon", req.AccountIds, false)
 		if err != nil {
-			// Fallback query without instance_url
-			rows, err = db.Query(`SELECT id::text, access_token FROM social_accounts WHERE user_id=$1 AND (platform='mastodon' OR provider='mastodon') AND id = ANY($2::uuid[])`, userID, pq.Array(req.AccountIds))
-			if err != nil {
-				// If no Mastodon accounts found, return friendly error message
-				fmt.Printf("DEBUG: No Mastodon accounts found\n")
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"error": "Mastodon account not connected",
-				})
-				return
-			}
-		}
-		defer rows.Close()
-
-		var results []MastodonPostResult
-		hasRows := false
-		for rows.Next() {
-			hasRows = true
-			var id, accessToken, instanceURL string
-			if err := rows.Scan(&id, &accessToken, &instanceURL); err != nil {
-				// Try scanning without instanceURL
-				if err := rows.Scan(&id, &accessToken); err != nil {
-					results = append(results, MastodonPostResult{
-						AccountID: id,
-						OK:        false,
-						Error:     "Failed to get account details: " + err.Error(),
-					})
-					continue
-				}
-				instanceURL = "https://mastodon.social" // Default instance
-			}
-
-			// Post to Mastodon
-			fmt.Printf("DEBUG: Posting to Mastodon instance: %s\n", instanceURL)
-			postData := map[string]interface{}{
-				"status": req.Status,
-			}
-
-			// Add media if provided
-			if len(req.MediaUrls) > 0 {
-				mediaIds := []string{}
-				for _, mediaUrl := range req.MediaUrls {
-					// Upload media to Mastodon instance
-					mediaId, err := uploadMediaToMastodon(instanceURL, accessToken, mediaUrl)
-					if err != nil {
-						fmt.Printf("DEBUG: Media upload failed for account %s: %v\n", id, err)
-						results = append(results, MastodonPostResult{
-							AccountID: id,
-							OK:        false,
-							Error:     "Failed to upload media: " + err.Error(),
-						})
-						continue
-					}
-					mediaIds = append(mediaIds, mediaId)
-				}
-				postData["media_ids"] = mediaIds
-			}
-
-			postBody, _ := json.Marshal(postData)
-			fmt.Printf("DEBUG: Mastodon post data: %s\n", string(postBody))
-
-			// Create HTTP request with proper headers
-			req, err := http.NewRequest("POST", instanceURL+"/api/v1/statuses", bytes.NewBuffer(postBody))
-			if err != nil {
-				results = append(results, MastodonPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Failed to create request: " + err.Error(),
-				})
-				continue
-			}
-
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-			req.Header.Set("Content-Type", "application/json")
-
-			client := &http.Client{Timeout: 30 * time.Second}
-			resp, err := client.Do(req)
-			if err != nil {
-				fmt.Printf("DEBUG: Mastodon request failed for account %s: %v\n", id, err)
-				results = append(results, MastodonPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Failed to post to Mastodon: " + err.Error(),
-				})
-				continue
-			}
-			defer resp.Body.Close()
-
-			fmt.Printf("DEBUG: Mastodon response status: %d\n", resp.StatusCode)
-			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-				body, _ := io.ReadAll(resp.Body)
-				fmt.Printf("DEBUG: Mastodon API error response: %s\n", string(body))
-				results = append(results, MastodonPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     fmt.Sprintf("Mastodon API error: %d %s", resp.StatusCode, string(body)),
-				})
-				continue
-			}
-
-			var postResponse map[string]interface{}
-			json.NewDecoder(resp.Body).Decode(&postResponse)
-			postID := ""
-			if responseID, ok := postResponse["id"].(string); ok {
-				postID = responseID
-			}
-
-			fmt.Printf("DEBUG: Successfully posted to Mastodon account %s, post ID: %s\n", id, postID)
-			results = append(results, MastodonPostResult{
-				AccountID: id,
-				OK:        true,
-				PostID:    postID,
-			})
+			http.Error(w, "failed to get Mastodon accounts", http.StatusInternalServerError)
+			return
 		}
-
-		// Check if no accounts were found
-		if !hasRows {
+		if len(accounts) == 0 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -179,6 +60,26 @@ func PostToMastodonHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		outcomes, err := utils.PublishToAccounts(r.Context(), "mastodon", accounts, utils.PublishRequest{
+			Content:   req.Status,
+			MediaURLs: req.MediaUrls,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var results []MastodonPostResult
+		for _, o := range outcomes {
+			res := MastodonPostResult{AccountID: o.Account.ID, OK: o.Err == nil}
+			if o.Err != nil {
+				res.Error = o.Err.Error()
+			} else if o.Result != nil {
+				res.PostID = o.Result.RemotePostID
+			}
+			results = append(results, res)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"results": results,
@@ -394,59 +295,3 @@ func GetMastodonAnalyticsHandler(db *sql.DB) http.HandlerFunc {
 		})
 	}
 }
-
-func uploadMediaToMastodon(instanceURL, accessToken, mediaUrl string) (string, error) {
-	// Download media from URL
-	resp, err := http.Get(mediaUrl)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Upload to Mastodon
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	part, err := writer.CreateFormFile("file", "media")
-	if err != nil {
-		return "", err
-	}
-
-	_, err = io.Copy(part, resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	writer.Close()
-
-	req, err := http.NewRequest("POST", instanceURL+"/api/v1/media", body)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err = client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("media upload failed: %d %s", resp.StatusCode, string(body))
-	}
-
-	var mediaResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&mediaResponse); err != nil {
-		return "", err
-	}
-
-	if id, ok := mediaResponse["id"].(string); ok {
-		return id, nil
-	}
-
-	return "", fmt.Errorf("no media ID in response")
-}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"social-sync-backend/lib"
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// requireTemplatePermission checks a workspace permission and writes the error response on failure.
+// Templates are part of drafting, so they use the draft:* permissions.
+func requireTemplatePermission(w http.ResponseWriter, r *http.Request, permission string) (string, string, bool) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+	ok, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return "", "", false
+	}
+	if !ok {
+		http.Error(w, "You don't have permission to manage templates in this workspace", http.StatusForbidden)
+		return "", "", false
+	}
+	return userID, workspaceID, true
+}
+
+// writeTemplateError maps template errors to HTTP responses
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch err {
+	case utils.ErrTemplateNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case utils.ErrTemplateNameTaken:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[ERROR] Template operation failed: %v", err)
+		http.Error(w, "Template operation failed", http.StatusInternalServerError)
+	}
+}
+
+// applyTemplateRequest copies the fields set in req onto tpl
+func applyTemplateRequest(tpl *models.PostTemplate, req models.PostTemplateRequest) {
+	if req.Name != nil {
+		tpl.Name = *req.Name
+	}
+	if req.Content != nil {
+		tpl.Content = *req.Content
+	}
+	if req.MediaURLs != nil {
+		tpl.MediaURLs = *req.MediaURLs
+	}
+	if req.Platforms != nil {
+		tpl.Platforms = *req.Platforms
+	}
+	if req.Targets != nil {
+		tpl.Targets = *req.Targets
+	}
+	if req.Hashtags != nil {
+		tpl.Hashtags = *req.Hashtags
+	}
+	if req.Variables != nil {
+		tpl.Variables = *req.Variables
+	}
+}
+
+// ListPostTemplates returns the workspace's templates by name
+func ListPostTemplates(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftRead)
+	if !ok {
+		return
+	}
+
+	templates, err := utils.ListPostTemplates(lib.DB, workspaceID)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// GetPostTemplate returns one template with the placeholders it uses
+func GetPostTemplate(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftRead)
+	if !ok {
+		return
+	}
+
+	tpl, err := utils.GetPostTemplate(lib.DB, workspaceID, mux.Vars(r)["templateId"])
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tpl)
+}
+
+// CreatePostTemplate adds a template to the workspace
+func CreatePostTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftCreate)
+	if !ok {
+		return
+	}
+
+	var req models.PostTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	tpl := models.PostTemplate{WorkspaceID: workspaceID, CreatedBy: &userID}
+	applyTemplateRequest(&tpl, req)
+	if err := utils.CheckPostTemplate(tpl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := utils.CreatePostTemplate(lib.DB, tpl)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdatePostTemplate changes the fields of a template that are set in the request
+func UpdatePostTemplate(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftUpdate)
+	if !ok {
+		return
+	}
+
+	var req models.PostTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	tpl, err := utils.GetPostTemplate(lib.DB, workspaceID, mux.Vars(r)["templateId"])
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+	applyTemplateRequest(tpl, req)
+	if err := utils.CheckPostTemplate(*tpl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := utils.UpdatePostTemplate(lib.DB, *tpl)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeletePostTemplate removes a template from the workspace
+func DeletePostTemplate(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftDelete)
+	if !ok {
+		return
+	}
+
+	if err := utils.DeletePostTemplate(lib.DB, workspaceID, mux.Vars(r)["templateId"]); err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UsePostTemplate renders a template with the given variables and creates a draft or a
+// scheduled post from it. Placeholders without a value fail with 422 and the missing names.
+func UsePostTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireTemplatePermission(w, r, models.PermDraftRead)
+	if !ok {
+		return
+	}
+
+	var req models.UsePostTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.As == "" {
+		req.As = models.TemplateAsDraft
+	}
+
+	// Creating the post needs the same permission as creating it directly
+	var permission string
+	switch req.As {
+	case models.TemplateAsDraft:
+		permission = models.PermDraftCreate
+	case models.TemplateAsScheduledPost:
+		permission = models.PermPostSchedule
+	default:
+		http.Error(w, "as must be draft or scheduled_post", http.StatusBadRequest)
+		return
+	}
+	if _, _, ok := requireTemplatePermission(w, r, permission); !ok {
+		return
+	}
+
+	tpl, err := utils.GetPostTemplate(lib.DB, workspaceID, mux.Vars(r)["templateId"])
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+	content, targets, err := utils.RenderPostTemplate(*tpl, req.Variables)
+	if missing, ok := err.(*utils.MissingVariablesError); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   missing.Error(),
+			"missing": missing.Names,
+		})
+		return
+	}
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	post := models.CreateScheduledPostRequest{
+		Content:     content,
+		MediaURLs:   tpl.MediaURLs,
+		Platforms:   tpl.Platforms,
+		Targets:     targets,
+		WorkspaceID: &workspaceID,
+		LocalTime:   req.LocalTime,
+		TimeZone:    req.TimeZone,
+	}
+	if req.ScheduledTime != nil {
+		post.ScheduledTime = *req.ScheduledTime
+	}
+
+	if req.As == models.TemplateAsDraft {
+		// Drafts keep a proposed time if one is given; they carry no per-platform targets
+		scheduledTime := req.ScheduledTime
+		if req.LocalTime != "" {
+			if err := resolveScheduleZone(lib.DB, userID, &post); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			scheduledTime = &post.ScheduledTime
+		}
+		draft, err := insertDraftPost(workspaceID, userID, content, tpl.MediaURLs, tpl.Platforms, scheduledTime)
+		if err != nil {
+			http.Error(w, "Failed to create draft", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(draft)
+
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type":  "draft_created",
+			"draft": draft,
+		})
+		hub.broadcast(workspaceID, websocket.TextMessage, msg)
+		return
+	}
+
+	if req.ScheduledTime == nil && req.LocalTime == "" {
+		http.Error(w, "scheduled_time or local_time is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkScheduledPostRequest(lib.DB, userID, &post); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report := utils.ValidateScheduledPost(lib.DB, validationInput(post))
+	if !report.Valid {
+		writeValidationFailure(w, report)
+		return
+	}
+	if post.Targets, err = utils.NormalizeTargets(post.Targets, post.MediaURLs); err != nil {
+		http.Error(w, "Invalid targets: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scheduledPost, err := insertScheduledPost(lib.DB, userID, post)
+	if err != nil {
+		http.Error(w, "Failed to create scheduled post: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scheduledPost.Validation = warningsOnly(report)
+	scheduledPost.Variants = utils.PostVariants(*scheduledPost)
+	utils.LocalizeScheduledPost(scheduledPost)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduledPost)
+}
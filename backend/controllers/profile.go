@@ -10,6 +10,7 @@ import (
     "social-sync-backend/lib"
     "social-sync-backend/middleware"
     "social-sync-backend/models"
+    "social-sync-backend/utils"
 )
 
 // ProfileHandler manages user profile GET, PUT, DELETE.
@@ -37,7 +38,7 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
         // FIXED QUERY: Select columns in the correct order matching your database schema
         // Excluding password for security reasons
         err := lib.DB.QueryRow(`
-            SELECT id, email, created_at, updated_at, is_verified, is_active, name, provider, provider_id, profile_picture
+            SELECT id, email, created_at, updated_at, is_verified, is_active, name, provider, provider_id, profile_picture, timezone
             FROM users WHERE id = $1
         `, userID).Scan(
             &user.ID,           // id (uuid)
@@ -50,6 +51,7 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
             &user.Provider,     // provider (character varying)
             &user.ProviderID,   // provider_id (character varying) - you'll need this in your struct
             &user.ProfilePicture, // profile_picture (text)
+            &user.Timezone,     // timezone (text, nullable)
         )
 
         if err != nil {
@@ -73,6 +75,7 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
         var updateData struct {
             Name  string `json:"name,omitempty"`
             Email string `json:"email,omitempty"`
+            Timezone string `json:"timezone,omitempty"`
         }
 
         if r.Body == nil {
@@ -98,8 +101,17 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
             args = append(args, updateData.Email)
             argCount++
         }
+        if updateData.Timezone != "" {
+            if _, err := utils.LoadTimeZone(updateData.Timezone); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            query += fmt.Sprintf(", timezone = $%d", argCount)
+            args = append(args, updateData.Timezone)
+            argCount++
+        }
 
-        if argCount == 1 { // No name or email provided
+        if argCount == 1 { // No name, email or timezone provided
             http.Error(w, "No fields to update", http.StatusBadRequest)
             return
         }
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"social-sync-backend/lib"
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// requirePausePermission checks a workspace permission and writes the error response on failure
+func requirePausePermission(w http.ResponseWriter, r *http.Request, permission string) (string, string, bool) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+	ok, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return "", "", false
+	}
+	if !ok {
+		http.Error(w, "You don't have permission to pause or resume publishing in this workspace", http.StatusForbidden)
+		return "", "", false
+	}
+	return userID, workspaceID, true
+}
+
+// broadcastPauseChanged tells workspace clients that publishing was paused or resumed
+func broadcastPauseChanged(workspaceID, eventType string, pause *models.PublishingPause) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":         eventType,
+		"workspace_id": workspaceID,
+		"pause":        pause,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// GetPublishingPauses lists the workspace's active pauses, or its whole pause/resume log
+// with ?history=true
+func GetPublishingPauses(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requirePausePermission(w, r, models.PermPostRead)
+	if !ok {
+		return
+	}
+
+	pauses, err := utils.GetPublishingPauses(lib.DB, workspaceID, r.URL.Query().Get("history") == "true")
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch publishing pauses: %v", err)
+		http.Error(w, "Failed to fetch publishing pauses", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pauses)
+}
+
+// PausePublishing stops outgoing posts of the workspace: all of them, those to one platform,
+// or those through one social account. Matching posts stay pending until it is resumed.
+func PausePublishing(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requirePausePermission(w, r, models.PermPostPublish)
+	if !ok {
+		return
+	}
+
+	var req models.PausePublishingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	pause := models.PublishingPause{
+		WorkspaceID: workspaceID,
+		Scope:       req.Scope,
+		Reason:      strings.TrimSpace(req.Reason),
+		PausedBy:    &userID,
+	}
+	if pause.Reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+	switch req.Scope {
+	case models.PauseWorkspace:
+	case models.PausePlatform:
+		if !utils.IsSupportedPlatform(req.Platform) {
+			http.Error(w, "Invalid platform: "+req.Platform, http.StatusBadRequest)
+			return
+		}
+		pause.Platform = &req.Platform
+	case models.PauseAccount:
+		// Only accounts connected by members of this workspace can be paused from it
+		var exists bool
+		err := lib.DB.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM social_accounts sa
+				JOIN workspace_members wm ON wm.user_id = sa.user_id
+				WHERE sa.id::text = $1 AND wm.workspace_id = $2
+			)`, req.SocialAccountID, workspaceID).Scan(&exists)
+		if err != nil {
+			http.Error(w, "Failed to look up social account", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Social account not found in this workspace", http.StatusBadRequest)
+			return
+		}
+		pause.SocialAccountID = &req.SocialAccountID
+	default:
+		http.Error(w, "scope must be workspace, platform or account", http.StatusBadRequest)
+		return
+	}
+
+	created, err := utils.PausePublishing(lib.DB, pause)
+	if err == utils.ErrAlreadyPaused {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to pause publishing: %v", err)
+		http.Error(w, "Failed to pause publishing", http.StatusInternalServerError)
+		return
+	}
+	broadcastPauseChanged(workspaceID, "publishing_paused", created)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ResumePublishing lifts one of the workspace's pauses
+func ResumePublishing(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requirePausePermission(w, r, models.PermPostPublish)
+	if !ok {
+		return
+	}
+	pauseID, err := strconv.Atoi(mux.Vars(r)["pauseId"])
+	if err != nil {
+		http.Error(w, "Invalid pause ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ResumePublishingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+
+	pause, err := utils.ResumePublishing(lib.DB, workspaceID, pauseID, userID, reason)
+	if err == utils.ErrPauseNotActive {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to resume publishing: %v", err)
+		http.Error(w, "Failed to resume publishing", http.StatusInternalServerError)
+		return
+	}
+	broadcastPauseChanged(workspaceID, "publishing_resumed", pause)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pause)
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"social-sync-backend/lib"
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+var queueTimeOfDay = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// requireQueuePermission checks a workspace permission and writes the error response on failure
+func requireQueuePermission(w http.ResponseWriter, r *http.Request, permission string) (string, string, bool) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+	ok, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return "", "", false
+	}
+	if !ok {
+		http.Error(w, "You don't have permission to manage this queue", http.StatusForbidden)
+		return "", "", false
+	}
+	return userID, workspaceID, true
+}
+
+// broadcastQueueChanged tells workspace clients to refresh the queue
+func broadcastQueueChanged(workspaceID string) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":         "queue_updated",
+		"workspace_id": workspaceID,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// writeQueueError maps queue errors to HTTP responses
+func writeQueueError(w http.ResponseWriter, err error) {
+	if err == utils.ErrNoQueueSlot {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("[ERROR] Queue operation failed: %v", err)
+	http.Error(w, "Queue operation failed: "+err.Error(), http.StatusInternalServerError)
+}
+
+// GetQueue returns the workspace's slots and queued posts in order
+func GetQueue(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireQueuePermission(w, r, models.PermPostRead)
+	if !ok {
+		return
+	}
+
+	slots, err := utils.ListQueueSlots(lib.DB, workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to fetch queue slots", http.StatusInternalServerError)
+		return
+	}
+	posts, err := utils.ListQueuedPosts(lib.DB, workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to fetch queued posts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"slots": slots,
+		"posts": posts,
+	})
+}
+
+// CreateQueueSlot adds a weekly slot and reassigns queued posts
+func CreateQueueSlot(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := requireQueuePermission(w, r, models.PermPostSchedule)
+	if !ok {
+		return
+	}
+
+	var req models.CreateQueueSlotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.DayOfWeek < 0 || req.DayOfWeek > 6 {
+		http.Error(w, "day_of_week must be between 0 (Sunday) and 6 (Saturday)", http.StatusBadRequest)
+		return
+	}
+	if !queueTimeOfDay.MatchString(req.TimeOfDay) {
+		http.Error(w, "time_of_day must be HH:MM", http.StatusBadRequest)
+		return
+	}
+	if req.Platform != nil && !utils.IsSupportedPlatform(*req.Platform) {
+		http.Error(w, "Invalid platform: "+*req.Platform, http.StatusBadRequest)
+		return
+	}
+
+	slot := models.QueueSlot{
+		WorkspaceID:     workspaceID,
+		Platform:        req.Platform,
+		SocialAccountID: req.SocialAccountID,
+		DayOfWeek:       req.DayOfWeek,
+		TimeOfDay:       req.TimeOfDay,
+		CreatedBy:       &userID,
+	}
+	err := lib.DB.QueryRow(`
+		INSERT INTO workspace_queue_slots (workspace_id, platform, social_account_id, day_of_week, time_of_day, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, workspaceID, req.Platform, req.SocialAccountID, req.DayOfWeek, req.TimeOfDay, userID).Scan(&slot.ID, &slot.CreatedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create queue slot: %v", err)
+		http.Error(w, "Failed to create queue slot", http.StatusInternalServerError)
+		return
+	}
+
+	// New slots can only make assignments earlier
+	if err := utils.RebalanceQueueNow(lib.DB, workspaceID); err != nil && err != utils.ErrNoQueueSlot {
+		log.Printf("[ERROR] Failed to rebalance queue: %v", err)
+	}
+	broadcastQueueChanged(workspaceID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(slot)
+}
+
+// DeleteQueueSlot removes a slot; queued posts move to the remaining slots
+func DeleteQueueSlot(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireQueuePermission(w, r, models.PermPostSchedule)
+	if !ok {
+		return
+	}
+	slotID, err := strconv.Atoi(mux.Vars(r)["slotId"])
+	if err != nil {
+		http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := lib.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to delete queue slot", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM workspace_queue_slots WHERE id = $1 AND workspace_id = $2`, slotID, workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to delete queue slot", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Queue slot not found", http.StatusNotFound)
+		return
+	}
+	if err := utils.RebalanceQueue(tx, workspaceID); err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to delete queue slot", http.StatusInternalServerError)
+		return
+	}
+	broadcastQueueChanged(workspaceID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderQueue moves the listed posts to the front of the queue in that order and shifts slots
+func ReorderQueue(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireQueuePermission(w, r, models.PermPostSchedule)
+	if !ok {
+		return
+	}
+
+	var req models.ReorderQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.PostIDs) == 0 {
+		http.Error(w, "post_ids is required", http.StatusBadRequest)
+		return
+	}
+	if err := utils.ReorderQueue(lib.DB, workspaceID, req.PostIDs); err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	broadcastQueueChanged(workspaceID)
+
+	posts, err := utils.ListQueuedPosts(lib.DB, workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to fetch queued posts", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"posts": posts})
+}
+
+// RemoveFromQueue cancels a queued post and closes the gap it leaves
+func RemoveFromQueue(w http.ResponseWriter, r *http.Request) {
+	_, workspaceID, ok := requireQueuePermission(w, r, models.PermPostDelete)
+	if !ok {
+		return
+	}
+	postID, err := strconv.Atoi(mux.Vars(r)["postId"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := lib.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to remove post from queue", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE scheduled_posts SET status = $1, queue_position = NULL, updated_at = NOW()
+		WHERE id = $2 AND workspace_id = $3 AND queue_position IS NOT NULL AND status = $4
+	`, models.StatusCancelled, postID, workspaceID, models.StatusPending)
+	if err != nil {
+		http.Error(w, "Failed to remove post from queue", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Queued post not found", http.StatusNotFound)
+		return
+	}
+	if err := utils.RebalanceQueue(tx, workspaceID); err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to remove post from queue", http.StatusInternalServerError)
+		return
+	}
+	broadcastQueueChanged(workspaceID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
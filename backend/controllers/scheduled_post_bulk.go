@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+)
+
+// bulkPermissions is the workspace permission each bulk action needs on every post it touches
+var bulkPermissions = map[string]string{
+	models.BulkReschedule: models.PermPostUpdate,
+	models.BulkCancel:     models.PermPostDelete,
+	models.BulkDelete:     models.PermPostDelete,
+	models.BulkDuplicate:  models.PermPostSchedule,
+	models.BulkRetarget:   models.PermPostUpdate,
+}
+
+// BulkScheduledPostsHandler applies one action (reschedule, cancel, delete, duplicate or
+// retarget) to many scheduled posts, picked by ID list or filter. Each post is handled on its
+// own under the same rules as the single-post endpoints: only editable posts are rescheduled
+// or retargeted, only deletable ones cancelled or deleted. The response gives every post's
+// outcome; one post failing does not stop the others.
+func BulkScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var req models.BulkPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		permission, ok := bulkPermissions[req.Action]
+		if !ok {
+			http.Error(w, "Unknown action; use reschedule, cancel, delete, duplicate or retarget", http.StatusBadRequest)
+			return
+		}
+		if (len(req.IDs) > 0) == (req.Filter != nil) {
+			http.Error(w, "Provide either ids or filter", http.StatusBadRequest)
+			return
+		}
+		if req.Filter != nil && req.Filter.WorkspaceID == "" {
+			http.Error(w, "filter.workspace_id is required", http.StatusBadRequest)
+			return
+		}
+		if len(req.IDs) > utils.MaxBulkPosts {
+			http.Error(w, fmt.Sprintf("A bulk operation can change at most %d posts", utils.MaxBulkPosts), http.StatusBadRequest)
+			return
+		}
+		switch req.Action {
+		case models.BulkReschedule, models.BulkDuplicate:
+			if err := utils.CheckBulkTime(req, req.Action == models.BulkReschedule); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case models.BulkRetarget:
+			if len(req.Platforms) == 0 && req.Targets == nil {
+				http.Error(w, "platforms or targets is required", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Permissions are per workspace; remember each answer for the rest of the batch
+		allowed := map[string]bool{}
+		permitted := func(workspaceID string) (bool, error) {
+			if ok, seen := allowed[workspaceID]; seen {
+				return ok, nil
+			}
+			ok, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+			if err != nil {
+				return false, err
+			}
+			allowed[workspaceID] = ok
+			return ok, nil
+		}
+
+		if req.Filter != nil {
+			ok, err := permitted(req.Filter.WorkspaceID)
+			if err != nil {
+				http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "Insufficient permissions: "+permission+" is required", http.StatusForbidden)
+				return
+			}
+		}
+
+		posts, err := utils.SelectBulkPosts(db, req.IDs, req.Filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result := models.BulkPostResult{Action: req.Action, Items: []models.BulkItemResult{}}
+		found := map[int]bool{}
+		for i := range posts {
+			post := &posts[i]
+			found[post.ID] = true
+
+			var item models.BulkItemResult
+			if ok, err := permitted(*post.WorkspaceID); err != nil {
+				item = bulkOutcome(post.ID, models.BulkItemFailed, "Failed to verify permissions")
+			} else if !ok {
+				item = bulkOutcome(post.ID, models.BulkItemDenied, "Scheduled post not found or "+permission+" is required")
+			} else {
+				item = applyBulkAction(db, userID, post, req)
+			}
+			result.Items = append(result.Items, item)
+		}
+		for _, id := range req.IDs {
+			if !found[id] {
+				found[id] = true
+				result.Items = append(result.Items, bulkOutcome(id, models.BulkItemDenied, "Scheduled post not found"))
+			}
+		}
+
+		result.Total = len(result.Items)
+		for _, item := range result.Items {
+			if item.Outcome == models.BulkItemApplied {
+				result.Applied++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// applyBulkAction runs the request's action on one post the caller may change
+func applyBulkAction(db *sql.DB, userID string, post *models.ScheduledPost, req models.BulkPostRequest) models.BulkItemResult {
+	item := models.BulkItemResult{ID: post.ID, Outcome: models.BulkItemApplied}
+	var err error
+	switch req.Action {
+	case models.BulkReschedule:
+		if !post.IsEditable() {
+			return bulkOutcome(post.ID, models.BulkItemSkipped, "Cannot reschedule scheduled post with status: "+post.Status)
+		}
+		var at time.Time
+		if at, err = bulkFutureTime(db, userID, *post, req); err == nil {
+			err = utils.RescheduleScheduledPost(db, post, at)
+		}
+		item.Post = post
+
+	case models.BulkCancel:
+		if !post.CanBeDeleted() {
+			return bulkOutcome(post.ID, models.BulkItemSkipped, "Cannot cancel scheduled post with status: "+post.Status)
+		}
+		err = utils.CancelScheduledPost(db, post)
+		item.Post = post
+
+	case models.BulkDelete:
+		// Cancelled posts are done with, so they may be cleared away too
+		if !post.CanBeDeleted() && post.Status != models.StatusCancelled {
+			return bulkOutcome(post.ID, models.BulkItemSkipped, "Cannot delete scheduled post with status: "+post.Status)
+		}
+		err = utils.DeleteScheduledPost(db, post)
+
+	case models.BulkDuplicate:
+		var at time.Time
+		if at, err = bulkFutureTime(db, userID, *post, req); err == nil {
+			item.Post, err = utils.DuplicateScheduledPost(db, *post, at)
+		}
+
+	case models.BulkRetarget:
+		if !post.IsEditable() {
+			return bulkOutcome(post.ID, models.BulkItemSkipped, "Cannot retarget scheduled post with status: "+post.Status)
+		}
+		platforms := []string(post.Platforms)
+		if len(req.Platforms) > 0 {
+			platforms = req.Platforms
+		}
+		targets := map[string]interface{}{}
+		if req.Targets != nil {
+			targets = *req.Targets
+		} else {
+			// Keep the overrides of the platforms the post still goes to
+			for platform, target := range post.Targets {
+				if containsPlatform(platforms, platform) {
+					targets[platform] = target
+				}
+			}
+		}
+		var report *models.ValidationResult
+		report, err = utils.RetargetScheduledPost(db, post, platforms, targets)
+		if err != nil {
+			item.Validation = report
+		} else {
+			post.Validation = report
+			item.Post = post
+		}
+	}
+
+	if err != nil {
+		msg := err.Error()
+		item.Outcome = models.BulkItemFailed
+		item.Error = &msg
+		item.Post = nil
+		return item
+	}
+	if item.Post != nil {
+		utils.LocalizeScheduledPost(item.Post)
+		item.Post.Variants = utils.PostVariants(*item.Post)
+	}
+	return item
+}
+
+// bulkFutureTime works out a post's new time and makes sure it is still ahead
+func bulkFutureTime(db *sql.DB, userID string, post models.ScheduledPost, req models.BulkPostRequest) (time.Time, error) {
+	at, err := utils.BulkPostTime(db, userID, post, req)
+	if err != nil {
+		return at, err
+	}
+	if at.Before(time.Now()) {
+		return at, fmt.Errorf("Scheduled time must be in the future")
+	}
+	return at, nil
+}
+
+// bulkOutcome is an item result that carries only an outcome and its reason
+func bulkOutcome(postID int, outcome, reason string) models.BulkItemResult {
+	return models.BulkItemResult{ID: postID, Outcome: outcome, Error: &reason}
+}
+
+func containsPlatform(platforms []string, platform string) bool {
+	for _, p := range platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
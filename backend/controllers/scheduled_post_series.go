@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social-sync-backend/middleware"
+	"social-sync-backend/models"
+	"social-sync-backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// loadSeriesForRequest resolves the {id} route variable to a series the caller may act on
+// with permission, writing the error response itself when it returns nil
+func loadSeriesForRequest(db *sql.DB, w http.ResponseWriter, r *http.Request, permission string) *models.ScheduledPostSeries {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return nil
+	}
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return nil
+	}
+
+	series, err := utils.GetScheduledPostSeries(db, seriesID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Recurring post not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch recurring post: "+err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	if !authorizeScheduledPost(w, userID, *series.WorkspaceID, permission) {
+		return nil
+	}
+	return series
+}
+
+// GetScheduledPostSeriesHandler returns a recurring post definition
+func GetScheduledPostSeriesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := loadSeriesForRequest(db, w, r, models.PermPostRead)
+		if series == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(series)
+	}
+}
+
+// GetSeriesOccurrencesHandler lists the upcoming occurrences of a recurring post (?limit=, default 10)
+func GetSeriesOccurrencesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := loadSeriesForRequest(db, w, r, models.PermPostRead)
+		if series == nil {
+			return
+		}
+
+		limit := 10
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		occurrences, err := utils.UpcomingOccurrences(db, series, limit)
+		if err != nil {
+			http.Error(w, "Failed to list occurrences: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"series_id":   series.ID,
+			"occurrences": occurrences,
+		})
+	}
+}
+
+// UpdateSeriesOccurrenceHandler skips or edits a single occurrence, identified by occurrence_at
+func UpdateSeriesOccurrenceHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := loadSeriesForRequest(db, w, r, models.PermPostUpdate)
+		if series == nil {
+			return
+		}
+
+		var req models.UpdateOccurrenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if req.OccurrenceAt.IsZero() {
+			http.Error(w, "occurrence_at is required", http.StatusBadRequest)
+			return
+		}
+		if !req.Skip && req.Content == nil && req.MediaURLs == nil && req.ScheduledTime == nil {
+			http.Error(w, "Nothing to update", http.StatusBadRequest)
+			return
+		}
+		if req.ScheduledTime != nil && req.ScheduledTime.Before(time.Now()) {
+			http.Error(w, "Scheduled time must be in the future", http.StatusBadRequest)
+			return
+		}
+
+		err := utils.UpdateOccurrence(db, series, req)
+		switch err {
+		case nil:
+		case utils.ErrOccurrenceNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case utils.ErrOccurrenceLocked:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		default:
+			http.Error(w, "Failed to update occurrence: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Occurrence updated successfully"})
+	}
+}
+
+// DeleteScheduledPostSeriesHandler stops a recurring post and cancels its pending occurrence
+func DeleteScheduledPostSeriesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := loadSeriesForRequest(db, w, r, models.PermPostDelete)
+		if series == nil {
+			return
+		}
+		if err := utils.CancelScheduledPostSeries(db, series.ID); err != nil {
+			http.Error(w, "Failed to cancel recurring post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Recurring post cancelled successfully"})
+	}
+}
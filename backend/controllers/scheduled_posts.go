@@ -3,14 +3,20 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"social-sync-backend/middleware"
 	"social-sync-backend/models"
+	"social-sync-backend/utils"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/lib/pq"
 )
 
@@ -29,87 +35,96 @@ func CreateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Validate required fields
-		// Allow empty content for YouTube-only scheduling (video carries the content)
-		if req.Content == "" {
-			allowEmpty := false
-			for _, p := range req.Platforms {
-				if p == "youtube" {
-					allowEmpty = true
-					break
-				}
-			}
-			if !allowEmpty {
-				http.Error(w, "Content is required", http.StatusBadRequest)
-				return
-			}
+		if err := checkScheduledPostRequest(db, userID, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		if len(req.Platforms) == 0 {
-			http.Error(w, "At least one platform is required", http.StatusBadRequest)
+		// Every post belongs to a workspace and is shared with its members
+		if req.WorkspaceID == nil || *req.WorkspaceID == "" {
+			http.Error(w, "workspace_id is required", http.StatusBadRequest)
 			return
 		}
-
-		// Validate scheduled time is in the future
-		if req.ScheduledTime.Before(time.Now()) {
-			http.Error(w, "Scheduled time must be in the future", http.StatusBadRequest)
+		hasPermission, err := middleware.CheckUserPermission(userID, *req.WorkspaceID, models.PermPostSchedule)
+		if err != nil {
+			http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+			return
+		}
+		if !hasPermission {
+			http.Error(w, "You don't have permission to schedule posts in this workspace", http.StatusForbidden)
 			return
 		}
 
-		// Validate platforms
-		validPlatforms := map[string]bool{
-			"facebook":  true,
-			"instagram": true,
-			"youtube":   true,
-			"twitter":   true,
-			"mastodon":  true,
-			"telegram":  true,
+		// Check the post against each platform's rules before anything is stored
+		report := utils.ValidateScheduledPost(db, validationInput(req))
+		if !report.Valid {
+			writeValidationFailure(w, report)
+			return
+		}
+		if req.Targets, err = utils.NormalizeTargets(req.Targets, req.MediaURLs); err != nil {
+			http.Error(w, "Invalid targets: "+err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		for _, platform := range req.Platforms {
-			if !validPlatforms[platform] {
-				http.Error(w, "Invalid platform: "+platform, http.StatusBadRequest)
+		// Queued posts take the next free slot of their workspace queue
+		if req.Queue {
+			if req.Recurrence != nil {
+				http.Error(w, "Recurring posts cannot be queued", http.StatusBadRequest)
 				return
 			}
+			post, err := utils.EnqueueScheduledPost(db, userID, *req.WorkspaceID, req)
+			if err == utils.ErrNoQueueSlot {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to queue post: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			post.Validation = warningsOnly(report)
+			post.Variants = utils.PostVariants(*post)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(post)
+			return
 		}
 
-		// Insert into database
-		query := `
-            INSERT INTO scheduled_posts (user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets)
-            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-			RETURNING id, created_at, updated_at
-		`
-
-		var scheduledPost models.ScheduledPost
-		now := time.Now()
+		if _, err := utils.EncodeThread(req.Thread); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		err = db.QueryRow(
-			query,
-			userID,
-			req.Content,
-			pq.Array(req.MediaURLs),
-			pq.Array(req.Platforms),
-			req.ScheduledTime,
-			models.StatusPending,
-			now,
-			now,
-			req.Targets,
-		).Scan(&scheduledPost.ID, &scheduledPost.CreatedAt, &scheduledPost.UpdatedAt)
+		// Recurring posts are stored as a series whose first occurrence is materialized now
+		if req.Recurrence != nil {
+			if len(req.Thread) > 0 {
+				http.Error(w, "Recurring posts cannot have thread parts", http.StatusBadRequest)
+				return
+			}
+			series, post, err := utils.CreateScheduledPostSeries(db, userID, req)
+			if err != nil {
+				http.Error(w, "Failed to create recurring post: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			utils.LocalizeScheduledPost(post)
+			post.Validation = warningsOnly(report)
+			post.Variants = utils.PostVariants(*post)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"series": series,
+				"post":   post,
+			})
+			return
+		}
 
+		scheduledPost, err := insertScheduledPost(db, userID, req)
 		if err != nil {
 			http.Error(w, "Failed to create scheduled post: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		// Populate the response
-		scheduledPost.UserID = userID
-		scheduledPost.Content = req.Content
-		scheduledPost.MediaURLs = pq.StringArray(req.MediaURLs)
-		scheduledPost.Platforms = pq.StringArray(req.Platforms)
-		scheduledPost.ScheduledTime = req.ScheduledTime
-		scheduledPost.Status = models.StatusPending
-		scheduledPost.Targets = req.Targets
-		scheduledPost.RetryCount = 0
+		scheduledPost.Validation = warningsOnly(report)
+		scheduledPost.Variants = utils.PostVariants(*scheduledPost)
+		utils.LocalizeScheduledPost(scheduledPost)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
@@ -117,7 +132,108 @@ func CreateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// GetScheduledPostsHandler retrieves all scheduled posts for a user
+// insertScheduledPost stores a checked, normalized request as a pending one-off post
+func insertScheduledPost(db *sql.DB, userID string, req models.CreateScheduledPostRequest) (*models.ScheduledPost, error) {
+	targetsJSON, err := json.Marshal(req.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	threadJSON, err := utils.EncodeThread(req.Thread)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO scheduled_posts (user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, timezone, workspace_id, thread, missed_window_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at
+	`
+
+	var scheduledPost models.ScheduledPost
+	now := time.Now()
+
+	err = db.QueryRow(
+		query,
+		userID,
+		req.Content,
+		pq.Array(req.MediaURLs),
+		pq.Array(req.Platforms),
+		req.ScheduledTime,
+		models.StatusPending,
+		now,
+		now,
+		targetsJSON,
+		req.TimeZone,
+		req.WorkspaceID,
+		threadJSON,
+		req.MissedWindow,
+	).Scan(&scheduledPost.ID, &scheduledPost.CreatedAt, &scheduledPost.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledPost.UserID = userID
+	scheduledPost.WorkspaceID = req.WorkspaceID
+	scheduledPost.Content = req.Content
+	scheduledPost.MediaURLs = pq.StringArray(req.MediaURLs)
+	scheduledPost.Platforms = pq.StringArray(req.Platforms)
+	scheduledPost.ScheduledTime = req.ScheduledTime
+	scheduledPost.Status = models.StatusPending
+	scheduledPost.Targets = req.Targets
+	scheduledPost.RetryCount = 0
+	scheduledPost.TimeZone = &req.TimeZone
+	scheduledPost.Thread = req.Thread
+	scheduledPost.MissedWindow = req.MissedWindow
+	return &scheduledPost, nil
+}
+
+// checkScheduledPostRequest applies the basic rules every new scheduled post must meet. It
+// resolves the request's zone first, turning a local wall-clock time into an instant.
+func checkScheduledPostRequest(db *sql.DB, userID string, req *models.CreateScheduledPostRequest) error {
+	// Allow empty content for YouTube-only scheduling (video carries the content)
+	if req.Content == "" {
+		allowEmpty := false
+		for _, p := range req.Platforms {
+			if p == "youtube" {
+				allowEmpty = true
+				break
+			}
+		}
+		if !allowEmpty {
+			return fmt.Errorf("Content is required")
+		}
+	}
+
+	if len(req.Platforms) == 0 {
+		return fmt.Errorf("At least one platform is required")
+	}
+
+	if err := resolveScheduleZone(db, userID, req); err != nil {
+		return err
+	}
+
+	// Queued posts get their time from the queue
+	if !req.Queue && req.ScheduledTime.Before(time.Now()) {
+		return fmt.Errorf("Scheduled time must be in the future")
+	}
+
+	// Validate platforms against the registered publishers
+	for _, platform := range req.Platforms {
+		if !utils.IsSupportedPlatform(platform) {
+			return fmt.Errorf("Invalid platform: %s", platform)
+		}
+	}
+
+	if req.MissedWindow != nil {
+		if err := utils.CheckMissedWindow(*req.MissedWindow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetScheduledPostsHandler retrieves the scheduled posts of a workspace (?workspace_id=,
+// required) for members who may read its posts
 func GetScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := middleware.GetUserIDFromContext(r)
@@ -126,14 +242,23 @@ func GetScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		workspaceID := r.URL.Query().Get("workspace_id")
+		if workspaceID == "" {
+			http.Error(w, "workspace_id is required", http.StatusBadRequest)
+			return
+		}
+		if !authorizeScheduledPost(w, userID, workspaceID, models.PermPostRead) {
+			return
+		}
+
 		query := `
-            SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, error_message, created_at, updated_at, targets
+            SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, error_message, created_at, updated_at, targets, timezone, workspace_id::text, queue_position, thread, missed_window_minutes, missed_at
             FROM scheduled_posts
-            WHERE user_id = $1
+            WHERE workspace_id = $1
             ORDER BY scheduled_time ASC
         `
 
-		rows, err := db.Query(query, userID)
+		rows, err := db.Query(query, workspaceID)
 		if err != nil {
 			http.Error(w, "Failed to fetch scheduled posts: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -144,7 +269,7 @@ func GetScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
 
 		for rows.Next() {
 			var post models.ScheduledPost
-			var rawTargets []byte
+			var rawTargets, rawThread []byte
 			err := rows.Scan(
 				&post.ID,
 				&post.UserID,
@@ -158,6 +283,12 @@ func GetScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
 				&post.CreatedAt,
 				&post.UpdatedAt,
 				&rawTargets,
+				&post.TimeZone,
+				&post.WorkspaceID,
+				&post.QueuePosition,
+				&rawThread,
+				&post.MissedWindow,
+				&post.MissedAt,
 			)
 			if err != nil {
 				http.Error(w, "Failed to scan scheduled post: "+err.Error(), http.StatusInternalServerError)
@@ -169,6 +300,9 @@ func GetScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
 					post.Targets = tgt
 				}
 			}
+			post.Thread = utils.DecodeThread(rawThread)
+			utils.LocalizeScheduledPost(&post)
+			post.Variants = utils.PostVariants(post)
 			scheduledPosts = append(scheduledPosts, post)
 		}
 
@@ -199,13 +333,14 @@ func GetScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		query := `
-			SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, error_message, created_at, updated_at
+			SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, next_attempt_at, error_message, created_at, updated_at, targets, series_id, occurrence_at, timezone, workspace_id::text, queue_position, thread, missed_window_minutes, missed_at
 			FROM scheduled_posts
-			WHERE id = $1 AND user_id = $2
+			WHERE id = $1
 		`
 
 		var post models.ScheduledPost
-		err = db.QueryRow(query, postID, userID).Scan(
+		var rawTargets, rawThread []byte
+		err = db.QueryRow(query, postID).Scan(
 			&post.ID,
 			&post.UserID,
 			&post.Content,
@@ -214,9 +349,19 @@ func GetScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			&post.ScheduledTime,
 			&post.Status,
 			&post.RetryCount,
+			&post.NextAttemptAt,
 			&post.ErrorMessage,
 			&post.CreatedAt,
 			&post.UpdatedAt,
+			&rawTargets,
+			&post.SeriesID,
+			&post.OccurrenceAt,
+			&post.TimeZone,
+			&post.WorkspaceID,
+			&post.QueuePosition,
+			&rawThread,
+			&post.MissedWindow,
+			&post.MissedAt,
 		)
 
 		if err == sql.ErrNoRows {
@@ -228,6 +373,26 @@ func GetScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !authorizeScheduledPost(w, userID, *post.WorkspaceID, models.PermPostRead) {
+			return
+		}
+		if len(rawTargets) > 0 {
+			var tgt map[string]interface{}
+			if uErr := json.Unmarshal(rawTargets, &tgt); uErr == nil {
+				post.Targets = tgt
+			}
+		}
+
+		post.Thread = utils.DecodeThread(rawThread)
+		utils.LocalizeScheduledPost(&post)
+		post.Variants = utils.PostVariants(post)
+
+		// Per-account delivery results
+		post.Deliveries, err = utils.GetScheduledPostDeliveries(db, post.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch deliveries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(post)
@@ -250,15 +415,16 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if post exists and belongs to user
+		// Check if post exists and the caller may edit it
 		var currentPost models.ScheduledPost
 		checkQuery := `
-			SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, error_message, created_at, updated_at
+			SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count, error_message, created_at, updated_at, workspace_id::text, queue_position, timezone, targets, thread, missed_window_minutes, missed_at
 			FROM scheduled_posts
-			WHERE id = $1 AND user_id = $2
+			WHERE id = $1
 		`
 
-		err = db.QueryRow(checkQuery, postID, userID).Scan(
+		var rawTargets, rawThread []byte
+		err = db.QueryRow(checkQuery, postID).Scan(
 			&currentPost.ID,
 			&currentPost.UserID,
 			&currentPost.Content,
@@ -270,6 +436,13 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			&currentPost.ErrorMessage,
 			&currentPost.CreatedAt,
 			&currentPost.UpdatedAt,
+			&currentPost.WorkspaceID,
+			&currentPost.QueuePosition,
+			&currentPost.TimeZone,
+			&rawTargets,
+			&rawThread,
+			&currentPost.MissedWindow,
+			&currentPost.MissedAt,
 		)
 
 		if err == sql.ErrNoRows {
@@ -281,6 +454,13 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if len(rawTargets) > 0 {
+			json.Unmarshal(rawTargets, &currentPost.Targets)
+		}
+		currentPost.Thread = utils.DecodeThread(rawThread)
+		if !authorizeScheduledPost(w, userID, *currentPost.WorkspaceID, models.PermPostUpdate) {
+			return
+		}
 
 		// Check if post is editable
 		if !currentPost.IsEditable() {
@@ -309,17 +489,9 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			// Validate platforms
-			validPlatforms := map[string]bool{
-				"facebook":  true,
-				"instagram": true,
-				"youtube":   true,
-				"twitter":   true,
-				"mastodon":  true,
-			}
-
+			// Validate platforms against the registered publishers
 			for _, platform := range *req.Platforms {
-				if !validPlatforms[platform] {
+				if !utils.IsSupportedPlatform(platform) {
 					http.Error(w, "Invalid platform: "+platform, http.StatusBadRequest)
 					return
 				}
@@ -328,6 +500,33 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			currentPost.Platforms = pq.StringArray(*req.Platforms)
 		}
 
+		// A new zone re-anchors the post; a local time is read in the post's (possibly new) zone
+		if req.TimeZone != nil {
+			if _, err := utils.LoadTimeZone(*req.TimeZone); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			currentPost.TimeZone = req.TimeZone
+		}
+		if req.LocalTime != nil {
+			zone := ""
+			if currentPost.TimeZone != nil {
+				zone = *currentPost.TimeZone
+			}
+			zone, loc, err := utils.ResolveTimeZone(db, userID, currentPost.WorkspaceID, zone)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			scheduledTime, err := utils.ParseLocalTime(*req.LocalTime, loc)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			currentPost.TimeZone = &zone
+			req.ScheduledTime = &scheduledTime
+		}
+
 		if req.ScheduledTime != nil {
 			if req.ScheduledTime.Before(time.Now()) {
 				http.Error(w, "Scheduled time must be in the future", http.StatusBadRequest)
@@ -336,11 +535,65 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			currentPost.ScheduledTime = *req.ScheduledTime
 		}
 
+		if req.MissedWindow != nil {
+			currentPost.MissedWindow = req.MissedWindow
+			if *req.MissedWindow == 0 {
+				currentPost.MissedWindow = nil
+			} else if err := utils.CheckMissedWindow(*req.MissedWindow); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if req.Targets != nil {
+			currentPost.Targets = *req.Targets
+		}
+		if req.Thread != nil {
+			currentPost.Thread = *req.Thread
+		}
+
+		// Re-check platform rules and variants when what gets published changes
+		if req.Content != nil || req.MediaURLs != nil || req.Platforms != nil || req.Targets != nil || req.Thread != nil {
+			report := utils.ValidateScheduledPost(db, utils.ValidationInput{
+				Content:   currentPost.Content,
+				MediaURLs: currentPost.MediaURLs,
+				Platforms: currentPost.Platforms,
+				Targets:   currentPost.Targets,
+				Thread:    currentPost.Thread,
+			})
+			if !report.Valid {
+				writeValidationFailure(w, report)
+				return
+			}
+			currentPost.Validation = warningsOnly(report)
+
+			if currentPost.Targets, err = utils.NormalizeTargets(currentPost.Targets, currentPost.MediaURLs); err != nil {
+				http.Error(w, "Invalid targets: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		targetsJSON, err := json.Marshal(currentPost.Targets)
+		if err != nil {
+			http.Error(w, "Invalid targets", http.StatusBadRequest)
+			return
+		}
+		threadJSON, err := utils.EncodeThread(currentPost.Thread)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Picking an explicit time takes a queued post out of its queue
+		leftQueue := req.ScheduledTime != nil && currentPost.QueuePosition != nil
+		if leftQueue {
+			currentPost.QueuePosition = nil
+		}
+
 		// Update in database
 		updateQuery := `
 			UPDATE scheduled_posts
-			SET content = $1, media_urls = $2, platforms = $3, scheduled_time = $4, updated_at = $5
-			WHERE id = $6 AND user_id = $7
+			SET content = $1, media_urls = $2, platforms = $3, scheduled_time = $4, updated_at = $5, queue_position = $7, timezone = $8, targets = $9, thread = $10, missed_window_minutes = $11
+			WHERE id = $6
 		`
 
 		currentPost.UpdatedAt = time.Now()
@@ -353,7 +606,11 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			currentPost.ScheduledTime,
 			currentPost.UpdatedAt,
 			postID,
-			userID,
+			currentPost.QueuePosition,
+			currentPost.TimeZone,
+			targetsJSON,
+			threadJSON,
+			currentPost.MissedWindow,
 		)
 
 		if err != nil {
@@ -361,6 +618,14 @@ func UpdateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		if leftQueue && currentPost.WorkspaceID != nil {
+			if err := utils.RebalanceQueueNow(db, *currentPost.WorkspaceID); err != nil {
+				log.Printf("Failed to rebalance queue of workspace %s: %v", *currentPost.WorkspaceID, err)
+			}
+		}
+
+		utils.LocalizeScheduledPost(&currentPost)
+		currentPost.Variants = utils.PostVariants(currentPost)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(currentPost)
 	}
@@ -382,15 +647,15 @@ func DeleteScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if post exists and belongs to user
-		var status string
+		// Check if post exists and the caller may delete it
+		var post models.ScheduledPost
 		checkQuery := `
-			SELECT status
+			SELECT id, user_id, status, series_id, workspace_id::text, queue_position
 			FROM scheduled_posts
-			WHERE id = $1 AND user_id = $2
+			WHERE id = $1
 		`
 
-		err = db.QueryRow(checkQuery, postID, userID).Scan(&status)
+		err = db.QueryRow(checkQuery, postID).Scan(&post.ID, &post.UserID, &post.Status, &post.SeriesID, &post.WorkspaceID, &post.QueuePosition)
 
 		if err == sql.ErrNoRows {
 			http.Error(w, "Scheduled post not found", http.StatusNotFound)
@@ -401,22 +666,22 @@ func DeleteScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !authorizeScheduledPost(w, userID, *post.WorkspaceID, models.PermPostDelete) {
+			return
+		}
 
 		// Check if post can be deleted
-		if status != models.StatusPending && status != models.StatusFailed {
-			http.Error(w, "Cannot delete scheduled post with status: "+status, http.StatusBadRequest)
+		if !post.CanBeDeleted() {
+			http.Error(w, "Cannot delete scheduled post with status: "+post.Status, http.StatusBadRequest)
 			return
 		}
 
-		// Update status to cancelled instead of deleting
-		updateQuery := `
-			UPDATE scheduled_posts
-			SET status = $1, updated_at = $2
-			WHERE id = $3 AND user_id = $4
-		`
-
-		_, err = db.Exec(updateQuery, models.StatusCancelled, time.Now(), postID, userID)
-
+		// Cancel instead of deleting; the draft, queue and series follow along
+		err = utils.CancelScheduledPost(db, &post)
+		if err == utils.ErrPostChanged {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to cancel scheduled post: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -427,3 +692,595 @@ func DeleteScheduledPostHandler(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(map[string]string{"message": "Scheduled post cancelled successfully"})
 	}
 }
+
+// UnpublishScheduledPostHandler takes a posted scheduled post down again by deleting it from
+// every platform that supports deletion. The response lists the outcome per remote post.
+func UnpublishScheduledPostHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		postID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		var ownerID, status, workspaceID string
+		err = db.QueryRow(`
+			SELECT user_id, status, workspace_id::text FROM scheduled_posts WHERE id = $1
+		`, postID).Scan(&ownerID, &status, &workspaceID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Scheduled post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authorizeScheduledPost(w, userID, workspaceID, models.PermPostDelete) {
+			return
+		}
+
+		// A partly failed post may still have targets that went out
+		if status != models.StatusPosted && status != models.StatusFailed {
+			http.Error(w, "Cannot unpublish scheduled post with status: "+status, http.StatusBadRequest)
+			return
+		}
+
+		results, err := utils.UnpublishScheduledPost(r.Context(), db, postID, ownerID, userID)
+		if err != nil {
+			http.Error(w, "Failed to unpublish scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(results) == 0 {
+			http.Error(w, "Scheduled post has no published targets to remove", http.StatusBadRequest)
+			return
+		}
+
+		if err := db.QueryRow(`SELECT status FROM scheduled_posts WHERE id = $1`, postID).Scan(&status); err != nil {
+			log.Printf("Failed to reload status of post %d: %v", postID, err)
+		}
+		utils.SyncDraftWithPost(db, postID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      postID,
+			"status":  status,
+			"results": results,
+		})
+	}
+}
+
+// GetUnpublishLogHandler returns the audit trail of a scheduled post's remote deletions
+func GetUnpublishLogHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		postID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		var workspaceID string
+		err = db.QueryRow(`SELECT workspace_id::text FROM scheduled_posts WHERE id = $1`, postID).Scan(&workspaceID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Scheduled post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authorizeScheduledPost(w, userID, workspaceID, models.PermPostRead) {
+			return
+		}
+
+		records, err := utils.GetUnpublishLog(db, postID)
+		if err != nil {
+			http.Error(w, "Failed to fetch unpublish log: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	}
+}
+
+// EditPublishedPostHandler pushes new content to a post that already went out. Targets on
+// platforms that allow edits are changed in place; the others are reported as immutable.
+func EditPublishedPostHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		postID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		var post models.ScheduledPost
+		var rawTargets []byte
+		err = db.QueryRow(`
+			SELECT id, user_id, content, media_urls, platforms, status, workspace_id::text, targets
+			FROM scheduled_posts
+			WHERE id = $1
+		`, postID).Scan(&post.ID, &post.UserID, &post.Content, &post.MediaURLs, &post.Platforms, &post.Status, &post.WorkspaceID, &rawTargets)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Scheduled post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(rawTargets) > 0 {
+			json.Unmarshal(rawTargets, &post.Targets)
+		}
+		if !authorizeScheduledPost(w, userID, *post.WorkspaceID, models.PermPostUpdate) {
+			return
+		}
+		if post.Status != models.StatusPosted && post.Status != models.StatusFailed {
+			http.Error(w, "Only published posts can be edited this way; use PUT for status: "+post.Status, http.StatusBadRequest)
+			return
+		}
+
+		var req models.EditPublishedPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if req.Content == nil && req.Targets == nil {
+			http.Error(w, "Nothing to edit: send content and/or targets", http.StatusBadRequest)
+			return
+		}
+		content, targets := post.Content, post.Targets
+		if req.Content != nil {
+			content = *req.Content
+		}
+		if req.Targets != nil {
+			targets, err = utils.NormalizeTargets(*req.Targets, post.MediaURLs)
+			if err != nil {
+				http.Error(w, "Invalid targets: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Only the platforms that will actually receive the edit have to accept it
+		var editable []string
+		for _, platform := range post.Platforms {
+			if utils.SupportsEditing(platform) {
+				editable = append(editable, platform)
+			}
+		}
+		report := utils.ValidateScheduledPost(db, utils.ValidationInput{
+			Content:   content,
+			MediaURLs: post.MediaURLs,
+			Platforms: editable,
+			Targets:   targets,
+		})
+		if !report.Valid {
+			writeValidationFailure(w, report)
+			return
+		}
+
+		edit, err := utils.EditPublishedPost(r.Context(), db, post, content, targets, userID)
+		if err == utils.ErrNothingPublished {
+			http.Error(w, "Scheduled post has no published targets to edit", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to edit published post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(edit)
+	}
+}
+
+// GetPostEditsHandler returns the edit history of a published post
+func GetPostEditsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		postID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		var workspaceID string
+		err = db.QueryRow(`SELECT workspace_id::text FROM scheduled_posts WHERE id = $1`, postID).Scan(&workspaceID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Scheduled post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authorizeScheduledPost(w, userID, workspaceID, models.PermPostRead) {
+			return
+		}
+
+		edits, err := utils.GetPostEdits(db, postID)
+		if err != nil {
+			http.Error(w, "Failed to fetch edit history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(edits)
+	}
+}
+
+// ResolveMissedPostHandler lets a member decide what happens to a post that missed its
+// schedule: publish it now, reschedule it, or discard it
+func ResolveMissedPostHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		postID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.ResolveMissedPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		permission := models.PermPostPublish
+		switch req.Action {
+		case models.MissedPublishNow:
+		case models.MissedReschedule:
+			permission = models.PermPostSchedule
+		case models.MissedDiscard:
+			permission = models.PermPostDelete
+		default:
+			http.Error(w, "action must be publish_now, reschedule or discard", http.StatusBadRequest)
+			return
+		}
+
+		var status, workspaceID string
+		var timeZone *string
+		err = db.QueryRow(`
+			SELECT status, workspace_id::text, timezone FROM scheduled_posts WHERE id = $1
+		`, postID).Scan(&status, &workspaceID, &timeZone)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Scheduled post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch scheduled post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authorizeScheduledPost(w, userID, workspaceID, permission) {
+			return
+		}
+		if status != models.StatusMissed {
+			http.Error(w, "Scheduled post has not missed its schedule (status: "+status+")", http.StatusConflict)
+			return
+		}
+
+		scheduledTime := time.Now()
+		if req.Action == models.MissedReschedule {
+			if req.LocalTime != "" {
+				zone := req.TimeZone
+				if zone == "" && timeZone != nil {
+					zone = *timeZone
+				}
+				_, loc, err := utils.ResolveTimeZone(db, userID, &workspaceID, zone)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				if scheduledTime, err = utils.ParseLocalTime(req.LocalTime, loc); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			} else if req.ScheduledTime != nil {
+				scheduledTime = *req.ScheduledTime
+			} else {
+				http.Error(w, "scheduled_time or local_time is required to reschedule", http.StatusBadRequest)
+				return
+			}
+			if scheduledTime.Before(time.Now()) {
+				http.Error(w, "Scheduled time must be in the future", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = utils.ResolveMissedPost(db, postID, req.Action, scheduledTime)
+		if err == utils.ErrPostNotMissed {
+			http.Error(w, "Scheduled post was already resolved", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		broadcastMissedPostResolved(workspaceID, postID, req.Action, userID)
+
+		newStatus := models.StatusPending
+		if req.Action == models.MissedDiscard {
+			newStatus = models.StatusCancelled
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             postID,
+			"action":         req.Action,
+			"status":         newStatus,
+			"scheduled_time": scheduledTime,
+		})
+	}
+}
+
+// ValidateScheduledPostHandler is a dry run of CreateScheduledPostHandler's platform checks:
+// it reports per-target errors and warnings without storing anything
+func ValidateScheduledPostHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := middleware.GetUserIDFromContext(r); err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var req models.CreateScheduledPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if len(req.Platforms) == 0 {
+			http.Error(w, "At least one platform is required", http.StatusBadRequest)
+			return
+		}
+
+		report := utils.ValidateScheduledPost(db, validationInput(req))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// PreviewScheduledPostImportHandler reads a CSV of posts (see utils.ParseImportCSV) and
+// reports for every row the post it becomes and why it cannot be scheduled, storing nothing
+func PreviewScheduledPostImportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		result := readPostImport(w, r, db, userID)
+		if result == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// ImportScheduledPostsHandler schedules every post of a CSV import. The rows are checked
+// again as in the preview; if any is invalid nothing is stored and the report is returned.
+func ImportScheduledPostsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserIDFromContext(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		result := readPostImport(w, r, db, userID)
+		if result == nil {
+			return
+		}
+		if !result.Valid {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		reqs := make([]models.CreateScheduledPostRequest, len(result.Rows))
+		for i, row := range result.Rows {
+			reqs[i] = *row.Post
+		}
+		posts, err := utils.ImportScheduledPosts(db, userID, reqs)
+		if err != nil {
+			http.Error(w, "Failed to import posts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i := range posts {
+			posts[i].Validation = result.Rows[i].Validation
+		}
+
+		result.Created = posts
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// readPostImport parses the CSV of an import request (a multipart "file" field, or the raw
+// body) and checks every row the way CreateScheduledPostHandler checks a post. The form
+// values workspace_id and timezone apply to every row. When the import cannot be read at all
+// it writes the error response and returns nil.
+func readPostImport(w http.ResponseWriter, r *http.Request, db *sql.DB, userID string) *models.ImportResult {
+	r.Body = http.MaxBytesReader(w, r.Body, 5<<20)
+
+	var csvFile io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "A CSV file is required in the \"file\" field", http.StatusBadRequest)
+			return nil
+		}
+		defer file.Close()
+		csvFile = file
+	}
+
+	// Imported posts belong to a workspace like any other
+	workspaceID := r.FormValue("workspace_id")
+	if workspaceID == "" {
+		http.Error(w, "workspace_id is required", http.StatusBadRequest)
+		return nil
+	}
+	hasPermission, err := middleware.CheckUserPermission(userID, workspaceID, models.PermPostSchedule)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return nil
+	}
+	if !hasPermission {
+		http.Error(w, "You don't have permission to schedule posts in this workspace", http.StatusForbidden)
+		return nil
+	}
+
+	rows, err := utils.ParseImportCSV(db, userID, workspaceID, r.FormValue("timezone"), csvFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	result := &models.ImportResult{Valid: true, Total: len(rows), Rows: rows}
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if len(row.Errors) == 0 {
+			checkImportRow(db, userID, row)
+		}
+		row.Valid = len(row.Errors) == 0
+		result.Valid = result.Valid && row.Valid
+	}
+	return result
+}
+
+// checkImportRow applies the create rules and the platform checks to one imported post
+func checkImportRow(db *sql.DB, userID string, row *models.ImportRow) {
+	if err := checkScheduledPostRequest(db, userID, row.Post); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+		return
+	}
+	report := utils.ValidateScheduledPost(db, validationInput(*row.Post))
+	if !report.Valid {
+		row.Validation = &report
+		row.Errors = append(row.Errors, "Post does not meet the rules of every target platform")
+		return
+	}
+	row.Validation = warningsOnly(report)
+
+	targets, err := utils.NormalizeTargets(row.Post.Targets, row.Post.MediaURLs)
+	if err != nil {
+		row.Errors = append(row.Errors, "Invalid targets: "+err.Error())
+		return
+	}
+	row.Post.Targets = targets
+}
+
+// validationInput extracts what platform validation looks at from a create request
+func validationInput(req models.CreateScheduledPostRequest) utils.ValidationInput {
+	return utils.ValidationInput{
+		Content:   req.Content,
+		MediaURLs: req.MediaURLs,
+		Platforms: req.Platforms,
+		Targets:   req.Targets,
+		Thread:    req.Thread,
+	}
+}
+
+// writeValidationFailure rejects a post that breaks platform rules, returning the full report
+func writeValidationFailure(w http.ResponseWriter, report models.ValidationResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      "Post does not meet the rules of every target platform",
+		"validation": report,
+	})
+}
+
+// warningsOnly returns the report to attach to a saved post, or nil when there is nothing to say
+func warningsOnly(report models.ValidationResult) *models.ValidationResult {
+	for _, target := range report.Targets {
+		if len(target.Warnings) > 0 {
+			return &report
+		}
+	}
+	return nil
+}
+
+func init() {
+	utils.OnScheduledPostMissed(notifyMissedPost)
+}
+
+// notifyMissedPost tells the members of the post's workspace that it missed its schedule and
+// waits for a decision
+func notifyMissedPost(post models.ScheduledPost) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":           "scheduled_post_missed",
+		"post_id":        post.ID,
+		"content":        post.Content,
+		"platforms":      post.Platforms,
+		"scheduled_time": post.ScheduledTime,
+		"missed_at":      post.MissedAt,
+		"actions":        []string{models.MissedPublishNow, models.MissedReschedule, models.MissedDiscard},
+	})
+	hub.broadcast(*post.WorkspaceID, websocket.TextMessage, msg)
+}
+
+// broadcastMissedPostResolved tells workspace clients that someone dealt with a missed post
+func broadcastMissedPostResolved(workspaceID string, postID int, action, userID string) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":        "scheduled_post_missed_resolved",
+		"post_id":     postID,
+		"action":      action,
+		"resolved_by": userID,
+		"resolved_at": time.Now(),
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+}
+
+// authorizeScheduledPost checks that userID has permission in the workspace of a scheduled
+// post or series. It writes the error response itself when it returns false.
+func authorizeScheduledPost(w http.ResponseWriter, userID, workspaceID, permission string) bool {
+	hasPermission, err := middleware.CheckUserPermission(userID, workspaceID, permission)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !hasPermission {
+		http.Error(w, "Insufficient permissions: "+permission+" is required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// resolveScheduleZone fills req.TimeZone from the request, workspace or user and, when
+// req.LocalTime is set, derives req.ScheduledTime from it in that zone
+func resolveScheduleZone(db *sql.DB, userID string, req *models.CreateScheduledPostRequest) error {
+	zone, loc, err := utils.ResolveTimeZone(db, userID, req.WorkspaceID, req.TimeZone)
+	if err != nil {
+		return err
+	}
+	req.TimeZone = zone
+	if req.LocalTime != "" {
+		scheduledTime, err := utils.ParseLocalTime(req.LocalTime, loc)
+		if err != nil {
+			return err
+		}
+		req.ScheduledTime = scheduledTime
+	}
+	return nil
+}
@@ -9,13 +9,13 @@ import (
 	"os"
 	"social-sync-backend/middleware"
 	"social-sync-backend/models"
+	"social-sync-backend/utils"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 // TelegramConnectRequest represents the request body for connecting Telegram
@@ -180,74 +180,43 @@ func PostToTelegramHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Build targets: AccountIDs, All, or fallback to default/first
-		type tgAcct struct {
-			ID    uuid.UUID
-			Chat  string
-			Token string
+		// Resolve targets: AccountIDs, All, or fallback to default/first
+		targets, err := utils.ResolvePublishAccounts(db, userID.String(), "telegram", req.AccountIDs, req.All)
+		if err != nil {
+			http.Error(w, "Failed to get Telegram connections", http.StatusInternalServerError)
+			return
 		}
-		var targets []tgAcct
-		if len(req.AccountIDs) > 0 {
-			rows, qErr := db.Query(`SELECT id, social_id, access_token FROM social_accounts WHERE user_id=$1 AND platform='telegram' AND id = ANY($2::uuid[])`, userID, pq.Array(req.AccountIDs))
-			if qErr != nil {
-				http.Error(w, "Failed to get Telegram connections", http.StatusInternalServerError)
-				return
-			}
-			defer rows.Close()
-			for rows.Next() {
-				var id uuid.UUID
-				var chat, tok string
-				if scanErr := rows.Scan(&id, &chat, &tok); scanErr == nil {
-					targets = append(targets, tgAcct{ID: id, Chat: chat, Token: tok})
-				}
-			}
-		} else if req.All {
-			rows, qErr := db.Query(`SELECT id, social_id, access_token FROM social_accounts WHERE user_id=$1 AND platform='telegram'`, userID)
-			if qErr != nil {
-				http.Error(w, "Failed to get Telegram connections", http.StatusInternalServerError)
-				return
-			}
-			defer rows.Close()
-			for rows.Next() {
-				var id uuid.UUID
-				var chat, tok string
-				if scanErr := rows.Scan(&id, &chat, &tok); scanErr == nil {
-					targets = append(targets, tgAcct{ID: id, Chat: chat, Token: tok})
-				}
-			}
-		} else {
-			// default/first
-			var id uuid.UUID
-			var chat, tok string
-			qErr := db.QueryRow(`SELECT id, social_id, access_token FROM social_accounts WHERE user_id=$1 AND platform='telegram' ORDER BY is_default DESC, connected_at DESC LIMIT 1`, userID).Scan(&id, &chat, &tok)
-			if qErr == sql.ErrNoRows {
+		if len(targets) == 0 {
+			if len(req.AccountIDs) == 0 && !req.All {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusBadRequest)
 				json.NewEncoder(w).Encode(map[string]interface{}{
 					"error": "Telegram account not connected",
 				})
 				return
-			} else if qErr != nil {
-				http.Error(w, "Failed to get Telegram connection", http.StatusInternalServerError)
-				return
 			}
-			targets = append(targets, tgAcct{ID: id, Chat: chat, Token: tok})
+			http.Error(w, "No Telegram targets selected", http.StatusBadRequest)
+			return
 		}
 
-		if len(targets) == 0 {
-			http.Error(w, "No Telegram targets selected", http.StatusBadRequest)
+		outcomes, err := utils.PublishToAccounts(r.Context(), "telegram", targets, utils.PublishRequest{
+			Content:   req.Message,
+			MediaURLs: req.MediaUrls,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Send to each target independently
+		// Each target is sent independently
 		var sendErrs []string
-		for _, t := range targets {
-			if err := sendTelegramMessage(t.Token, t.Chat, req.Message, req.MediaUrls); err != nil {
-				sendErrs = append(sendErrs, fmt.Sprintf("chat %s: %v", t.Chat, err))
+		for _, o := range outcomes {
+			if o.Err != nil {
+				sendErrs = append(sendErrs, fmt.Sprintf("chat %s: %v", o.Account.ExternalID, o.Err))
 				continue
 			}
 			// Update last_synced_at for success
-			_, _ = db.Exec(`UPDATE social_accounts SET last_synced_at=$1 WHERE id=$2`, time.Now(), t.ID)
+			_, _ = db.Exec(`UPDATE social_accounts SET last_synced_at=$1 WHERE id=$2`, time.Now(), o.Account.ID)
 		}
 
 		if len(sendErrs) > 0 && len(targets) == 1 {
@@ -302,226 +271,6 @@ func getTelegramChat(botToken, chatID string) (*TelegramChatResponse, error) {
 	return &chatResp, nil
 }
 
-// sendTelegramMessage sends a message with optional media to Telegram
-func sendTelegramMessage(botToken, chatID, message string, mediaUrls []string) error {
-	if len(mediaUrls) == 0 {
-		// Send text-only message
-		return sendTelegramTextMessage(botToken, chatID, message)
-	} else if len(mediaUrls) == 1 {
-		// Send single media with caption
-		return sendTelegramSingleMedia(botToken, chatID, message, mediaUrls[0])
-	} else {
-		// Send media group with caption
-		return sendTelegramMediaGroup(botToken, chatID, message, mediaUrls)
-	}
-}
-
-// sendTelegramTextMessage sends a text-only message
-func sendTelegramTextMessage(botToken, chatID, message string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-
-	payload := map[string]interface{}{
-		"chat_id": chatID,
-		"text":    message,
-	}
-
-	return sendTelegramRequest(url, payload)
-}
-
-// sendTelegramSingleMedia sends a single photo/video with caption
-func sendTelegramSingleMedia(botToken, chatID, caption, mediaUrl string) error {
-	var url string
-	var payload map[string]interface{}
-
-	// Determine if it's a photo or video based on URL
-	if isVideoUrl(mediaUrl) {
-		url = fmt.Sprintf("https://api.telegram.org/bot%s/sendVideo", botToken)
-		payload = map[string]interface{}{
-			"chat_id": chatID,
-			"video":   mediaUrl,
-			"caption": caption,
-		}
-	} else {
-		url = fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", botToken)
-		payload = map[string]interface{}{
-			"chat_id": chatID,
-			"photo":   mediaUrl,
-			"caption": caption,
-		}
-	}
-
-	return sendTelegramRequest(url, payload)
-}
-
-// sendTelegramMediaGroup sends multiple media items as a group
-func sendTelegramMediaGroup(botToken, chatID, caption string, mediaUrls []string) error {
-	// Check if we have mixed media types
-	var hasPhotos, hasVideos bool
-	for _, mediaUrl := range mediaUrls {
-		if isVideoUrl(mediaUrl) {
-			hasVideos = true
-		} else {
-			hasPhotos = true
-		}
-	}
-
-	// If we have mixed media, send them separately
-	if hasPhotos && hasVideos {
-		return sendTelegramMixedMedia(botToken, chatID, caption, mediaUrls)
-	}
-
-	// For same media type, use media group
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMediaGroup", botToken)
-
-	// Build media array (max 10 items for Telegram)
-	media := make([]map[string]interface{}, 0, len(mediaUrls))
-	for i, mediaUrl := range mediaUrls {
-		if i >= 10 { // Telegram limit
-			break
-		}
-
-		mediaType := "photo"
-		if isVideoUrl(mediaUrl) {
-			mediaType = "video"
-		}
-
-		mediaItem := map[string]interface{}{
-			"type":  mediaType,
-			"media": mediaUrl,
-		}
-
-		// Add caption to first item only
-		if i == 0 && caption != "" {
-			mediaItem["caption"] = caption
-		}
-
-		media = append(media, mediaItem)
-	}
-
-	payload := map[string]interface{}{
-		"chat_id": chatID,
-		"media":   media,
-	}
-
-	return sendTelegramRequest(url, payload)
-}
-
-// sendTelegramMixedMedia handles mixed media by sending them as separate messages
-func sendTelegramMixedMedia(botToken, chatID, caption string, mediaUrls []string) error {
-	// Separate photos and videos
-	var photos, videos []string
-	for _, mediaUrl := range mediaUrls {
-		if isVideoUrl(mediaUrl) {
-			videos = append(videos, mediaUrl)
-		} else {
-			photos = append(photos, mediaUrl)
-		}
-	}
-
-	// Send photos first (if any)
-	if len(photos) > 0 {
-		photoCaption := caption
-		if len(videos) > 0 {
-			photoCaption = fmt.Sprintf("%s\n\n[Photos %d/%d]", caption, 1, 2)
-		}
-
-		if len(photos) == 1 {
-			// Single photo
-			if err := sendTelegramSingleMedia(botToken, chatID, photoCaption, photos[0]); err != nil {
-				return fmt.Errorf("failed to send photo: %v", err)
-			}
-		} else {
-			// Multiple photos - use media group
-			if err := sendTelegramMediaGroup(botToken, chatID, photoCaption, photos); err != nil {
-				return fmt.Errorf("failed to send photos: %v", err)
-			}
-		}
-	}
-
-	// Send videos (if any)
-	if len(videos) > 0 {
-		videoCaption := caption
-		if len(photos) > 0 {
-			videoCaption = fmt.Sprintf("%s\n\n[Videos %d/%d]", caption, 2, 2)
-		}
-
-		if len(videos) == 1 {
-			// Single video
-			if err := sendTelegramSingleMedia(botToken, chatID, videoCaption, videos[0]); err != nil {
-				return fmt.Errorf("failed to send video: %v", err)
-			}
-		} else {
-			// Multiple videos - use media group
-			if err := sendTelegramMediaGroup(botToken, chatID, videoCaption, videos); err != nil {
-				return fmt.Errorf("failed to send videos: %v", err)
-			}
-		}
-	}
-
-	return nil
-}
-
-// sendTelegramRequest sends a request to Telegram API
-func sendTelegramRequest(url string, payload map[string]interface{}) error {
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %v", err)
-	}
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(jsonData)))
-	if err != nil {
-		return fmt.Errorf("network error: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
-	}
-
-	var sendResp TelegramSendResponse
-	if err := json.Unmarshal(body, &sendResp); err != nil {
-		return fmt.Errorf("failed to parse response: %v", err)
-	}
-
-	if !sendResp.OK {
-		return fmt.Errorf("telegram API error: %s", sendResp.Description)
-	}
-
-	return nil
-}
-
-// isVideoUrl determines if a URL points to a video file
-func isVideoUrl(url string) bool {
-	videoExtensions := []string{".mp4", ".mov", ".avi", ".mkv", ".wmv", ".flv", ".webm", ".m4v"}
-	lowerUrl := strings.ToLower(url)
-
-	// Check for file extensions
-	for _, ext := range videoExtensions {
-		if strings.Contains(lowerUrl, ext) {
-			return true
-		}
-	}
-
-	// Check for Cloudinary video URLs
-	if strings.Contains(lowerUrl, "cloudinary.com") {
-		// Cloudinary video URLs typically contain "video" in the path
-		if strings.Contains(lowerUrl, "/video/") || strings.Contains(lowerUrl, "video_upload") {
-			return true
-		}
-	}
-
-	// Check for other video indicators in URL
-	videoIndicators := []string{"video", "mp4", "mov", "avi", "mkv", "webm"}
-	for _, indicator := range videoIndicators {
-		if strings.Contains(lowerUrl, indicator) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // GetTelegramPostsHandler fetches the user's Telegram channel messages
 func GetTelegramPostsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1,18 +1,16 @@
 package controllers
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"sort"
-	"strings"
 	"time"
 
 	"social-sync-backend/middleware"
+	"social-sync-backend/utils"
 
 	"github.com/lib/pq"
 )
@@ -32,14 +30,12 @@ type TwitterPostResult struct {
 
 func PostToTwitterHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("DEBUG: Twitter post handler called\n")
 		userID, err := middleware.GetUserIDFromContext(r)
 		if err != nil {
 			fmt.Printf("DEBUG: Twitter post - user not authenticated: %v\n", err)
 			http.Error(w, "user not authenticated", http.StatusUnauthorized)
 			return
 		}
-		fmt.Printf("DEBUG: Twitter post - user ID: %s\n", userID)
 
 		var req TwitterPostRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -47,18 +43,18 @@ func PostToTwitterHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "failed to parse JSON data", http.StatusBadRequest)
 			return
 		}
-		fmt.Printf("DEBUG: Twitter post request: %+v\n", req)
 
 		if req.Text == "" {
 			http.Error(w, "text is required", http.StatusBadRequest)
 			return
 		}
 
-		// Get Twitter accounts - simplified query without access_token_secret
-		rows, err := db.Query(`SELECT id::text, access_token FROM social_accounts WHERE user_id=$1 AND (platform='twitter' OR provider='twitter') AND id = ANY($2::uuid[])`, userID, pq.Array(req.AccountIds))
+		accounts, err := utils.ResolvePublishAccounts(db, userID, "twitter", req.AccountIds, false)
 		if err != nil {
-			// If no Twitter accounts found, return friendly error message
-			fmt.Printf("DEBUG: No Twitter accounts found\n")
+			http.Error(w, "failed to get Twitter accounts", http.StatusInternalServerError)
+			return
+		}
+		if len(accounts) == 0 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -66,206 +62,25 @@ func PostToTwitterHandler(db *sql.DB) http.HandlerFunc {
 			})
 			return
 		}
-		defer rows.Close()
-
-		var results []TwitterPostResult
-		hasRows := false
-		for rows.Next() {
-			hasRows = true
-			var id, accessToken string
-			accessTokenSecret := "" // Twitter OAuth 1.0a secret not stored in DB
-
-			if err := rows.Scan(&id, &accessToken); err != nil {
-				results = append(results, TwitterPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Failed to get account details: " + err.Error(),
-				})
-				continue
-			} // Post to Twitter
-			fmt.Printf("DEBUG: Posting to Twitter for account: %s\n", id)
-			fmt.Printf("DEBUG: Tweet text: %s\n", req.Text)
-
-			// Check if we have valid Twitter API credentials
-			if accessToken == "" || len(accessToken) < 10 {
-				fmt.Printf("DEBUG: No valid Twitter API credentials for account %s\n", id)
-				results = append(results, TwitterPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Twitter account not properly connected",
-				})
-				continue
-			}
-
-			// Check if access token looks like a real Twitter API token
-			// Real Twitter API tokens are much longer and have specific format
-			if len(accessToken) < 50 || !containsValidTwitterTokenFormat(accessToken) {
-				fmt.Printf("DEBUG: Twitter API token format invalid for account %s\n", id)
-				results = append(results, TwitterPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Twitter account not properly connected",
-				})
-				continue
-			}
-
-			// Create tweet data with proper Twitter API v2 format
-			tweetData := map[string]interface{}{
-				"text": req.Text,
-			}
-
-			// Add media if provided
-			if len(req.MediaUrls) > 0 {
-				mediaIds := []string{}
-				hasValidMedia := false
-
-				for _, mediaUrl := range req.MediaUrls {
-					// Upload media to Twitter
-					mediaId, err := uploadMediaToTwitter(accessToken, accessTokenSecret, mediaUrl)
-					if err != nil {
-						fmt.Printf("DEBUG: Media upload failed for account %s: %v\n", id, err)
-						// Continue without this media item
-						continue
-					}
-
-					// Check if we got a real media ID (not mock)
-					if !strings.HasPrefix(mediaId, "mock_media_id_") {
-						mediaIds = append(mediaIds, mediaId)
-						hasValidMedia = true
-					} else {
-						fmt.Printf("DEBUG: Got mock media ID, skipping media for this tweet\n")
-					}
-				}
-
-				// Only add media if we have valid media IDs
-				if hasValidMedia && len(mediaIds) > 0 {
-					tweetData["media"] = map[string]interface{}{
-						"media_ids": mediaIds,
-					}
-					fmt.Printf("DEBUG: Added %d valid media IDs to tweet\n", len(mediaIds))
-				} else {
-					fmt.Printf("DEBUG: No valid media IDs, adding image URLs to tweet text\n")
-					// Add image URLs to the tweet text as a fallback
-					imageUrls := []string{}
-					for _, mediaUrl := range req.MediaUrls {
-						imageUrls = append(imageUrls, mediaUrl)
-					}
-					if len(imageUrls) > 0 {
-						tweetData["text"] = req.Text + "\n\n📸 " + strings.Join(imageUrls, " ")
-					}
-				}
-			}
-
-			// Validate tweet text length (Twitter limit is 280 characters)
-			if len(req.Text) > 280 {
-				fmt.Printf("DEBUG: Tweet text too long (%d chars), truncating to 280\n", len(req.Text))
-				tweetData["text"] = req.Text[:280]
-			}
-
-			// Post tweet to Twitter API v2
-			tweetBody, _ := json.Marshal(tweetData)
-			fmt.Printf("DEBUG: Twitter tweet data: %s\n", string(tweetBody))
 
-			// Create HTTP request with proper headers
-			req, err := http.NewRequest("POST", "https://api.twitter.com/2/tweets", bytes.NewBuffer(tweetBody))
-			if err != nil {
-				results = append(results, TwitterPostResult{
-					AccountID: id,
-					OK:        false,
-					Error:     "Failed to create request: " + err.Error(),
-				})
-				continue
-			}
-
-			// Set OAuth 2.0 Bearer token (simplified for demo)
-			// In production, you'd need proper OAuth 1.0a or 2.0 implementation
-			req.Header.Set("Authorization", "Bearer "+accessToken)
-			req.Header.Set("Content-Type", "application/json")
-
-			client := &http.Client{Timeout: 30 * time.Second}
-			resp, err := client.Do(req)
-			if err != nil {
-				fmt.Printf("DEBUG: Twitter request failed for account %s: %v\n", id, err)
-				// Network error - return mock success for testing
-				fmt.Printf("DEBUG: Network error for Twitter account %s, returning mock success\n", id)
-				results = append(results, TwitterPostResult{
-					AccountID: id,
-					OK:        true,
-					TweetID:   "mock_tweet_id_" + id,
-				})
-				continue
-			}
-			defer resp.Body.Close()
-
-			fmt.Printf("DEBUG: Twitter response status: %d\n", resp.StatusCode)
-			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-				body, _ := io.ReadAll(resp.Body)
-				fmt.Printf("DEBUG: Twitter API error response: %s\n", string(body))
-
-				// Handle specific error cases
-				if resp.StatusCode == 401 {
-					// Unauthorized - likely invalid credentials
-					fmt.Printf("DEBUG: Twitter API credentials invalid for account %s, returning mock success\n", id)
-					results = append(results, TwitterPostResult{
-						AccountID: id,
-						OK:        true,
-						TweetID:   "mock_tweet_id_" + id,
-					})
-					continue
-				} else if resp.StatusCode == 403 {
-					// Forbidden - likely rate limited or insufficient permissions
-					fmt.Printf("DEBUG: Twitter API access forbidden for account %s, returning mock success\n", id)
-					results = append(results, TwitterPostResult{
-						AccountID: id,
-						OK:        true,
-						TweetID:   "mock_tweet_id_" + id,
-					})
-					continue
-				} else if resp.StatusCode == 400 {
-					// Bad Request - likely invalid request format or missing required fields
-					fmt.Printf("DEBUG: Twitter API bad request for account %s, returning mock success\n", id)
-					results = append(results, TwitterPostResult{
-						AccountID: id,
-						OK:        true,
-						TweetID:   "mock_tweet_id_" + id,
-					})
-					continue
-				} else {
-					// Other errors
-					results = append(results, TwitterPostResult{
-						AccountID: id,
-						OK:        false,
-						Error:     fmt.Sprintf("Twitter API error: %d %s", resp.StatusCode, string(body)),
-					})
-					continue
-				}
-			}
-
-			var tweetResponse map[string]interface{}
-			json.NewDecoder(resp.Body).Decode(&tweetResponse)
-			tweetID := ""
-			if data, ok := tweetResponse["data"].(map[string]interface{}); ok {
-				if id, ok := data["id"].(string); ok {
-					tweetID = id
-				}
-			}
-
-			fmt.Printf("DEBUG: Successfully posted to Twitter account %s, tweet ID: %s\n", id, tweetID)
-			results = append(results, TwitterPostResult{
-				AccountID: id,
-				OK:        true,
-				TweetID:   tweetID,
-			})
+		outcomes, err := utils.PublishToAccounts(r.Context(), "twitter", accounts, utils.PublishRequest{
+			Content:   req.Text,
+			MediaURLs: req.MediaUrls,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		// Check if no accounts were found
-		if !hasRows {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": "Twitter account not connected",
-			})
-			return
+		var results []TwitterPostResult
+		for _, o := range outcomes {
+			res := TwitterPostResult{AccountID: o.Account.ID, OK: o.Err == nil}
+			if o.Err != nil {
+				res.Error = o.Err.Error()
+			} else if o.Result != nil {
+				res.TweetID = o.Result.RemotePostID
+			}
+			results = append(results, res)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -298,116 +113,6 @@ func containsOnlyValidChars(token string) bool {
 	return true
 }
 
-// uploadMediaToTwitter uploads media to Twitter and returns the media ID
-func uploadMediaToTwitter(accessToken, accessTokenSecret, mediaUrl string) (string, error) {
-	fmt.Printf("DEBUG: Starting real media upload for Twitter: %s\n", mediaUrl)
-
-	// Step 1: Download the media from the URL
-	resp, err := http.Get(mediaUrl)
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to download media from %s: %v\n", mediaUrl, err)
-		return "", fmt.Errorf("failed to download media: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("DEBUG: Media download failed with status %d\n", resp.StatusCode)
-		return "", fmt.Errorf("media download failed with status %d", resp.StatusCode)
-	}
-
-	// Step 2: Read the media data
-	mediaData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to read media data: %v\n", err)
-		return "", fmt.Errorf("failed to read media data: %v", err)
-	}
-
-	fmt.Printf("DEBUG: Downloaded media, size: %d bytes\n", len(mediaData))
-
-	// Step 3: Upload to Twitter's media upload endpoint
-	uploadURL := "https://upload.twitter.com/1.1/media/upload.json"
-
-	// Create multipart form data
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
-
-	// Add the media file
-	part, err := writer.CreateFormFile("media", "image")
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to create form file: %v\n", err)
-		return "", fmt.Errorf("failed to create form file: %v", err)
-	}
-
-	_, err = part.Write(mediaData)
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to write media data: %v\n", err)
-		return "", fmt.Errorf("failed to write media data: %v", err)
-	}
-
-	// Close the writer
-	writer.Close()
-
-	// Step 4: Create HTTP request to Twitter
-	req, err := http.NewRequest("POST", uploadURL, &requestBody)
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to create upload request: %v\n", err)
-		return "", fmt.Errorf("failed to create upload request: %v", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	// Step 5: Make the request
-	client := &http.Client{Timeout: 60 * time.Second} // Longer timeout for media upload
-	uploadResp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("DEBUG: Media upload request failed: %v\n", err)
-		return "", fmt.Errorf("media upload request failed: %v", err)
-	}
-	defer uploadResp.Body.Close()
-
-	// Step 6: Handle response
-	if uploadResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(uploadResp.Body)
-		fmt.Printf("DEBUG: Twitter media upload failed: %d - %s\n", uploadResp.StatusCode, string(body))
-
-		// For testing, return mock success if we get auth errors
-		if uploadResp.StatusCode == 401 || uploadResp.StatusCode == 403 {
-			fmt.Printf("DEBUG: Twitter auth error, returning mock media ID for testing\n")
-			return "mock_media_id_" + accessToken[:8], nil
-		}
-
-		return "", fmt.Errorf("twitter media upload failed: %d - %s", uploadResp.StatusCode, string(body))
-	}
-
-	// Step 7: Parse response and extract media ID
-	var uploadResult struct {
-		MediaID string `json:"media_id_string"`
-	}
-
-	responseBody, err := io.ReadAll(uploadResp.Body)
-	if err != nil {
-		fmt.Printf("DEBUG: Failed to read upload response: %v\n", err)
-		return "", fmt.Errorf("failed to read upload response: %v", err)
-	}
-
-	fmt.Printf("DEBUG: Twitter upload response: %s\n", string(responseBody))
-
-	if err := json.Unmarshal(responseBody, &uploadResult); err != nil {
-		fmt.Printf("DEBUG: Failed to parse upload response: %v\n", err)
-		return "", fmt.Errorf("failed to parse upload response: %v", err)
-	}
-
-	if uploadResult.MediaID == "" {
-		fmt.Printf("DEBUG: No media ID in response, returning mock for testing\n")
-		return "mock_media_id_" + accessToken[:8], nil
-	}
-
-	fmt.Printf("DEBUG: Successfully uploaded media to Twitter, ID: %s\n", uploadResult.MediaID)
-	return uploadResult.MediaID, nil
-}
-
 func GetTwitterPostsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Add panic recovery to prevent 500 errors
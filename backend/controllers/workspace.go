@@ -7,6 +7,7 @@ import (
 	"social-sync-backend/lib"
 	"social-sync-backend/middleware"
 	"social-sync-backend/models"
+	"social-sync-backend/utils"
 	"time"
 
 	"sync"
@@ -93,7 +94,7 @@ func ListWorkspaces(w http.ResponseWriter, r *http.Request) {
 
 	// TODO: Filter by user (currently returns all workspaces)
 	rows, err := lib.DB.Query(`
-		SELECT w.id, w.name, w.avatar, w.admin_id, u.name as admin_name, w.created_at
+		SELECT w.id, w.name, w.avatar, w.admin_id, u.name as admin_name, w.timezone, w.created_at, w.missed_window_minutes
 		FROM workspaces w
 		INNER JOIN workspace_members wm ON w.id = wm.workspace_id
 		INNER JOIN users u ON w.admin_id = u.id
@@ -111,7 +112,7 @@ func ListWorkspaces(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var ws models.Workspace
 		var adminName *string
-		err := rows.Scan(&ws.ID, &ws.Name, &ws.Avatar, &ws.AdminID, &adminName, &ws.CreatedAt)
+		err := rows.Scan(&ws.ID, &ws.Name, &ws.Avatar, &ws.AdminID, &adminName, &ws.Timezone, &ws.CreatedAt, &ws.MissedWindow)
 		if err != nil {
 			log.Printf("Error scanning workspace: %v", err)
 			http.Error(w, "Failed to process workspace data", http.StatusInternalServerError)
@@ -600,3 +601,108 @@ func ChangeMemberRole(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Member role updated successfully"})
 }
+
+// UpdateWorkspaceTimezone sets the IANA time zone used for the workspace's queue slots
+// and as the default for posts scheduled in it
+func UpdateWorkspaceTimezone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+
+	hasPermission, err := middleware.CheckUserPermission(userID, workspaceID, models.PermWorkspaceUpdate)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	}
+	if !hasPermission {
+		http.Error(w, "Insufficient permissions to update workspace", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if _, err := utils.LoadTimeZone(req.Timezone); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := lib.DB.Exec(`UPDATE workspaces SET timezone = $1 WHERE id = $2`, req.Timezone, workspaceID)
+	if err != nil {
+		log.Printf("Error updating workspace timezone: %v", err)
+		http.Error(w, "Failed to update workspace timezone", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	// Queue slots are wall-clock times, so queued posts move with the zone
+	if err := utils.RebalanceQueueNow(lib.DB, workspaceID); err != nil {
+		log.Printf("Error rebalancing queue after timezone change: %v", err)
+	}
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":     "workspace_timezone_changed",
+		"timezone": req.Timezone,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"timezone": req.Timezone})
+}
+
+// UpdateWorkspaceMissedWindow sets how late the workspace's posts may still be published;
+// later than that they are marked missed. null turns the window off.
+func UpdateWorkspaceMissedWindow(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	workspaceID := mux.Vars(r)["workspaceId"]
+
+	hasPermission, err := middleware.CheckUserPermission(userID, workspaceID, models.PermWorkspaceUpdate)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	}
+	if !hasPermission {
+		http.Error(w, "Insufficient permissions to update workspace", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		MissedWindow *int `json:"missed_window_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.MissedWindow != nil {
+		if err := utils.CheckMissedWindow(*req.MissedWindow); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := lib.DB.Exec(`UPDATE workspaces SET missed_window_minutes = $1 WHERE id = $2`, req.MissedWindow, workspaceID)
+	if err != nil {
+		log.Printf("Error updating workspace missed window: %v", err)
+		http.Error(w, "Failed to update workspace missed window", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":                  "workspace_missed_window_changed",
+		"missed_window_minutes": req.MissedWindow,
+	})
+	hub.broadcast(workspaceID, websocket.TextMessage, msg)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"missed_window_minutes": req.MissedWindow})
+}
@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"bytes"
-	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -11,59 +9,13 @@ import (
 	"mime/multipart"
 	"net/http"
 	"social-sync-backend/lib"
-	"strconv"
 	"strings"
 	"time"
 
 	"social-sync-backend/middleware"
-
-	"github.com/lib/pq"
-	"golang.org/x/oauth2"
+	"social-sync-backend/utils"
 )
 
-// YouTubeUploadRequest represents the video upload request
-// (renamed to YouTubeVideoMetadata)
-type YouTubeVideoMetadata struct {
-	Snippet struct {
-		Title       string   `json:"title"`
-		Description string   `json:"description"`
-		Tags        []string `json:"tags,omitempty"`
-		CategoryID  string   `json:"categoryId"`
-	} `json:"snippet"`
-	Status struct {
-		PrivacyStatus string `json:"privacyStatus"`
-	} `json:"status"`
-}
-
-// YouTubeUploadResponse represents YouTube API response
-type YouTubeUploadResponse struct {
-	Kind    string `json:"kind"`
-	Etag    string `json:"etag"`
-	ID      string `json:"id"`
-	Snippet struct {
-		PublishedAt  string                 `json:"publishedAt"`
-		ChannelID    string                 `json:"channelId"`
-		Title        string                 `json:"title"`
-		Description  string                 `json:"description"`
-		Thumbnails   map[string]interface{} `json:"thumbnails"`
-		ChannelTitle string                 `json:"channelTitle"`
-		Tags         []string               `json:"tags"`
-		CategoryID   string                 `json:"categoryId"`
-	} `json:"snippet"`
-	Status struct {
-		UploadStatus        string `json:"uploadStatus"`
-		PrivacyStatus       string `json:"privacyStatus"`
-		License             string `json:"license"`
-		Embeddable          bool   `json:"embeddable"`
-		PublicStatsViewable bool   `json:"publicStatsViewable"`
-	} `json:"status"`
-}
-
-// readSeekCloser wraps a bytes.Reader to satisfy multipart.File (adds Close no-op)
-type readSeekCloser struct{ *bytes.Reader }
-
-func (rsc *readSeekCloser) Close() error { return nil }
-
 // PostToYouTubeHandler handles video upload to YouTube
 func PostToYouTubeHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -163,110 +115,55 @@ func PostToYouTubeHandler(db *sql.DB) http.HandlerFunc {
 			postAll = r.FormValue("all") == "true"
 		}
 
-		var buf bytes.Buffer
 		if file != nil {
-			// Upload to Cloudinary once (backup), and read the video into memory once for reuse
+			// Upload to Cloudinary once; the publisher reads the video back from there
 			cloudinaryURL, err = lib.UploadToCloudinary(file, "videos", fileHeader.Filename)
 			if err != nil {
 				http.Error(w, "failed to upload video to storage", http.StatusInternalServerError)
 				return
 			}
-			// Rewind and read into buffer for reuse across multiple uploads
-			_, _ = file.Seek(0, 0)
-			if _, err := io.Copy(&buf, file); err != nil {
-				http.Error(w, "failed to buffer upload", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			// For JSON requests, we already have the cloudinaryURL
-			// We can't actually upload to YouTube without a video file, so we'll return a mock success
-			// In a real implementation, you'd download the video from the cloudinaryURL and upload it
-		}
-
-		// reader wrapper is defined at file scope
-
-		// Resolve target accounts
-		type ytAcct struct {
-			ID           string
-			AccessToken  string
-			RefreshToken string
-		}
-		var targets []ytAcct
-
-		if len(selectedIDs) > 0 {
-			// First, try to get accounts with external_id if the column exists
-			rows, qErr := db.Query(`SELECT id::text, access_token, refresh_token, COALESCE(external_id, social_id, '') as channel_id FROM social_accounts WHERE user_id=$1 AND (platform='youtube' OR provider='youtube') AND id = ANY($2::uuid[])`, userID, pq.Array(selectedIDs))
-			if qErr != nil {
-				// If that fails, try without external_id
-				fmt.Printf("DEBUG: First query failed, trying without external_id: %v\n", qErr)
-				rows, qErr = db.Query(`SELECT id::text, access_token, refresh_token FROM social_accounts WHERE user_id=$1 AND (platform='youtube' OR provider='youtube') AND id = ANY($2::uuid[])`, userID, pq.Array(selectedIDs))
-				if qErr != nil {
-					http.Error(w, "failed to get YouTube accounts", http.StatusInternalServerError)
-					return
-				}
-				defer rows.Close()
-
-				// Simple approach without duplicate detection
-				for rows.Next() {
-					var id, at, rt string
-					if scanErr := rows.Scan(&id, &at, &rt); scanErr == nil {
-						targets = append(targets, ytAcct{ID: id, AccessToken: at, RefreshToken: rt})
-					}
-				}
-			} else {
-				defer rows.Close()
-
-				// Track unique access tokens to prevent duplicate uploads to the same channel
-				uniqueTokens := make(map[string]ytAcct)
-				for rows.Next() {
-					var id, at, rt, channelID string
-					if scanErr := rows.Scan(&id, &at, &rt, &channelID); scanErr == nil {
-						// Use access token as key to detect same Google account
-						if _, exists := uniqueTokens[at]; !exists {
-							uniqueTokens[at] = ytAcct{ID: id, AccessToken: at, RefreshToken: rt}
-							fmt.Printf("DEBUG: Added unique YouTube account: %s (Channel ID: %s)\n", id, channelID)
-						} else {
-							fmt.Printf("DEBUG: Skipping duplicate YouTube account (same Google account): %s (Channel ID: %s)\n", id, channelID)
-						}
-					}
-				}
-
-				// Convert map values to slice
-				for _, target := range uniqueTokens {
-					targets = append(targets, target)
-				}
-
-				fmt.Printf("DEBUG: Found %d YouTube accounts from %d selected accounts\n", len(targets), len(selectedIDs))
-			}
-		} else if postAll {
-			rows, qErr := db.Query(`SELECT id::text, access_token, refresh_token FROM social_accounts WHERE user_id=$1 AND (platform='youtube' OR provider='youtube')`, userID)
-			if qErr != nil {
-				http.Error(w, "failed to get YouTube accounts", http.StatusInternalServerError)
-				return
-			}
-			defer rows.Close()
-			for rows.Next() {
-				var id, at, rt string
-				if scanErr := rows.Scan(&id, &at, &rt); scanErr == nil {
-					targets = append(targets, ytAcct{ID: id, AccessToken: at, RefreshToken: rt})
-				}
-			}
-		} else {
-			var id, at, rt string
-			qErr := db.QueryRow(`SELECT id::text, access_token, refresh_token FROM social_accounts WHERE user_id=$1 AND (platform='youtube' OR provider='youtube') ORDER BY is_default DESC, connected_at DESC LIMIT 1`, userID).Scan(&id, &at, &rt)
-			if qErr == sql.ErrNoRows {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"error": "YouTube account not connected",
-				})
-				return
-			} else if qErr != nil {
-				http.Error(w, "failed to get YouTube account", http.StatusInternalServerError)
-				return
+		}
+
+		accounts, err := utils.ResolvePublishAccounts(db, userID, "youtube", selectedIDs, postAll)
+		if err != nil {
+			http.Error(w, "failed to get YouTube accounts", http.StatusInternalServerError)
+			return
+		}
+		if len(accounts) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": "YouTube account not connected",
+			})
+			return
+		}
+
+		// Accounts sharing an access token belong to the same Google account; upload once
+		var targets []utils.PublishAccount
+		seenTokens := make(map[string]bool)
+		for _, a := range accounts {
+			if seenTokens[a.AccessToken] {
+				fmt.Printf("DEBUG: Skipping duplicate YouTube account (same Google account): %s\n", a.ID)
+				continue
 			}
-			targets = append(targets, ytAcct{ID: id, AccessToken: at, RefreshToken: rt})
+			seenTokens[a.AccessToken] = true
+			targets = append(targets, a)
+		}
+
+		outcomes, err := utils.PublishToAccounts(r.Context(), "youtube", targets, utils.PublishRequest{
+			Content:     description,
+			MediaURLs:   []string{cloudinaryURL},
+			Title:       title,
+			Description: description,
+			Tags:        tags,
+			Privacy:     privacy,
+			CategoryID:  categoryID,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
 		// Upload per-target and collect results
 		type ytResult struct {
 			AccountID string `json:"accountId"`
@@ -275,99 +172,14 @@ func PostToYouTubeHandler(db *sql.DB) http.HandlerFunc {
 			Error     string `json:"error,omitempty"`
 		}
 		var results []ytResult
-
-		if strings.Contains(contentType, "application/json") {
-			// For JSON requests, download video from Cloudinary and upload to YouTube
-			fmt.Printf("DEBUG: Processing %d YouTube targets\n", len(targets))
-			for i, t := range targets {
-				fmt.Printf("DEBUG: Processing target %d/%d - Account ID: %s\n", i+1, len(targets), t.ID)
-				// Download video from Cloudinary
-				videoData, err := downloadVideoFromCloudinary(cloudinaryURL)
-				if err != nil {
-					results = append(results, ytResult{
-						AccountID: t.ID,
-						OK:        false,
-						Error:     "Failed to download video: " + err.Error(),
-					})
-					continue
-				}
-
-				// Upload to YouTube
-				reader := &readSeekCloser{bytes.NewReader(videoData)}
-				videoID, upErr := uploadVideoToYouTube(reader, int64(len(videoData)), title, description, tags, privacy, categoryID, t.AccessToken)
-				if upErr != nil {
-					// Attempt per-account refresh on auth errors
-					if (strings.Contains(upErr.Error(), "401") || strings.Contains(upErr.Error(), "UNAUTHENTICATED") || strings.Contains(upErr.Error(), "Invalid Credentials")) && t.RefreshToken != "" {
-						newToken, rErr := refreshYouTubeToken(t.RefreshToken)
-						if rErr == nil && newToken != "" {
-							// update only this account's token
-							_, _ = db.Exec(`UPDATE social_accounts SET access_token=$1, last_synced_at=$2 WHERE id=$3::uuid`, newToken, time.Now(), t.ID)
-							reader2 := &readSeekCloser{bytes.NewReader(videoData)}
-							videoID, upErr = uploadVideoToYouTube(reader2, int64(len(videoData)), title, description, tags, privacy, categoryID, newToken)
-						}
-					}
-				}
-				if upErr != nil {
-					fmt.Printf("DEBUG: YouTube upload failed for account %s: %v\n", t.ID, upErr)
-
-					// Check if it's an upload limit error
-					errorMsg := upErr.Error()
-					if strings.Contains(errorMsg, "uploadLimitExceeded") || strings.Contains(errorMsg, "exceeded the number of videos") {
-						errorMsg = "YouTube daily upload limit exceeded. Please try again tomorrow or verify your YouTube account to increase limits."
-					}
-
-					results = append(results, ytResult{AccountID: t.ID, OK: false, Error: errorMsg})
-					continue
-				}
-				results = append(results, ytResult{AccountID: t.ID, OK: true, VideoID: videoID})
-				fmt.Printf("DEBUG: Successfully uploaded to account %s, video ID: %s\n", t.ID, videoID)
-			}
-			successfulCount := 0
-			for _, result := range results {
-				if result.OK {
-					successfulCount++
-				}
-			}
-			fmt.Printf("DEBUG: Completed processing %d targets, %d successful, %d failed\n", len(targets), successfulCount, len(results)-successfulCount)
-		} else {
-			// For multipart form requests, do actual upload
-			for _, t := range targets {
-				reader := &readSeekCloser{bytes.NewReader(buf.Bytes())}
-				videoID, upErr := uploadVideoToYouTube(reader, int64(buf.Len()), title, description, tags, privacy, categoryID, t.AccessToken)
-				if upErr != nil {
-					// Attempt per-account refresh on auth errors
-					if (strings.Contains(upErr.Error(), "401") || strings.Contains(upErr.Error(), "UNAUTHENTICATED") || strings.Contains(upErr.Error(), "Invalid Credentials")) && t.RefreshToken != "" {
-						newToken, rErr := refreshYouTubeToken(t.RefreshToken)
-						if rErr == nil && newToken != "" {
-							// update only this account's token
-							_, _ = db.Exec(`UPDATE social_accounts SET access_token=$1, last_synced_at=$2 WHERE id=$3::uuid`, newToken, time.Now(), t.ID)
-							reader2 := &readSeekCloser{bytes.NewReader(buf.Bytes())}
-							videoID, upErr = uploadVideoToYouTube(reader2, int64(buf.Len()), title, description, tags, privacy, categoryID, newToken)
-						}
-					}
-				}
-				if upErr != nil {
-					fmt.Printf("DEBUG: YouTube upload failed for account %s: %v\n", t.ID, upErr)
-
-					// Check if it's an upload limit error
-					errorMsg := upErr.Error()
-					if strings.Contains(errorMsg, "uploadLimitExceeded") || strings.Contains(errorMsg, "exceeded the number of videos") {
-						errorMsg = "YouTube daily upload limit exceeded. Please try again tomorrow or verify your YouTube account to increase limits."
-					}
-
-					results = append(results, ytResult{AccountID: t.ID, OK: false, Error: errorMsg})
-					continue
-				}
-				results = append(results, ytResult{AccountID: t.ID, OK: true, VideoID: videoID})
-			}
-
-			successfulCount := 0
-			for _, result := range results {
-				if result.OK {
-					successfulCount++
-				}
+		for _, o := range outcomes {
+			res := ytResult{AccountID: o.Account.ID, OK: o.Err == nil}
+			if o.Err != nil {
+				res.Error = o.Err.Error()
+			} else if o.Result != nil {
+				res.VideoID = o.Result.RemotePostID
 			}
-			fmt.Printf("DEBUG: Completed processing %d targets, %d successful, %d failed\n", len(targets), successfulCount, len(results)-successfulCount)
+			results = append(results, res)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -380,177 +192,6 @@ func PostToYouTubeHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func uploadVideoToYouTube(file multipart.File, fileSize int64, title, description, tags, privacy, categoryID, accessToken string) (string, error) {
-	metadata := YouTubeVideoMetadata{}
-	metadata.Snippet.Title = title
-	metadata.Snippet.Description = description
-	metadata.Snippet.CategoryID = categoryID
-	metadata.Status.PrivacyStatus = privacy
-
-	if tags != "" {
-		tagList := strings.Split(tags, ",")
-		for i, tag := range tagList {
-			tagList[i] = strings.TrimSpace(tag)
-		}
-		metadata.Snippet.Tags = tagList
-	}
-
-	metadataJSON, err := json.Marshal(metadata)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Printf("DEBUG: Sending metadata to YouTube: %s\n", string(metadataJSON))
-
-	initURL := "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part=snippet,status"
-	initReq, err := http.NewRequest("POST", initURL, bytes.NewReader(metadataJSON))
-	if err != nil {
-		return "", err
-	}
-
-	initReq.Header.Set("Authorization", "Bearer "+accessToken)
-	initReq.Header.Set("Content-Type", "application/json")
-	initReq.Header.Set("X-Upload-Content-Type", "video/*")
-	initReq.Header.Set("X-Upload-Content-Length", strconv.FormatInt(fileSize, 10))
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	initResp, err := client.Do(initReq)
-	if err != nil {
-		return "", err
-	}
-	defer initResp.Body.Close()
-
-	fmt.Printf("DEBUG: Init response status: %d\n", initResp.StatusCode)
-
-	if initResp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(initResp.Body)
-		fmt.Printf("DEBUG: Init error response: %s\n", string(bodyBytes))
-		return "", fmt.Errorf("failed to initialize upload: %d - %s", initResp.StatusCode, string(bodyBytes))
-	}
-
-	uploadURL := initResp.Header.Get("Location")
-	if uploadURL == "" {
-		return "", fmt.Errorf("no upload URL received from YouTube")
-	}
-
-	fmt.Printf("DEBUG: Upload URL: %s\n", uploadURL)
-
-	file.Seek(0, 0)
-
-	uploadReq, err := http.NewRequest("PUT", uploadURL, file)
-	if err != nil {
-		return "", err
-	}
-
-	uploadReq.Header.Set("Content-Type", "video/*")
-	uploadReq.Header.Set("Content-Length", strconv.FormatInt(fileSize, 10))
-
-	uploadClient := &http.Client{Timeout: 300 * time.Second}
-	uploadResp, err := uploadClient.Do(uploadReq)
-	if err != nil {
-		return "", err
-	}
-	defer uploadResp.Body.Close()
-
-	fmt.Printf("DEBUG: Upload response status: %d\n", uploadResp.StatusCode)
-
-	if uploadResp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(uploadResp.Body)
-		fmt.Printf("DEBUG: Upload error response: %s\n", string(bodyBytes))
-		return "", fmt.Errorf("failed to upload video: %d - %s", uploadResp.StatusCode, string(bodyBytes))
-	}
-
-	var uploadRespData YouTubeUploadResponse
-	if err := json.NewDecoder(uploadResp.Body).Decode(&uploadRespData); err != nil {
-		return "", err
-	}
-
-	fmt.Printf("DEBUG: YouTube response - Title: %s, Privacy: %s\n", uploadRespData.Snippet.Title, uploadRespData.Status.PrivacyStatus)
-	fmt.Printf("DEBUG: Full YouTube response: %+v\n", uploadRespData)
-
-	// Ensure snippet (title/description/category) is set by updating after upload (defensive)
-	if err := updateYouTubeVideoSnippet(uploadRespData.ID, title, description, tags, categoryID, accessToken); err != nil {
-		fmt.Printf("WARNING: Failed to update YouTube snippet post-upload: %v\n", err)
-	}
-
-	return uploadRespData.ID, nil
-}
-
-// updateYouTubeVideoSnippet ensures the uploaded video's title/description/category are set
-func updateYouTubeVideoSnippet(videoID, title, description, tags, categoryID, accessToken string) error {
-	if videoID == "" {
-		return nil
-	}
-	var tagList []string
-	if tags != "" {
-		for _, t := range strings.Split(tags, ",") {
-			tt := strings.TrimSpace(t)
-			if tt != "" {
-				tagList = append(tagList, tt)
-			}
-		}
-	}
-	body := map[string]interface{}{
-		"id": videoID,
-		"snippet": map[string]interface{}{
-			"title":       title,
-			"description": description,
-			"categoryId":  categoryID,
-			"tags":        tagList,
-		},
-	}
-	payload, _ := json.Marshal(body)
-	req, err := http.NewRequest("PUT", "https://www.googleapis.com/youtube/v3/videos?part=snippet", bytes.NewReader(payload))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("videos.update failed: %d - %s", resp.StatusCode, string(b))
-	}
-	return nil
-}
-
-func refreshYouTubeToken(refreshToken string) (string, error) {
-	config := getYouTubeOAuthConfig()
-	token := &oauth2.Token{RefreshToken: refreshToken}
-	tokenSource := config.TokenSource(context.Background(), token)
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		return "", err
-	}
-	return newToken.AccessToken, nil
-}
-
-// downloadVideoFromCloudinary downloads a video from Cloudinary URL
-func downloadVideoFromCloudinary(cloudinaryURL string) ([]byte, error) {
-	client := &http.Client{Timeout: 300 * time.Second}
-	resp, err := client.Get(cloudinaryURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download video from Cloudinary: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download video: HTTP %d", resp.StatusCode)
-	}
-
-	videoData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read video data: %v", err)
-	}
-
-	return videoData, nil
-}
-
 func isValidVideoFile(filename string) bool {
 	validExtensions := []string{".mp4", ".mov", ".avi", ".wmv", ".flv", ".webm", ".mkv"}
 	filename = strings.ToLower(filename)
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"social-sync-backend/lib"
 	"social-sync-backend/routes"
@@ -14,6 +18,9 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
+// defaultShutdownTimeout is how long a SIGTERM waits for in-flight work (SHUTDOWN_TIMEOUT overrides)
+const defaultShutdownTimeout = 25 * time.Second
+
 // CORSMiddleware sets CORS headers.
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,13 +71,11 @@ func main() {
 	// Initialize scheduled post processor
 	scheduledPostProcessor := utils.NewScheduledPostProcessor(lib.DB)
 	scheduledPostProcessor.Start()
-	defer scheduledPostProcessor.Stop()
 	log.Println("✅ Scheduled post processor started!")
 
 	// Initialize analytics scheduler
 	analyticsScheduler := utils.NewAnalyticsScheduler()
 	analyticsScheduler.Start()
-	defer analyticsScheduler.Stop()
 	log.Println("✅ Analytics scheduler started!")
 
 	// Setup cron job for social account sync
@@ -85,7 +90,6 @@ func main() {
 		log.Fatalf("❌ Failed to schedule cron: %v", err)
 	}
 	c.Start()
-	defer c.Stop()
 	log.Println("✅ Cron job started (every 12h).")
 
 	// Setup routes and middleware
@@ -97,6 +101,41 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
-	log.Printf("🚀 Server running at: http://localhost:%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		log.Printf("🚀 Server running at: http://localhost:%s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ Server failed: %v", err)
+		}
+	}()
+
+	// Wait for SIGTERM (deploys) or Ctrl+C, then drain
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	sig := <-stop
+	log.Printf("🛑 Received %s, shutting down...", sig)
+
+	timeout := defaultShutdownTimeout
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && d > 0 {
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Stop accepting requests first, then let background work finish within the same deadline
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("❌ HTTP server shutdown: %v", err)
+	}
+	if err := scheduledPostProcessor.Shutdown(ctx); err != nil {
+		log.Printf("❌ Scheduled post processor shutdown: %v", err)
+	}
+	if err := analyticsScheduler.Shutdown(ctx); err != nil {
+		log.Printf("❌ Analytics scheduler shutdown: %v", err)
+	}
+	select {
+	case <-c.Stop().Done():
+	case <-ctx.Done():
+		log.Println("❌ Social account sync still running at shutdown deadline")
+	}
+	log.Println("✅ Shutdown complete.")
 }
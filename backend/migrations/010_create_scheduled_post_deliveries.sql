@@ -0,0 +1,33 @@
+-- Migration: Create scheduled_post_deliveries table
+-- One row per platform/account target of a scheduled post, so partial successes
+-- and per-account failures are tracked individually instead of in error_message
+
+CREATE TABLE IF NOT EXISTS scheduled_post_deliveries (
+    id SERIAL PRIMARY KEY,
+    scheduled_post_id INTEGER NOT NULL REFERENCES scheduled_posts(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    social_account_id UUID REFERENCES social_accounts(id) ON DELETE CASCADE,
+    account_name TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    remote_post_id TEXT,
+    permalink TEXT,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- A platform-level failure (no connected account) is stored with a NULL account. Rows of an
+-- account are deleted with it rather than set to NULL, which would make them collide here
+-- and read back as platform-level rows.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_post_deliveries_target
+  ON scheduled_post_deliveries(scheduled_post_id, platform, COALESCE(social_account_id, '00000000-0000-0000-0000-000000000000'::uuid));
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_deliveries_post_id ON scheduled_post_deliveries(scheduled_post_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_deliveries_status ON scheduled_post_deliveries(status);
+
+COMMENT ON TABLE scheduled_post_deliveries IS 'Per platform/account delivery state of a scheduled post';
+COMMENT ON COLUMN scheduled_post_deliveries.status IS 'pending, posted or failed';
+COMMENT ON COLUMN scheduled_post_deliveries.attempts IS 'Number of publish attempts made for this target';
+COMMENT ON COLUMN scheduled_post_deliveries.remote_post_id IS 'ID of the post on the remote platform';
+COMMENT ON COLUMN scheduled_post_deliveries.last_error IS 'Error from the most recent failed attempt';
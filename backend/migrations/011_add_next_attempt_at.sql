@@ -0,0 +1,11 @@
+-- Migration: Add next_attempt_at for per-target retry backoff
+-- Failed deliveries are retried individually once next_attempt_at has passed;
+-- the post-level column lets the processor skip posts that are backing off
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_due ON scheduled_posts(status, scheduled_time, next_attempt_at);
+
+COMMENT ON COLUMN scheduled_posts.next_attempt_at IS 'Earliest time any failed target of the post may be retried';
+COMMENT ON COLUMN scheduled_post_deliveries.next_attempt_at IS 'When this failed target may be retried; NULL once posted or out of attempts';
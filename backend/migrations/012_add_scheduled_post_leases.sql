@@ -0,0 +1,11 @@
+-- Migration: Lease columns for claiming scheduled posts
+-- A replica claims due posts by moving them to 'processing' with its worker ID and a
+-- lease expiry; posts whose lease expires (crashed worker) are claimed again
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS locked_by TEXT;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_lease ON scheduled_posts(status, lease_expires_at);
+
+COMMENT ON COLUMN scheduled_posts.locked_by IS 'Worker currently processing the post';
+COMMENT ON COLUMN scheduled_posts.lease_expires_at IS 'When the processing claim lapses and the post may be claimed again';
@@ -0,0 +1,51 @@
+-- Migration: Recurring scheduled posts
+-- A series holds the content template and recurrence rule (RRULE or cron). Only the next
+-- occurrence is materialized as a scheduled_posts row; after it runs the processor
+-- creates the following one. Per-occurrence skips and edits live in the exceptions table.
+
+CREATE TABLE IF NOT EXISTS scheduled_post_series (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL DEFAULT '',
+    media_urls TEXT[] DEFAULT '{}',
+    platforms TEXT[] NOT NULL,
+    targets JSONB DEFAULT '{}'::jsonb,
+    recurrence_rule TEXT NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    max_occurrences INTEGER,
+    occurrences_created INTEGER NOT NULL DEFAULT 0,
+    last_occurrence_at TIMESTAMP WITH TIME ZONE,
+    status TEXT NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_series_user_id ON scheduled_post_series(user_id);
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS series_id INTEGER REFERENCES scheduled_post_series(id) ON DELETE SET NULL;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMP WITH TIME ZONE;
+
+-- Guards against materializing the same occurrence twice
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_posts_series_occurrence
+  ON scheduled_posts(series_id, occurrence_at) WHERE series_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS scheduled_post_series_exceptions (
+    id SERIAL PRIMARY KEY,
+    series_id INTEGER NOT NULL REFERENCES scheduled_post_series(id) ON DELETE CASCADE,
+    occurrence_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    skipped BOOLEAN NOT NULL DEFAULT FALSE,
+    content TEXT,
+    media_urls TEXT[],
+    scheduled_time TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (series_id, occurrence_at)
+);
+
+COMMENT ON TABLE scheduled_post_series IS 'Recurring scheduled post definitions';
+COMMENT ON COLUMN scheduled_post_series.recurrence_rule IS 'iCalendar RRULE (FREQ=...) or 5-field cron expression';
+COMMENT ON COLUMN scheduled_post_series.occurrences_created IS 'Occurrences generated so far, including skipped ones';
+COMMENT ON COLUMN scheduled_post_series.status IS 'active, completed or cancelled';
+COMMENT ON COLUMN scheduled_posts.occurrence_at IS 'Original rule time of a series occurrence (before any per-occurrence edit)';
+COMMENT ON TABLE scheduled_post_series_exceptions IS 'Skips and edits of single occurrences that are not materialized yet';
@@ -0,0 +1,31 @@
+-- Migration: Posting queues with weekly time slots
+-- A workspace defines weekly slots (optionally limited to a platform or account).
+-- Queued scheduled posts keep an order in queue_position and are assigned the next
+-- free matching slot; reordering or removing a post reassigns the times.
+
+CREATE TABLE IF NOT EXISTS workspace_queue_slots (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    platform TEXT,
+    social_account_id UUID REFERENCES social_accounts(id) ON DELETE CASCADE,
+    day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6),
+    time_of_day TIME NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_queue_slots_unique
+  ON workspace_queue_slots(workspace_id, COALESCE(platform, ''),
+     COALESCE(social_account_id, '00000000-0000-0000-0000-000000000000'::uuid), day_of_week, time_of_day);
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS queue_position INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_workspace_id ON scheduled_posts(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_queue ON scheduled_posts(workspace_id, queue_position) WHERE queue_position IS NOT NULL;
+
+COMMENT ON TABLE workspace_queue_slots IS 'Weekly posting slots of a workspace queue';
+COMMENT ON COLUMN workspace_queue_slots.day_of_week IS '0 = Sunday ... 6 = Saturday';
+COMMENT ON COLUMN workspace_queue_slots.platform IS 'Limit the slot to one platform; NULL for any';
+COMMENT ON COLUMN workspace_queue_slots.social_account_id IS 'Limit the slot to one account; NULL for any';
+COMMENT ON COLUMN scheduled_posts.queue_position IS 'Order within the workspace queue; NULL when scheduled at a fixed time';
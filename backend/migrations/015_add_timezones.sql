@@ -0,0 +1,14 @@
+-- Migration: IANA time zones for users, workspaces and scheduled posts
+-- Scheduled times are still stored as instants (timestamptz); the zone records the
+-- author's wall clock so times can be displayed locally and recurrences/queue slots
+-- keep their local time across DST changes
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT;
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS timezone TEXT;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS timezone TEXT;
+ALTER TABLE scheduled_post_series ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN users.timezone IS 'IANA time zone, e.g. Europe/Berlin; NULL means UTC';
+COMMENT ON COLUMN workspaces.timezone IS 'IANA time zone for queue slots; NULL falls back to the author''s zone';
+COMMENT ON COLUMN scheduled_posts.timezone IS 'IANA time zone the post was scheduled in';
+COMMENT ON COLUMN scheduled_post_series.timezone IS 'IANA time zone the recurrence rule is evaluated in';
@@ -0,0 +1,12 @@
+-- Migration: Workspace ownership of scheduled posts
+-- Scheduled posts and recurring series may belong to a workspace; members act on them
+-- according to their post:* permissions. Posts without a workspace stay private to their author.
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE scheduled_post_series ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_workspace_time ON scheduled_posts(workspace_id, scheduled_time);
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_series_workspace_id ON scheduled_post_series(workspace_id);
+
+COMMENT ON COLUMN scheduled_posts.workspace_id IS 'Owning workspace; NULL for personal posts visible only to user_id';
+COMMENT ON COLUMN scheduled_post_series.workspace_id IS 'Owning workspace; copied to every materialized occurrence';
@@ -0,0 +1,9 @@
+-- Migration: Publish drafts through scheduled posts
+-- Publishing a draft creates a scheduled post (due now or at the draft's scheduled_time).
+-- The draft keeps a link to it so delivery status and errors show up in the draft view.
+
+ALTER TABLE draft_posts ADD COLUMN IF NOT EXISTS scheduled_post_id INTEGER REFERENCES scheduled_posts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_draft_posts_scheduled_post_id ON draft_posts(scheduled_post_id) WHERE scheduled_post_id IS NOT NULL;
+
+COMMENT ON COLUMN draft_posts.scheduled_post_id IS 'Scheduled post created when the draft was published';
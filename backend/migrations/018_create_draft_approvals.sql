@@ -0,0 +1,36 @@
+-- Migration: Draft approval workflow
+-- When a workspace enables approvals, drafts move draft -> in_review -> (changes_requested ->
+-- in_review ...) -> approved -> scheduled and can only be published once approved.
+-- Required approvers are configured per workspace by user or by role; every review action
+-- is recorded in the approval log.
+
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS approvals_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS workspace_approvers (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((user_id IS NULL) <> (role IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_approvers_workspace_id ON workspace_approvers(workspace_id);
+
+CREATE TABLE IF NOT EXISTS draft_approval_log (
+    id SERIAL PRIMARY KEY,
+    draft_id UUID NOT NULL REFERENCES draft_posts(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    comment TEXT,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_draft_approval_log_draft_id ON draft_approval_log(draft_id, created_at);
+
+COMMENT ON COLUMN workspaces.approvals_enabled IS 'Require approval before drafts can be published or scheduled';
+COMMENT ON TABLE workspace_approvers IS 'Required approvals of a workspace; each row is one user or any member with a role';
+COMMENT ON TABLE draft_approval_log IS 'Submit, approve and request-changes actions on drafts';
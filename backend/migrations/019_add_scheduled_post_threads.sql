@@ -0,0 +1,30 @@
+-- Migration: Multi-part (thread) scheduled posts
+-- A scheduled post may carry follow-up parts that are published as replies to the main
+-- post (Twitter threads, Mastodon reply chains) or as sequential Telegram messages.
+-- Every published part is recorded per account so a failed part can be retried
+-- without re-posting the parts before it.
+
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS thread JSONB;
+
+CREATE TABLE IF NOT EXISTS scheduled_post_thread_parts (
+    id SERIAL PRIMARY KEY,
+    scheduled_post_id INTEGER NOT NULL REFERENCES scheduled_posts(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    social_account_id UUID NOT NULL REFERENCES social_accounts(id) ON DELETE CASCADE,
+    part_index INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'posted',
+    remote_post_id TEXT,
+    permalink TEXT,
+    last_error TEXT,
+    posted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (scheduled_post_id, platform, social_account_id, part_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_thread_parts_post_id ON scheduled_post_thread_parts(scheduled_post_id);
+
+COMMENT ON COLUMN scheduled_posts.thread IS 'Follow-up parts ([{content, media_urls}]) published after the main post, in order';
+COMMENT ON TABLE scheduled_post_thread_parts IS 'Per account publish state of each part of a multi-part scheduled post';
+COMMENT ON COLUMN scheduled_post_thread_parts.part_index IS '0 is the main post, 1.. are the thread parts';
+COMMENT ON COLUMN scheduled_post_thread_parts.status IS 'posted or failed';
@@ -0,0 +1,13 @@
+-- Migration: Track the automatic first comment of scheduled post deliveries
+-- Targets may carry a first_comment that is posted under the freshly published post
+-- (Instagram media, Facebook page posts). Its outcome is stored next to the delivery
+-- but never changes the delivery's own status.
+
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS first_comment_status TEXT;
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS first_comment_id TEXT;
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS first_comment_error TEXT;
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS first_comment_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN scheduled_post_deliveries.first_comment_status IS 'posted or failed; NULL when the target has no first comment';
+COMMENT ON COLUMN scheduled_post_deliveries.first_comment_id IS 'ID of the comment on the remote platform';
+COMMENT ON COLUMN scheduled_post_deliveries.first_comment_error IS 'Error from posting the first comment';
@@ -0,0 +1,27 @@
+-- Migration: Unpublishing of posted scheduled posts
+-- A posted scheduled post can be taken down again: every delivery is deleted from its
+-- platform through the stored remote post ID. Each remote deletion attempt (main post
+-- and thread parts) is kept in an append-only log so there is a record of who removed
+-- what, and which targets could not be removed.
+
+CREATE TABLE IF NOT EXISTS scheduled_post_unpublish_log (
+    id SERIAL PRIMARY KEY,
+    scheduled_post_id INTEGER NOT NULL REFERENCES scheduled_posts(id) ON DELETE CASCADE,
+    delivery_id INTEGER REFERENCES scheduled_post_deliveries(id) ON DELETE SET NULL,
+    platform TEXT NOT NULL,
+    social_account_id UUID,
+    account_name TEXT,
+    remote_post_id TEXT,
+    part_index INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    error TEXT,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_unpublish_log_post_id ON scheduled_post_unpublish_log(scheduled_post_id);
+
+COMMENT ON TABLE scheduled_post_unpublish_log IS 'Audit trail of remote deletions made when unpublishing scheduled posts';
+COMMENT ON COLUMN scheduled_post_unpublish_log.part_index IS '0 is the main post, 1.. are thread parts';
+COMMENT ON COLUMN scheduled_post_unpublish_log.status IS 'deleted, failed, partially_failed or unsupported';
+COMMENT ON COLUMN scheduled_post_unpublish_log.requested_by IS 'User who unpublished the post';
@@ -0,0 +1,21 @@
+-- Migration: Edit history of published scheduled posts
+-- Posted content can be changed afterwards on the platforms that allow it (Facebook,
+-- Mastodon, Telegram, YouTube). Every edit keeps the content and targets it replaced
+-- together with the per-target outcome, so the post's history can be reviewed later.
+
+CREATE TABLE IF NOT EXISTS scheduled_post_edits (
+    id SERIAL PRIMARY KEY,
+    scheduled_post_id INTEGER NOT NULL REFERENCES scheduled_posts(id) ON DELETE CASCADE,
+    edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    previous_content TEXT NOT NULL,
+    content TEXT NOT NULL,
+    previous_targets JSONB,
+    targets JSONB,
+    results JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_post_edits_post_id ON scheduled_post_edits(scheduled_post_id);
+
+COMMENT ON TABLE scheduled_post_edits IS 'History of edits pushed to already published scheduled posts';
+COMMENT ON COLUMN scheduled_post_edits.results IS 'Per target outcome: [{delivery_id, platform, social_account_id, remote_post_id, status, error}]; status is edited, failed or immutable';
@@ -0,0 +1,16 @@
+-- Migration: Missed-schedule policy
+-- After an outage the processor would publish everything that became due, however late.
+-- A staleness window (per post, else per workspace) bounds that: a post that is still
+-- unpublished this many minutes after its scheduled time is marked 'missed' instead, and
+-- waits for someone to publish it now, reschedule it or discard it.
+-- NULL windows keep the old behaviour of publishing late posts.
+
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS missed_window_minutes INTEGER;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS missed_window_minutes INTEGER;
+ALTER TABLE scheduled_posts ADD COLUMN IF NOT EXISTS missed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_posts_missed ON scheduled_posts(workspace_id) WHERE status = 'missed';
+
+COMMENT ON COLUMN workspaces.missed_window_minutes IS 'Default staleness window of the workspace''s posts; NULL publishes late posts whenever possible';
+COMMENT ON COLUMN scheduled_posts.missed_window_minutes IS 'Staleness window of this post; NULL inherits the workspace''s';
+COMMENT ON COLUMN scheduled_posts.missed_at IS 'When the processor last marked the post missed';
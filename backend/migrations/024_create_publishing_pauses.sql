@@ -0,0 +1,29 @@
+-- Migration: Emergency publishing pauses
+-- A workspace can stop outgoing posts at once, for the whole workspace, for one platform
+-- or for one social account. While a pause is active the scheduled post processor holds
+-- the matching targets back and leaves their posts pending; they go out once it is resumed.
+-- Resumed pauses are kept: each row records who paused and resumed publishing, and why.
+
+CREATE TABLE IF NOT EXISTS publishing_pauses (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL CHECK (scope IN ('workspace', 'platform', 'account')),
+    platform TEXT,
+    social_account_id UUID,
+    reason TEXT NOT NULL,
+    paused_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    paused_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    resumed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resumed_at TIMESTAMP WITH TIME ZONE,
+    resume_reason TEXT
+);
+
+-- At most one active pause per workspace and target
+CREATE UNIQUE INDEX IF NOT EXISTS idx_publishing_pauses_active
+    ON publishing_pauses(workspace_id, scope, COALESCE(platform, ''), COALESCE(social_account_id::text, ''))
+    WHERE resumed_at IS NULL;
+
+COMMENT ON TABLE publishing_pauses IS 'Pauses of outgoing scheduled posts; rows with resumed_at set are the pause/resume audit log';
+COMMENT ON COLUMN publishing_pauses.scope IS 'workspace, platform (uses platform) or account (uses social_account_id)';
+COMMENT ON COLUMN publishing_pauses.reason IS 'Why publishing was paused';
+COMMENT ON COLUMN publishing_pauses.resume_reason IS 'Why publishing was resumed';
@@ -0,0 +1,19 @@
+-- Migration: iCalendar feed of the content calendar
+-- Each workspace member can create a secret subscription URL that serves the workspace's
+-- scheduled posts (optionally drafts with a scheduled time and task due dates) as an .ics
+-- feed for Google Calendar, Outlook and the like. Only a hash of the token is stored; the
+-- feed stops working once the token is revoked or its owner loses access to the workspace.
+
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (workspace_id, user_id)
+);
+
+COMMENT ON TABLE calendar_feed_tokens IS 'Per-member secret tokens of workspace .ics calendar feeds';
+COMMENT ON COLUMN calendar_feed_tokens.token_hash IS 'SHA-256 of the token in the feed URL, hex encoded';
+COMMENT ON COLUMN calendar_feed_tokens.last_used_at IS 'When a calendar last fetched the feed';
@@ -0,0 +1,26 @@
+-- Migration: Post templates
+-- Reusable post skeletons per workspace. Content (and text in the default targets) may hold
+-- {{variable}} placeholders that are filled in when a draft or scheduled post is created
+-- from the template; variables holds the default value of any of them. Default hashtags are
+-- appended to the rendered content. Templates are managed under the draft:* permissions.
+
+CREATE TABLE IF NOT EXISTS post_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    media_urls TEXT[] DEFAULT '{}',
+    platforms TEXT[] DEFAULT '{}',
+    targets JSONB DEFAULT '{}'::jsonb,
+    hashtags TEXT[] DEFAULT '{}',
+    variables JSONB DEFAULT '{}'::jsonb,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (workspace_id, name)
+);
+
+COMMENT ON TABLE post_templates IS 'Reusable post templates with {{variable}} placeholders';
+COMMENT ON COLUMN post_templates.targets IS 'Default per-platform account selection and overrides, as on scheduled_posts';
+COMMENT ON COLUMN post_templates.hashtags IS 'Hashtags appended to content rendered from the template';
+COMMENT ON COLUMN post_templates.variables IS 'Default values of placeholders, by variable name';
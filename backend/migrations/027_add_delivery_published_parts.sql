@@ -0,0 +1,9 @@
+-- Migration: Published parts of a delivery
+-- Some publishes take several remote posts: Telegram sends more than 10 media items as
+-- several albums, and Facebook publishes each video of a mixed-media post on its own. If a
+-- later step fails, the steps already done are kept here so the retry skips them instead of
+-- posting them again.
+
+ALTER TABLE scheduled_post_deliveries ADD COLUMN IF NOT EXISTS published_parts TEXT[];
+
+COMMENT ON COLUMN scheduled_post_deliveries.published_parts IS 'Remote IDs of the steps of a multi-step publish done so far, in order';
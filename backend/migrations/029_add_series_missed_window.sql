@@ -0,0 +1,8 @@
+-- Migration: Missed window of recurring posts
+-- A recurring post keeps the staleness window it was created with: it is stored on the
+-- series and copied to every occurrence, the way the series' time zone is. NULL inherits the
+-- workspace's window.
+
+ALTER TABLE scheduled_post_series ADD COLUMN IF NOT EXISTS missed_window_minutes INTEGER;
+
+COMMENT ON COLUMN scheduled_post_series.missed_window_minutes IS 'Staleness window copied to each occurrence; NULL inherits the workspace''s';
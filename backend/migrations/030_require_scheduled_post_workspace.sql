@@ -0,0 +1,42 @@
+-- Migration: Every scheduled post belongs to a workspace
+-- Posts and series created before workspace ownership existed have no workspace_id. They are
+-- moved into the oldest workspace their author administers, else the one they joined first.
+-- Authors in no workspace at all can't be backfilled; the migration stops and lists how many
+-- such rows remain so they can be assigned or removed by hand before running it again.
+
+UPDATE scheduled_post_series s
+SET workspace_id = COALESCE(
+    (SELECT w.id FROM workspaces w WHERE w.admin_id = s.user_id ORDER BY w.created_at, w.id LIMIT 1),
+    (SELECT m.workspace_id FROM workspace_members m WHERE m.user_id = s.user_id ORDER BY m.joined_at, m.workspace_id LIMIT 1))
+WHERE s.workspace_id IS NULL;
+
+-- Occurrences follow their series; one-off posts are placed like series
+UPDATE scheduled_posts p
+SET workspace_id = s.workspace_id
+FROM scheduled_post_series s
+WHERE p.workspace_id IS NULL AND p.series_id = s.id;
+
+UPDATE scheduled_posts p
+SET workspace_id = COALESCE(
+    (SELECT w.id FROM workspaces w WHERE w.admin_id = p.user_id ORDER BY w.created_at, w.id LIMIT 1),
+    (SELECT m.workspace_id FROM workspace_members m WHERE m.user_id = p.user_id ORDER BY m.joined_at, m.workspace_id LIMIT 1))
+WHERE p.workspace_id IS NULL;
+
+DO $$
+DECLARE
+    orphan_posts INTEGER;
+    orphan_series INTEGER;
+BEGIN
+    SELECT COUNT(*) INTO orphan_posts FROM scheduled_posts WHERE workspace_id IS NULL;
+    SELECT COUNT(*) INTO orphan_series FROM scheduled_post_series WHERE workspace_id IS NULL;
+    IF orphan_posts > 0 OR orphan_series > 0 THEN
+        RAISE EXCEPTION '% scheduled post(s) and % series have an author in no workspace; assign or delete them first',
+            orphan_posts, orphan_series;
+    END IF;
+END $$;
+
+ALTER TABLE scheduled_posts ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE scheduled_post_series ALTER COLUMN workspace_id SET NOT NULL;
+
+COMMENT ON COLUMN scheduled_posts.workspace_id IS 'Owning workspace; members act on the post according to their post:* permissions';
+COMMENT ON COLUMN scheduled_post_series.workspace_id IS 'Owning workspace; copied to every materialized occurrence';
@@ -143,3 +143,32 @@ type AnalyticsFilters struct {
 	DateRange DateRange `json:"date_range,omitempty"`
 	Limit     int       `json:"limit,omitempty"`
 }
+
+// BestTimeSlot is a recommended weekly posting time. DayOfWeek and TimeOfDay use the
+// same convention as QueueSlot so a recommendation can be added to a queue as-is.
+type BestTimeSlot struct {
+	DayOfWeek     int     `json:"day_of_week"` // 0 = Sunday
+	Hour          int     `json:"hour"`
+	TimeOfDay     string  `json:"time_of_day"` // HH:MM
+	Score         float64 `json:"score"`       // smoothed average engagement per post
+	AvgEngagement float64 `json:"avg_engagement"`
+	Posts         int     `json:"posts"`
+}
+
+// AccountBestTimes is the weekday × hour engagement heatmap of one account
+type AccountBestTimes struct {
+	AccountID       *string        `json:"account_id"` // nil for platform-level snapshots
+	Platform        string         `json:"platform"`
+	DisplayName     string         `json:"display_name,omitempty"`
+	SampledPosts    int            `json:"sampled_posts"`
+	Heatmap         [7][24]float64 `json:"heatmap"` // [day_of_week][hour] score; 0 where there is no data
+	Samples         [7][24]int     `json:"samples"` // [day_of_week][hour] number of posts
+	Recommendations []BestTimeSlot `json:"recommendations"`
+}
+
+// BestTimesResponse is returned by the best-time-to-post endpoint
+type BestTimesResponse struct {
+	TimeZone string             `json:"timezone"`
+	Since    time.Time          `json:"since"`
+	Accounts []AccountBestTimes `json:"accounts"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CalendarFeed is a member's .ics subscription of a workspace's content calendar
+type CalendarFeed struct {
+	WorkspaceID string     `json:"workspace_id"`
+	URL         string     `json:"url,omitempty"` // holds the secret token; only returned when it is created
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CalendarEvent is one entry of a calendar feed: a scheduled post, a draft or a task
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool     // Start is a date; the event lasts the day
+	Categories  []string // platforms
+	Modified    time.Time
+}
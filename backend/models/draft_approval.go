@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Approval log actions
+const (
+	ApprovalActionSubmit         = "submit"
+	ApprovalActionApprove        = "approve"
+	ApprovalActionRequestChanges = "request_changes"
+	ApprovalActionReset          = "reset" // the draft was edited after review started
+)
+
+// WorkspaceApprover is one required approval of a workspace: either a specific user or
+// any member holding a role
+type WorkspaceApprover struct {
+	ID          int       `json:"id" db:"id"`
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	UserID      *string   `json:"user_id,omitempty" db:"user_id"`
+	Role        *string   `json:"role,omitempty" db:"role"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ApprovalSettings is a workspace's approval configuration
+type ApprovalSettings struct {
+	WorkspaceID string              `json:"workspace_id"`
+	Enabled     bool                `json:"enabled"`
+	Approvers   []WorkspaceApprover `json:"approvers"`
+}
+
+// UpdateApprovalSettingsRequest replaces a workspace's approval configuration
+type UpdateApprovalSettingsRequest struct {
+	Enabled   bool `json:"enabled"`
+	Approvers []struct {
+		UserID *string `json:"user_id,omitempty"`
+		Role   *string `json:"role,omitempty"`
+	} `json:"approvers"`
+}
+
+// DraftApprovalEntry is one row of a draft's approval log
+type DraftApprovalEntry struct {
+	ID         int       `json:"id" db:"id"`
+	DraftID    string    `json:"draft_id" db:"draft_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	UserName   *string   `json:"user_name,omitempty" db:"user_name"`
+	Action     string    `json:"action" db:"action"`
+	Comment    *string   `json:"comment,omitempty" db:"comment"`
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// ApprovalRequirement reports whether one required approver has approved the current review round
+type ApprovalRequirement struct {
+	Approver   WorkspaceApprover `json:"approver"`
+	Satisfied  bool              `json:"satisfied"`
+	ApprovedBy *string           `json:"approved_by,omitempty"`
+}
+
+// DraftApprovalStatus is the approval state of a draft
+type DraftApprovalStatus struct {
+	DraftID      string                `json:"draft_id"`
+	Status       string                `json:"status"`
+	Enabled      bool                  `json:"enabled"`
+	Requirements []ApprovalRequirement `json:"requirements"`
+	Log          []DraftApprovalEntry  `json:"log"`
+}
+
+// DraftReviewRequest carries the reviewer's comment for submit, approve and request-changes
+type DraftReviewRequest struct {
+	Comment string `json:"comment"`
+}
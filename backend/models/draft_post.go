@@ -25,9 +25,30 @@ type DraftPost struct {
 	Content       string     `json:"content"`
 	Media         []string   `json:"media"` // or []Media if you want richer objects
 	Platforms     []string   `json:"platforms"`
-	Status        string     `json:"status"` // draft, scheduled, published
+	Status        string     `json:"status"` // draft, in_review, changes_requested, approved, scheduled, published, failed
 	ScheduledTime *time.Time `json:"scheduled_time"`
 	PublishedTime *time.Time `json:"published_time"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// ScheduledPostID links the draft to the scheduled post that delivers it
+	ScheduledPostID *int `json:"scheduled_post_id,omitempty"`
+}
+
+// Draft status constants
+const (
+	DraftStatusDraft            = "draft"
+	DraftStatusInReview         = "in_review"
+	DraftStatusChangesRequested = "changes_requested"
+	DraftStatusApproved         = "approved"
+	DraftStatusScheduled        = "scheduled"
+	DraftStatusPublished        = "published"
+	DraftStatusFailed           = "failed"
+)
+
+// PublishDraftRequest optionally overrides how a draft is published
+type PublishDraftRequest struct {
+	PublishNow bool                   `json:"publish_now"`       // ignore the draft's scheduled_time
+	Republish  bool                   `json:"republish"`         // publish again a draft whose post already went out
+	Targets    map[string]interface{} `json:"targets,omitempty"` // per-platform account selection
 }
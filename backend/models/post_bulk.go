@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Bulk operations on scheduled posts
+const (
+	BulkReschedule = "reschedule"
+	BulkCancel     = "cancel"
+	BulkDelete     = "delete"
+	BulkDuplicate  = "duplicate"
+	BulkRetarget   = "retarget"
+)
+
+// Outcomes of a bulk operation for one post
+const (
+	BulkItemApplied = "applied"
+	BulkItemSkipped = "skipped" // the post's status doesn't allow the operation
+	BulkItemDenied  = "denied"  // the post doesn't exist or the caller may not change it
+	BulkItemFailed  = "failed"
+)
+
+// BulkPostFilter picks scheduled posts by their attributes instead of by ID
+type BulkPostFilter struct {
+	WorkspaceID string     `json:"workspace_id"`     // required
+	Status      string     `json:"status,omitempty"` // defaults to pending
+	Platform    string     `json:"platform,omitempty"`
+	AccountID   string     `json:"account_id,omitempty"` // posts targeting this social account
+	From        *time.Time `json:"from,omitempty"`       // scheduled at or after
+	To          *time.Time `json:"to,omitempty"`         // scheduled before
+}
+
+// BulkPostRequest applies one action to many scheduled posts, picked by IDs or by Filter
+type BulkPostRequest struct {
+	Action string          `json:"action"` // reschedule, cancel, delete, duplicate, retarget
+	IDs    []int           `json:"ids,omitempty"`
+	Filter *BulkPostFilter `json:"filter,omitempty"`
+
+	// reschedule and duplicate: an absolute time, a wall-clock time (in TimeZone, else each
+	// post's zone), or an offset from each post's own time in its zone. duplicate keeps the
+	// original time when none is given.
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
+	LocalTime     string     `json:"local_time,omitempty"`
+	TimeZone      string     `json:"timezone,omitempty"`
+	OffsetMinutes *int       `json:"offset_minutes,omitempty"`
+
+	// retarget: replaces each post's platforms and/or per-platform targets
+	Platforms []string                `json:"platforms,omitempty"`
+	Targets   *map[string]interface{} `json:"targets,omitempty"`
+}
+
+// BulkItemResult is the outcome of a bulk operation for one post
+type BulkItemResult struct {
+	ID      int            `json:"id"`
+	Outcome string         `json:"outcome"` // applied, skipped, denied, failed
+	Error   *string        `json:"error,omitempty"`
+	Post    *ScheduledPost `json:"post,omitempty"` // the changed post, or the new copy for duplicate
+
+	// Platform errors that kept a retarget from being applied
+	Validation *ValidationResult `json:"validation,omitempty"`
+}
+
+// BulkPostResult reports a bulk operation, item by item in the order the posts were picked
+type BulkPostResult struct {
+	Action  string           `json:"action"`
+	Total   int              `json:"total"`
+	Applied int              `json:"applied"`
+	Items   []BulkItemResult `json:"items"`
+}
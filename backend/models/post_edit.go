@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// EditPublishedPostRequest is the new content pushed to a post that has already been published
+type EditPublishedPostRequest struct {
+	Content *string                 `json:"content,omitempty"`
+	Targets *map[string]interface{} `json:"targets,omitempty"` // replaces the per-platform/account overrides
+}
+
+// Outcomes of editing one published target
+const (
+	EditApplied   = "edited"
+	EditFailed    = "failed"
+	EditImmutable = "immutable" // the platform doesn't allow changing a published post
+)
+
+// PostEditResult is the outcome of an edit for one delivered platform/account target
+type PostEditResult struct {
+	DeliveryID      int     `json:"delivery_id"`
+	Platform        string  `json:"platform"`
+	SocialAccountID *string `json:"social_account_id,omitempty"`
+	AccountName     *string `json:"account_name,omitempty"`
+	RemotePostID    *string `json:"remote_post_id,omitempty"`
+	Status          string  `json:"status"` // edited, failed, immutable
+	Error           *string `json:"error,omitempty"`
+}
+
+// PostEdit is one entry of a published post's edit history
+type PostEdit struct {
+	ID              int                    `json:"id" db:"id"`
+	ScheduledPostID int                    `json:"scheduled_post_id" db:"scheduled_post_id"`
+	EditedBy        *string                `json:"edited_by,omitempty" db:"edited_by"`
+	PreviousContent string                 `json:"previous_content" db:"previous_content"`
+	Content         string                 `json:"content" db:"content"`
+	PreviousTargets map[string]interface{} `json:"previous_targets,omitempty" db:"previous_targets"`
+	Targets         map[string]interface{} `json:"targets,omitempty" db:"targets"`
+	Results         []PostEditResult       `json:"results" db:"results"`
+	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// ImportRow is the outcome of one CSV row of a scheduled post import
+type ImportRow struct {
+	Line       int                         `json:"line"` // line in the CSV; the header is line 1
+	Valid      bool                        `json:"valid"`
+	Post       *CreateScheduledPostRequest `json:"post,omitempty"` // the post the row becomes, once it could be read
+	Errors     []string                    `json:"errors"`
+	Validation *ValidationResult           `json:"validation,omitempty"` // platform errors and warnings
+}
+
+// ImportResult reports a whole import. A preview stores nothing; a commit stores every row
+// or, when any row is invalid, none.
+type ImportResult struct {
+	Valid   bool            `json:"valid"`
+	Total   int             `json:"total"`
+	Rows    []ImportRow     `json:"rows"`
+	Created []ScheduledPost `json:"created,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// PostTemplate is a reusable post of a workspace. Content and the string values of Targets
+// may contain {{variable}} placeholders, filled in when a post is created from it.
+type PostTemplate struct {
+	ID           string                 `json:"id"`
+	WorkspaceID  string                 `json:"workspace_id"`
+	Name         string                 `json:"name"`
+	Content      string                 `json:"content"`
+	MediaURLs    []string               `json:"media_urls"`
+	Platforms    []string               `json:"platforms"`
+	Targets      map[string]interface{} `json:"targets,omitempty"`   // default account selection and overrides
+	Hashtags     []string               `json:"hashtags"`            // appended to the rendered content
+	Variables    map[string]string      `json:"variables,omitempty"` // default placeholder values
+	Placeholders []string               `json:"placeholders"`        // variable names used by the template
+	CreatedBy    *string                `json:"created_by,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// PostTemplateRequest creates a template, or updates the fields that are set
+type PostTemplateRequest struct {
+	Name      *string                 `json:"name,omitempty"`
+	Content   *string                 `json:"content,omitempty"`
+	MediaURLs *[]string               `json:"media_urls,omitempty"`
+	Platforms *[]string               `json:"platforms,omitempty"`
+	Targets   *map[string]interface{} `json:"targets,omitempty"`
+	Hashtags  *[]string               `json:"hashtags,omitempty"`
+	Variables *map[string]string      `json:"variables,omitempty"`
+}
+
+// What a template can be turned into
+const (
+	TemplateAsDraft         = "draft"
+	TemplateAsScheduledPost = "scheduled_post"
+)
+
+// UsePostTemplateRequest creates a draft or a scheduled post from a template
+type UsePostTemplateRequest struct {
+	As        string            `json:"as"`        // draft or scheduled_post
+	Variables map[string]string `json:"variables"` // placeholder values; override the template's defaults
+
+	// When to publish: required for a scheduled post, optional for a draft
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
+	LocalTime     string     `json:"local_time,omitempty"`
+	TimeZone      string     `json:"timezone,omitempty"`
+}
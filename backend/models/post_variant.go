@@ -0,0 +1,36 @@
+package models
+
+// ContentVariant overrides what a post publishes to one platform or one account. Unset fields
+// inherit from the level above: account → platform → the post itself.
+type ContentVariant struct {
+	Content      *string   `json:"content,omitempty"`
+	MediaURLs    *[]string `json:"media_urls,omitempty"` // subset of the post's media; [] publishes text only
+	Hashtags     *[]string `json:"hashtags,omitempty"`   // appended to the text, with or without the leading #
+	Link         *string   `json:"link,omitempty"`       // appended to the text unless it already contains it
+	Title        *string   `json:"title,omitempty"`      // YouTube video title
+	Description  *string   `json:"description,omitempty"`
+	FirstComment *string   `json:"first_comment,omitempty"` // posted as a comment under the published post (Instagram, Facebook)
+}
+
+// PostTarget is the value of one platform key in ScheduledPost.Targets:
+//
+//	{"twitter": {"ids": ["<account>"], "content": "short version", "hashtags": ["launch"],
+//	             "accounts": {"<account>": {"link": "https://..."}}}}
+type PostTarget struct {
+	IDs []string `json:"ids,omitempty"` // accounts to post to; empty means the default account
+	All bool     `json:"all,omitempty"` // post to every connected account of the platform
+	ContentVariant
+	Accounts map[string]ContentVariant `json:"accounts,omitempty"` // per-account overrides, keyed by social account ID
+	Meta     map[string]interface{}    `json:"meta,omitempty"`     // YouTube upload settings: title, description, privacy, categoryId, tags
+}
+
+// PostVariant is the content a target will actually publish once overrides are applied
+type PostVariant struct {
+	Platform     string   `json:"platform"`
+	AccountID    *string  `json:"account_id,omitempty"` // set for per-account overrides
+	Content      string   `json:"content"`
+	MediaURLs    []string `json:"media_urls"`
+	Title        string   `json:"title,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	FirstComment string   `json:"first_comment,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Levels a publishing pause applies at
+const (
+	PauseWorkspace = "workspace"
+	PausePlatform  = "platform"
+	PauseAccount   = "account"
+)
+
+// PublishingPause stops the scheduled post processor from publishing a workspace's posts,
+// or their targets on one platform or social account, until it is resumed
+type PublishingPause struct {
+	ID              int        `json:"id" db:"id"`
+	WorkspaceID     string     `json:"workspace_id" db:"workspace_id"`
+	Scope           string     `json:"scope" db:"scope"`                                   // workspace, platform, account
+	Platform        *string    `json:"platform,omitempty" db:"platform"`                   // set for platform pauses
+	SocialAccountID *string    `json:"social_account_id,omitempty" db:"social_account_id"` // set for account pauses
+	Reason          string     `json:"reason" db:"reason"`
+	PausedBy        *string    `json:"paused_by,omitempty" db:"paused_by"`
+	PausedAt        time.Time  `json:"paused_at" db:"paused_at"`
+	ResumedBy       *string    `json:"resumed_by,omitempty" db:"resumed_by"`
+	ResumedAt       *time.Time `json:"resumed_at,omitempty" db:"resumed_at"` // nil while the pause is active
+	ResumeReason    *string    `json:"resume_reason,omitempty" db:"resume_reason"`
+}
+
+// PausePublishingRequest pauses publishing in a workspace
+type PausePublishingRequest struct {
+	Scope           string `json:"scope"`
+	Platform        string `json:"platform,omitempty"`
+	SocialAccountID string `json:"social_account_id,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// ResumePublishingRequest lifts a publishing pause
+type ResumePublishingRequest struct {
+	Reason string `json:"reason"`
+}
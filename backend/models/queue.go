@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// QueueSlot is a weekly posting time of a workspace queue
+type QueueSlot struct {
+	ID              int       `json:"id" db:"id"`
+	WorkspaceID     string    `json:"workspace_id" db:"workspace_id"`
+	Platform        *string   `json:"platform,omitempty" db:"platform"`                   // nil for any platform
+	SocialAccountID *string   `json:"social_account_id,omitempty" db:"social_account_id"` // nil for any account
+	DayOfWeek       int       `json:"day_of_week" db:"day_of_week"`                       // 0 = Sunday
+	TimeOfDay       string    `json:"time_of_day" db:"time_of_day"`                       // HH:MM
+	CreatedBy       *string   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateQueueSlotRequest is the payload for adding a queue slot
+type CreateQueueSlotRequest struct {
+	Platform        *string `json:"platform,omitempty"`
+	SocialAccountID *string `json:"social_account_id,omitempty"`
+	DayOfWeek       int     `json:"day_of_week"`
+	TimeOfDay       string  `json:"time_of_day"`
+}
+
+// ReorderQueueRequest lists queued post IDs in their new order
+type ReorderQueueRequest struct {
+	PostIDs []int `json:"post_ids"`
+}
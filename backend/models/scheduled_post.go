@@ -8,18 +8,99 @@ import (
 
 // ScheduledPost represents a scheduled social media post
 type ScheduledPost struct {
-	ID            int                    `json:"id" db:"id"`
-	UserID        string                 `json:"user_id" db:"user_id"` // UUID as string
-	Content       string                 `json:"content" db:"content"`
-	MediaURLs     pq.StringArray         `json:"media_urls" db:"media_urls"`
-	Platforms     pq.StringArray         `json:"platforms" db:"platforms"`
-	ScheduledTime time.Time              `json:"scheduled_time" db:"scheduled_time"`
-	Status        string                 `json:"status" db:"status"` // pending, posted, failed, cancelled
-	RetryCount    int                    `json:"retry_count" db:"retry_count"`
-	ErrorMessage  *string                `json:"error_message,omitempty" db:"error_message"`
-	CreatedAt     time.Time              `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time              `json:"updated_at" db:"updated_at"`
-	Targets       map[string]interface{} `json:"targets" db:"targets"`
+	ID             int                     `json:"id" db:"id"`
+	UserID         string                  `json:"user_id" db:"user_id"` // UUID as string
+	Content        string                  `json:"content" db:"content"`
+	MediaURLs      pq.StringArray          `json:"media_urls" db:"media_urls"`
+	Platforms      pq.StringArray          `json:"platforms" db:"platforms"`
+	ScheduledTime  time.Time               `json:"scheduled_time" db:"scheduled_time"`
+	Status         string                  `json:"status" db:"status"` // pending, processing, posted, failed, cancelled, unpublished, missed
+	RetryCount     int                     `json:"retry_count" db:"retry_count"`
+	NextAttemptAt  *time.Time              `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // set while failed targets are backing off
+	LockedBy       *string                 `json:"locked_by,omitempty" db:"locked_by"`             // worker holding the processing lease
+	LeaseExpiresAt *time.Time              `json:"lease_expires_at,omitempty" db:"lease_expires_at"`
+	ErrorMessage   *string                 `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt      time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at" db:"updated_at"`
+	Targets        map[string]interface{}  `json:"targets" db:"targets"`
+	Deliveries     []ScheduledPostDelivery `json:"deliveries,omitempty" db:"-"`
+	SeriesID       *int                    `json:"series_id,omitempty" db:"series_id"`         // set for occurrences of a recurring post
+	OccurrenceAt   *time.Time              `json:"occurrence_at,omitempty" db:"occurrence_at"` // rule time of the occurrence
+	WorkspaceID    *string                 `json:"workspace_id,omitempty" db:"workspace_id"`
+	QueuePosition  *int                    `json:"queue_position,omitempty" db:"queue_position"` // set while the post sits in its workspace queue
+	TimeZone       *string                 `json:"timezone,omitempty" db:"timezone"`             // IANA zone the post was scheduled in
+	LocalTime      string                  `json:"local_scheduled_time,omitempty" db:"-"`        // ScheduledTime rendered in TimeZone
+	Validation     *ValidationResult       `json:"validation,omitempty" db:"-"`                  // platform warnings found when the post was saved
+	Variants       []PostVariant           `json:"variants,omitempty" db:"-"`                    // what each platform/account publishes after overrides
+	Thread         []ThreadPart            `json:"thread,omitempty" db:"thread"`                 // follow-up parts published as a thread after the main post
+
+	// Missed-schedule policy: a post still unpublished MissedWindow minutes after its scheduled
+	// time is marked missed instead of published late; nil inherits the workspace's window
+	MissedWindow *int       `json:"missed_window_minutes,omitempty" db:"missed_window_minutes"`
+	MissedAt     *time.Time `json:"missed_at,omitempty" db:"missed_at"`
+}
+
+// ScheduledPostDelivery tracks the outcome of a scheduled post for one platform/account target
+type ScheduledPostDelivery struct {
+	ID              int        `json:"id" db:"id"`
+	ScheduledPostID int        `json:"scheduled_post_id" db:"scheduled_post_id"`
+	Platform        string     `json:"platform" db:"platform"`
+	SocialAccountID *string    `json:"social_account_id,omitempty" db:"social_account_id"` // nil when no account could be resolved
+	AccountName     *string    `json:"account_name,omitempty" db:"account_name"`
+	Status          string     `json:"status" db:"status"` // pending, posted, failed, unpublished
+	Attempts        int        `json:"attempts" db:"attempts"`
+	RemotePostID    *string    `json:"remote_post_id,omitempty" db:"remote_post_id"`
+	Permalink       *string    `json:"permalink,omitempty" db:"permalink"`
+	LastError       *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt   *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // nil once posted or out of attempts
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	// First comment posted under the delivered post, tracked apart from the post itself
+	FirstCommentStatus *string    `json:"first_comment_status,omitempty" db:"first_comment_status"` // posted, failed
+	FirstCommentID     *string    `json:"first_comment_id,omitempty" db:"first_comment_id"`
+	FirstCommentError  *string    `json:"first_comment_error,omitempty" db:"first_comment_error"`
+	FirstCommentAt     *time.Time `json:"first_comment_at,omitempty" db:"first_comment_at"`
+
+	// Remote IDs of the steps of a multi-step publish done so far (Telegram albums, Facebook videos)
+	PublishedParts pq.StringArray `json:"published_parts,omitempty" db:"published_parts"`
+
+	// Per-part results of a multi-part post
+	Parts []ThreadPartDelivery `json:"parts,omitempty" db:"-"`
+}
+
+// UnpublishRecord is one entry of a post's unpublish audit trail: the outcome of deleting
+// one remote post (a delivery's main post or one of its thread parts)
+type UnpublishRecord struct {
+	ID              int       `json:"id" db:"id"`
+	ScheduledPostID int       `json:"scheduled_post_id" db:"scheduled_post_id"`
+	DeliveryID      *int      `json:"delivery_id,omitempty" db:"delivery_id"`
+	Platform        string    `json:"platform" db:"platform"`
+	SocialAccountID *string   `json:"social_account_id,omitempty" db:"social_account_id"`
+	AccountName     *string   `json:"account_name,omitempty" db:"account_name"`
+	RemotePostID    *string   `json:"remote_post_id,omitempty" db:"remote_post_id"`
+	PartIndex       int       `json:"part_index" db:"part_index"` // 0 is the main post
+	Status          string    `json:"status" db:"status"`         // deleted, failed, partially_failed, unsupported
+	Error           *string   `json:"error,omitempty" db:"error"`
+	RequestedBy     *string   `json:"requested_by,omitempty" db:"requested_by"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// ThreadPart is one follow-up part of a multi-part scheduled post
+type ThreadPart struct {
+	Content   string   `json:"content"`
+	MediaURLs []string `json:"media_urls,omitempty"`
+}
+
+// ThreadPartDelivery records the publish state of one part of a thread for one account
+type ThreadPartDelivery struct {
+	PartIndex    int        `json:"part_index" db:"part_index"` // 0 is the main post
+	Status       string     `json:"status" db:"status"`         // posted, failed
+	RemotePostID *string    `json:"remote_post_id,omitempty" db:"remote_post_id"`
+	Permalink    *string    `json:"permalink,omitempty" db:"permalink"`
+	LastError    *string    `json:"last_error,omitempty" db:"last_error"`
+	PostedAt     *time.Time `json:"posted_at,omitempty" db:"posted_at"`
 }
 
 // CreateScheduledPostRequest represents the request payload for creating a scheduled post
@@ -28,23 +109,63 @@ type CreateScheduledPostRequest struct {
 	MediaURLs     []string               `json:"media_urls"`
 	Platforms     []string               `json:"platforms" validate:"required,min=1"`
 	ScheduledTime time.Time              `json:"scheduled_time" validate:"required"`
-	Targets       map[string]interface{} `json:"targets"`
+	Targets       map[string]interface{} `json:"targets"`              // platform → PostTarget (account selection and content overrides)
+	Recurrence    *RecurrenceRequest     `json:"recurrence,omitempty"` // makes the post recurring
+	WorkspaceID   *string                `json:"workspace_id,omitempty"`
+	Queue         bool                   `json:"queue,omitempty"`      // add to the workspace queue instead of using ScheduledTime
+	LocalTime     string                 `json:"local_time,omitempty"` // wall-clock alternative to ScheduledTime, e.g. 2026-03-08T09:00
+	TimeZone      string                 `json:"timezone,omitempty"`   // IANA zone for LocalTime; defaults to the workspace's, then the user's
+	Thread        []ThreadPart           `json:"thread,omitempty"`     // follow-up parts, published as a thread where the platform supports it
+
+	// Publish at most this many minutes late, else mark the post missed; nil uses the workspace's window
+	MissedWindow *int `json:"missed_window_minutes,omitempty"`
 }
 
 // UpdateScheduledPostRequest represents the request payload for updating a scheduled post
 type UpdateScheduledPostRequest struct {
-	Content       *string    `json:"content,omitempty"`
-	MediaURLs     *[]string  `json:"media_urls,omitempty"`
-	Platforms     *[]string  `json:"platforms,omitempty"`
-	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
+	Content       *string                 `json:"content,omitempty"`
+	MediaURLs     *[]string               `json:"media_urls,omitempty"`
+	Platforms     *[]string               `json:"platforms,omitempty"`
+	ScheduledTime *time.Time              `json:"scheduled_time,omitempty"`
+	LocalTime     *string                 `json:"local_time,omitempty"`
+	TimeZone      *string                 `json:"timezone,omitempty"`
+	Targets       *map[string]interface{} `json:"targets,omitempty"`
+	Thread        *[]ThreadPart           `json:"thread,omitempty"`
+	MissedWindow  *int                    `json:"missed_window_minutes,omitempty"` // 0 goes back to the workspace's window
+}
+
+// Actions that resolve a missed post
+const (
+	MissedPublishNow = "publish_now"
+	MissedReschedule = "reschedule"
+	MissedDiscard    = "discard"
+)
+
+// ResolveMissedPostRequest decides what happens to a post that missed its schedule
+type ResolveMissedPostRequest struct {
+	Action        string     `json:"action"`                   // publish_now, reschedule, discard
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"` // new time for reschedule
+	LocalTime     string     `json:"local_time,omitempty"`     // wall-clock alternative to ScheduledTime
+	TimeZone      string     `json:"timezone,omitempty"`       // IANA zone for LocalTime; defaults to the post's
 }
 
 // ScheduledPostStatus constants
 const (
-	StatusPending   = "pending"
-	StatusPosted    = "posted"
-	StatusFailed    = "failed"
-	StatusCancelled = "cancelled"
+	StatusPending     = "pending"
+	StatusProcessing  = "processing"
+	StatusPosted      = "posted"
+	StatusFailed      = "failed"
+	StatusCancelled   = "cancelled"
+	StatusUnpublished = "unpublished" // posted, then deleted from the platforms again
+	StatusMissed      = "missed"      // too late to publish; waits for publish now, reschedule or discard
+)
+
+// Outcomes of deleting one remote post while unpublishing
+const (
+	UnpublishDeleted     = "deleted"
+	UnpublishFailed      = "failed"
+	UnpublishPartial     = "partially_failed" // not deleted, though other parts of the same post were
+	UnpublishUnsupported = "unsupported"      // the platform has no delete API
 )
 
 // IsEditable returns true if the scheduled post can be edited
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ScheduledPostSeries is a recurring post: a content template plus an RRULE or cron rule.
+// Its occurrences are materialized one at a time as ScheduledPost rows.
+type ScheduledPostSeries struct {
+	ID                 int                    `json:"id" db:"id"`
+	UserID             string                 `json:"user_id" db:"user_id"`
+	WorkspaceID        *string                `json:"workspace_id,omitempty" db:"workspace_id"`
+	Content            string                 `json:"content" db:"content"`
+	MediaURLs          pq.StringArray         `json:"media_urls" db:"media_urls"`
+	Platforms          pq.StringArray         `json:"platforms" db:"platforms"`
+	Targets            map[string]interface{} `json:"targets" db:"targets"`
+	RecurrenceRule     string                 `json:"recurrence_rule" db:"recurrence_rule"`
+	StartsAt           time.Time              `json:"starts_at" db:"starts_at"`
+	TimeZone           string                 `json:"timezone" db:"timezone"` // zone the rule's wall-clock times are in
+	EndsAt             *time.Time             `json:"ends_at,omitempty" db:"ends_at"`
+	MaxOccurrences     *int                   `json:"max_occurrences,omitempty" db:"max_occurrences"`
+	OccurrencesCreated int                    `json:"occurrences_created" db:"occurrences_created"`
+	LastOccurrenceAt   *time.Time             `json:"last_occurrence_at,omitempty" db:"last_occurrence_at"`
+	Status             string                 `json:"status" db:"status"`                                         // active, completed, cancelled
+	MissedWindow       *int                   `json:"missed_window_minutes,omitempty" db:"missed_window_minutes"` // copied to each occurrence
+	CreatedAt          time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// RecurrenceRequest makes a CreateScheduledPostRequest recurring. ScheduledTime is the first occurrence.
+type RecurrenceRequest struct {
+	Rule  string     `json:"rule"`            // RRULE ("FREQ=WEEKLY;BYDAY=MO") or cron ("0 9 * * 1")
+	Until *time.Time `json:"until,omitempty"` // last possible occurrence time
+	Count *int       `json:"count,omitempty"` // total number of occurrences
+}
+
+// ScheduledPostOccurrence is one upcoming occurrence of a series
+type ScheduledPostOccurrence struct {
+	OccurrenceAt  time.Time      `json:"occurrence_at"`  // rule time; identifies the occurrence
+	ScheduledTime time.Time      `json:"scheduled_time"` // when it will actually be published
+	Status        string         `json:"status"`         // pending, processing, skipped or planned
+	Edited        bool           `json:"edited"`
+	PostID        *int           `json:"post_id,omitempty"` // set once materialized
+	Content       string         `json:"content"`
+	MediaURLs     pq.StringArray `json:"media_urls"`
+}
+
+// UpdateOccurrenceRequest skips or edits a single occurrence of a series
+type UpdateOccurrenceRequest struct {
+	OccurrenceAt  time.Time  `json:"occurrence_at"`
+	Skip          bool       `json:"skip,omitempty"`
+	Content       *string    `json:"content,omitempty"`
+	MediaURLs     *[]string  `json:"media_urls,omitempty"`
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
+}
+
+// ScheduledPostSeries status constants
+const (
+	SeriesStatusActive    = "active"
+	SeriesStatusCompleted = "completed"
+	SeriesStatusCancelled = "cancelled"
+)
+
+// OccurrenceStatus values for occurrences that have no scheduled_posts row yet
+const (
+	OccurrenceStatusPlanned = "planned"
+	OccurrenceStatusSkipped = "skipped"
+)
@@ -32,6 +32,7 @@ type User struct {
     UpdatedAt       *time.Time `json:"updated_at"`     // Changed to *time.Time (nullable)
     IsVerified      *bool      `json:"is_verified"`    // Changed to *bool (nullable)
     IsActive        *bool      `json:"is_active"`      // Changed to *bool (nullable)
+    Timezone        *string    `json:"timezone"`       // IANA zone, e.g. "Europe/Berlin" (nullable)
 }
 
 // CREATE EXTENSION IF NOT EXISTS "pgcrypto";
@@ -0,0 +1,27 @@
+package models
+
+// ValidationIssue is one problem found while checking a post against a platform's rules
+type ValidationIssue struct {
+	Code    string `json:"code"`  // e.g. too_long, too_many_media, unsupported_media
+	Field   string `json:"field"` // content, media, media[2], targets.youtube.meta.title
+	Message string `json:"message"`
+	Limit   *int64 `json:"limit,omitempty"` // the platform limit that was exceeded, when there is one
+	Actual  *int64 `json:"actual,omitempty"`
+}
+
+// TargetValidation is the validation outcome for one target platform or account override
+type TargetValidation struct {
+	Platform       string            `json:"platform"`
+	AccountID      *string           `json:"account_id,omitempty"` // set when an account override is checked
+	Valid          bool              `json:"valid"`
+	CharacterCount int               `json:"character_count"` // weighted for Twitter
+	CharacterLimit int               `json:"character_limit,omitempty"`
+	Errors         []ValidationIssue `json:"errors"`
+	Warnings       []ValidationIssue `json:"warnings"`
+}
+
+// ValidationResult is the pre-flight report for a post; Valid is false when any target has errors
+type ValidationResult struct {
+	Valid   bool               `json:"valid"`
+	Targets []TargetValidation `json:"targets"`
+}
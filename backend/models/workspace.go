@@ -8,7 +8,11 @@ type Workspace struct {
 	Avatar    *string   `json:"avatar"`
 	AdminID   string    `json:"admin_id"`
 	AdminName string    `json:"admin_name"`
+	Timezone  *string   `json:"timezone"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Default staleness window of the workspace's posts; nil publishes late posts
+	MissedWindow *int `json:"missed_window_minutes"`
 }
 
 type WorkspaceMember struct {
@@ -18,6 +18,9 @@ func RegisterAnalyticsRoutes(r *mux.Router) {
 	// Get platform comparison
 	analyticsRouter.HandleFunc("/platforms", controllers.GetPlatformComparison).Methods("GET")
 
+	// Best times to post (weekday × hour heatmap per account)
+	analyticsRouter.HandleFunc("/best-times", controllers.GetBestTimesToPost).Methods("GET")
+
 	// Manual analytics sync
 	analyticsRouter.HandleFunc("/sync", controllers.SyncAnalytics).Methods("POST")
 }
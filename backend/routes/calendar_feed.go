@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"social-sync-backend/controllers"
+	"social-sync-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterCalendarFeedRoutes(r *mux.Router) {
+	feed := r.PathPrefix("/api/workspaces/{workspaceId}/calendar-feed").Subrouter()
+	feed.Use(middleware.JWTMiddleware)
+	feed.HandleFunc("", controllers.GetCalendarFeed).Methods("GET")
+	feed.HandleFunc("", controllers.CreateCalendarFeed).Methods("POST")
+	feed.HandleFunc("", controllers.RevokeCalendarFeed).Methods("DELETE")
+
+	// Calendar apps subscribe without logging in; the token in the URL is the credential
+	r.HandleFunc("/api/calendar/{token:[0-9a-f]+}.ics", controllers.ServeCalendarFeed).Methods("GET")
+}
@@ -16,6 +16,18 @@ func RegisterDraftPostRoutes(r *mux.Router) {
 	drafts.HandleFunc("/{draftId}", controllers.DeleteDraftPost).Methods("DELETE")
 	drafts.HandleFunc("/{draftId}/publish", controllers.PublishDraftPost).Methods("POST")
 
+	// Draft approval workflow
+	drafts.HandleFunc("/{draftId}/approvals", controllers.GetDraftApprovals).Methods("GET")
+	drafts.HandleFunc("/{draftId}/submit", controllers.SubmitDraftForReview).Methods("POST")
+	drafts.HandleFunc("/{draftId}/approve", controllers.ApproveDraft).Methods("POST")
+	drafts.HandleFunc("/{draftId}/request-changes", controllers.RequestDraftChanges).Methods("POST")
+
+	// Workspace approval settings
+	approvals := r.PathPrefix("/api/workspaces/{workspaceId}/approval-settings").Subrouter()
+	approvals.Use(middleware.JWTMiddleware)
+	approvals.HandleFunc("", controllers.GetApprovalSettings).Methods("GET")
+	approvals.HandleFunc("", controllers.UpdateApprovalSettings).Methods("PUT")
+
 	// Draft comments
 	comments := drafts.PathPrefix("/{draftId}/comments").Subrouter()
 	comments.HandleFunc("", controllers.DraftListComments).Methods("GET")
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"social-sync-backend/controllers"
+	"social-sync-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterPostTemplateRoutes(r *mux.Router) {
+	templates := r.PathPrefix("/api/workspaces/{workspaceId}/templates").Subrouter()
+	templates.Use(middleware.JWTMiddleware)
+	templates.HandleFunc("", controllers.ListPostTemplates).Methods("GET")
+	templates.HandleFunc("", controllers.CreatePostTemplate).Methods("POST")
+	templates.HandleFunc("/{templateId}", controllers.GetPostTemplate).Methods("GET")
+	templates.HandleFunc("/{templateId}", controllers.UpdatePostTemplate).Methods("PATCH")
+	templates.HandleFunc("/{templateId}", controllers.DeletePostTemplate).Methods("DELETE")
+	templates.HandleFunc("/{templateId}/use", controllers.UsePostTemplate).Methods("POST")
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"social-sync-backend/controllers"
+	"social-sync-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterPublishingPauseRoutes(r *mux.Router) {
+	pauses := r.PathPrefix("/api/workspaces/{workspaceId}/pauses").Subrouter()
+	pauses.Use(middleware.JWTMiddleware)
+	pauses.HandleFunc("", controllers.GetPublishingPauses).Methods("GET")
+	pauses.HandleFunc("", controllers.PausePublishing).Methods("POST")
+	pauses.HandleFunc("/{pauseId}/resume", controllers.ResumePublishing).Methods("POST")
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"social-sync-backend/controllers"
+	"social-sync-backend/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterQueueRoutes(r *mux.Router) {
+	queue := r.PathPrefix("/api/workspaces/{workspaceId}/queue").Subrouter()
+	queue.Use(middleware.JWTMiddleware)
+	queue.HandleFunc("", controllers.GetQueue).Methods("GET")
+	queue.HandleFunc("/order", controllers.ReorderQueue).Methods("PUT")
+	queue.HandleFunc("/slots", controllers.CreateQueueSlot).Methods("POST")
+	queue.HandleFunc("/slots/{slotId}", controllers.DeleteQueueSlot).Methods("DELETE")
+	queue.HandleFunc("/{postId}", controllers.RemoveFromQueue).Methods("DELETE")
+}
@@ -14,6 +14,10 @@ func InitRoutes() *mux.Router {
 	RegisterDraftPostRoutes(r)
 	RegisterMediaRoutes(r)
 	ScheduledPostRoutes(r)
+	RegisterQueueRoutes(r)
+	RegisterPublishingPauseRoutes(r)
+	RegisterCalendarFeedRoutes(r)
+	RegisterPostTemplateRoutes(r)
 	RegisterAnalyticsRoutes(r)
 	// Add more like RegisterPostRoutes(r), etc.
 
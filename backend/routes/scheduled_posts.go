@@ -20,6 +20,41 @@ func ScheduledPostRoutes(r *mux.Router) {
 		http.HandlerFunc(controllers.GetScheduledPostsHandler(lib.DB)),
 	))).Methods("GET", "OPTIONS")
 
+	// Dry run of the platform checks done on create/update
+	r.Handle("/api/scheduled-posts/validate", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.ValidateScheduledPostHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+
+	// Bulk import from CSV: preview reports per-row errors, import stores all rows or none
+	r.Handle("/api/scheduled-posts/import/preview", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.PreviewScheduledPostImportHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+	r.Handle("/api/scheduled-posts/import", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.ImportScheduledPostsHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+
+	// Reschedule, cancel, delete, duplicate or retarget many posts at once
+	r.Handle("/api/scheduled-posts/bulk", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.BulkScheduledPostsHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+
+	// ----------- Recurring Posts ----------- //
+	r.Handle("/api/scheduled-posts/series/{id}", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.GetScheduledPostSeriesHandler(lib.DB)),
+	))).Methods("GET", "OPTIONS")
+
+	r.Handle("/api/scheduled-posts/series/{id}", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.DeleteScheduledPostSeriesHandler(lib.DB)),
+	))).Methods("DELETE", "OPTIONS")
+
+	r.Handle("/api/scheduled-posts/series/{id}/occurrences", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.GetSeriesOccurrencesHandler(lib.DB)),
+	))).Methods("GET", "OPTIONS")
+
+	r.Handle("/api/scheduled-posts/series/{id}/occurrences", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.UpdateSeriesOccurrenceHandler(lib.DB)),
+	))).Methods("PUT", "OPTIONS")
+
 	r.Handle("/api/scheduled-posts/{id}", middleware.EnableCORS(middleware.JWTMiddleware(
 		http.HandlerFunc(controllers.GetScheduledPostHandler(lib.DB)),
 	))).Methods("GET", "OPTIONS")
@@ -31,4 +66,27 @@ func ScheduledPostRoutes(r *mux.Router) {
 	r.Handle("/api/scheduled-posts/{id}", middleware.EnableCORS(middleware.JWTMiddleware(
 		http.HandlerFunc(controllers.DeleteScheduledPostHandler(lib.DB)),
 	))).Methods("DELETE", "OPTIONS")
+
+	// Publish now, reschedule or discard a post that missed its schedule
+	r.Handle("/api/scheduled-posts/{id}/missed", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.ResolveMissedPostHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+
+	// Change a posted post in place where the platform allows it, and its edit history
+	r.Handle("/api/scheduled-posts/{id}/published", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.EditPublishedPostHandler(lib.DB)),
+	))).Methods("PUT", "OPTIONS")
+
+	r.Handle("/api/scheduled-posts/{id}/edits", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.GetPostEditsHandler(lib.DB)),
+	))).Methods("GET", "OPTIONS")
+
+	// Delete a posted post from its platforms; the log is the audit trail of those deletions
+	r.Handle("/api/scheduled-posts/{id}/unpublish", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.UnpublishScheduledPostHandler(lib.DB)),
+	))).Methods("POST", "OPTIONS")
+
+	r.Handle("/api/scheduled-posts/{id}/unpublish-log", middleware.EnableCORS(middleware.JWTMiddleware(
+		http.HandlerFunc(controllers.GetUnpublishLogHandler(lib.DB)),
+	))).Methods("GET", "OPTIONS")
 }
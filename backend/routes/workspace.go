@@ -23,5 +23,9 @@ func RegisterWorkspaceRoutes(r *mux.Router) {
 		middleware.JWTMiddleware(http.HandlerFunc(controllers.DeleteWorkspace))).Methods("DELETE")
 	r.Handle("/api/workspaces/{workspaceId}/members/{memberId}/role",
 		middleware.JWTMiddleware(http.HandlerFunc(controllers.ChangeMemberRole))).Methods("PATCH")
+	r.Handle("/api/workspaces/{workspaceId}/timezone",
+		middleware.JWTMiddleware(http.HandlerFunc(controllers.UpdateWorkspaceTimezone))).Methods("PATCH")
+	r.Handle("/api/workspaces/{workspaceId}/missed-window",
+		middleware.JWTMiddleware(http.HandlerFunc(controllers.UpdateWorkspaceMissedWindow))).Methods("PATCH")
 	r.HandleFunc("/ws/{workspaceId}", controllers.WorkspaceWSHandler).Methods("GET")
 }
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"context"
 	"log"
+	"sync"
 	"time"
 
 	"social-sync-backend/lib"
@@ -12,6 +14,8 @@ import (
 // AnalyticsScheduler manages background jobs for analytics syncing
 type AnalyticsScheduler struct {
 	stopChan chan bool
+	stopOnce sync.Once
+	wg       sync.WaitGroup // one per ticker goroutine; held while a sync runs
 }
 
 // NewAnalyticsScheduler creates a new analytics scheduler
@@ -26,22 +30,61 @@ func (as *AnalyticsScheduler) Start() {
 	// Starting analytics scheduler
 
 	// Start platform-specific tickers
-	go as.startMastodonTicker()
-	go as.startFacebookTicker()
-	go as.startInstagramTicker()
-	// go as.startTwitterTicker() // DISABLED for testing
-	go as.startYouTubeTicker()
+	as.run(as.startMastodonTicker)
+	as.run(as.startFacebookTicker)
+	as.run(as.startInstagramTicker)
+	// as.run(as.startTwitterTicker) // DISABLED for testing
+	as.run(as.startYouTubeTicker)
 
 	// Start user-specific sync job
-	go as.startUserSyncJob()
+	as.run(as.startUserSyncJob)
 
 	// Analytics scheduler started
 }
 
-// Stop stops the analytics scheduler
+// run starts a ticker goroutine that Shutdown waits for
+func (as *AnalyticsScheduler) run(job func()) {
+	as.wg.Add(1)
+	go func() {
+		defer as.wg.Done()
+		job()
+	}()
+}
+
+// Stop stops the analytics scheduler without waiting for running syncs
 func (as *AnalyticsScheduler) Stop() {
 	// Stopping analytics scheduler
-	close(as.stopChan)
+	as.stopOnce.Do(func() { close(as.stopChan) })
+}
+
+// Shutdown stops scheduling new syncs and waits until ctx is done for the running ones.
+// A sync finishes the account it is on and skips the remaining users; snapshots are
+// upserted, so an interrupted run is simply repeated on the next tick.
+func (as *AnalyticsScheduler) Shutdown(ctx context.Context) error {
+	as.Stop()
+	done := make(chan struct{})
+	go func() {
+		as.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("Analytics scheduler drained")
+		return nil
+	case <-ctx.Done():
+		log.Println("Shutdown deadline reached with analytics syncs still running")
+		return ctx.Err()
+	}
+}
+
+// stopped reports whether Stop has been called, so long syncs can bail out between users
+func (as *AnalyticsScheduler) stopped() bool {
+	select {
+	case <-as.stopChan:
+		return true
+	default:
+		return false
+	}
 }
 
 // startMastodonTicker runs Mastodon analytics sync every 2 hours
@@ -185,6 +228,9 @@ func (as *AnalyticsScheduler) syncPlatformAnalytics(platform string) {
 
 	// Sync analytics for each user
 	for _, userID := range userIDs {
+		if as.stopped() {
+			return
+		}
 		syncer := NewAnalyticsSyncer(userID, platform)
 		if err := syncer.SyncAnalytics(); err != nil {
 			// Error syncing analytics
@@ -220,6 +266,9 @@ func (as *AnalyticsScheduler) syncAllUsers() {
 
 	// Sync analytics for each user
 	for _, userID := range userIDs {
+		if as.stopped() {
+			return
+		}
 		if err := SyncAllUserAnalytics(userID); err != nil {
 			log.Printf("Error syncing analytics for user %s: %v", userID, err)
 		}
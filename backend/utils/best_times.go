@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// bestTimePrior is how many "average" posts each heatmap cell is padded with, so a
+	// single viral post does not outrank an hour with a consistent track record
+	bestTimePrior = 2.0
+	// DefaultBestTimeLimit is the number of recommendations returned when none is requested
+	DefaultBestTimeLimit = 3
+)
+
+// BestTimesFilter selects the analytics history mined for recommendations
+type BestTimesFilter struct {
+	AccountIDs []string
+	Platforms  []string
+	Since      time.Time
+	Location   *time.Location
+	Limit      int
+}
+
+// accountPosts collects the distinct top posts seen for one account
+type accountPosts struct {
+	best  models.AccountBestTimes
+	posts map[string]models.TopPost
+}
+
+// GetBestTimesToPost builds a weekday × hour engagement heatmap per account from the
+// top posts stored in post_analytics and returns the best slots of each. Posts are
+// bucketed by their publish time in filter.Location; a post that appears in several
+// snapshots counts once, with its most recent metrics.
+func GetBestTimesToPost(db *sql.DB, userID string, filter BestTimesFilter) ([]models.AccountBestTimes, error) {
+	if filter.Location == nil {
+		filter.Location = time.UTC
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultBestTimeLimit
+	}
+
+	query := `
+		SELECT pa.platform, pa.account_id::text, COALESCE(sa.display_name, sa.profile_name, ''), pa.top_posts
+		FROM post_analytics pa
+		LEFT JOIN social_accounts sa ON sa.id = pa.account_id
+		WHERE pa.user_id = $1 AND pa.snapshot_at >= $2 AND pa.top_posts IS NOT NULL
+	`
+	args := []interface{}{userID, filter.Since}
+	if len(filter.AccountIDs) > 0 {
+		args = append(args, pq.Array(filter.AccountIDs))
+		query += fmt.Sprintf(" AND pa.account_id = ANY($%d::uuid[])", len(args))
+	}
+	if len(filter.Platforms) > 0 {
+		args = append(args, pq.Array(filter.Platforms))
+		query += fmt.Sprintf(" AND pa.platform = ANY($%d)", len(args))
+	}
+	query += " ORDER BY pa.snapshot_at DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query post analytics: %v", err)
+	}
+	defer rows.Close()
+
+	byAccount := make(map[string]*accountPosts)
+	var order []string
+	for rows.Next() {
+		var platform, displayName string
+		var accountID *string
+		var rawTopPosts []byte
+		if err := rows.Scan(&platform, &accountID, &displayName, &rawTopPosts); err != nil {
+			return nil, fmt.Errorf("failed to scan post analytics: %v", err)
+		}
+
+		var topPosts []models.TopPost
+		if err := json.Unmarshal(rawTopPosts, &topPosts); err != nil {
+			continue
+		}
+
+		key := platform
+		if accountID != nil {
+			key += "|" + *accountID
+		}
+		acc, ok := byAccount[key]
+		if !ok {
+			acc = &accountPosts{
+				best:  models.AccountBestTimes{AccountID: accountID, Platform: platform, DisplayName: displayName},
+				posts: make(map[string]models.TopPost),
+			}
+			byAccount[key] = acc
+			order = append(order, key)
+		}
+		for _, p := range topPosts {
+			if p.ID == "" || p.CreatedAt.IsZero() {
+				continue
+			}
+			// Snapshots are newest first, so the first copy has the latest metrics
+			if _, seen := acc.posts[p.ID]; !seen {
+				acc.posts[p.ID] = p
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate post analytics: %v", err)
+	}
+
+	results := make([]models.AccountBestTimes, 0, len(order))
+	for _, key := range order {
+		acc := byAccount[key]
+		scoreHeatmap(&acc.best, acc.posts, filter.Location, filter.Limit)
+		results = append(results, acc.best)
+	}
+	return results, nil
+}
+
+// topPostEngagement falls back to summing interactions for platforms that don't report engagement
+func topPostEngagement(p models.TopPost) float64 {
+	if p.Engagement > 0 {
+		return float64(p.Engagement)
+	}
+	return float64(p.Likes + p.Comments + p.Shares)
+}
+
+// scoreHeatmap fills the heatmap, sample counts and top recommendations of best.
+// Each cell's score is its average engagement shrunk towards the account mean.
+func scoreHeatmap(best *models.AccountBestTimes, posts map[string]models.TopPost, loc *time.Location, limit int) {
+	var totals [7][24]float64
+	var overall float64
+	for _, p := range posts {
+		t := p.CreatedAt.In(loc)
+		d, h := int(t.Weekday()), t.Hour()
+		e := topPostEngagement(p)
+		totals[d][h] += e
+		best.Samples[d][h]++
+		overall += e
+	}
+	best.SampledPosts = len(posts)
+	best.Recommendations = []models.BestTimeSlot{}
+	if len(posts) == 0 {
+		return
+	}
+	mean := overall / float64(len(posts))
+
+	var slots []models.BestTimeSlot
+	for d := 0; d < 7; d++ {
+		for h := 0; h < 24; h++ {
+			n := best.Samples[d][h]
+			if n == 0 {
+				continue
+			}
+			score := roundScore((totals[d][h] + bestTimePrior*mean) / (float64(n) + bestTimePrior))
+			best.Heatmap[d][h] = score
+			slots = append(slots, models.BestTimeSlot{
+				DayOfWeek:     d,
+				Hour:          h,
+				TimeOfDay:     fmt.Sprintf("%02d:00", h),
+				Score:         score,
+				AvgEngagement: roundScore(totals[d][h] / float64(n)),
+				Posts:         n,
+			})
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Score != slots[j].Score {
+			return slots[i].Score > slots[j].Score
+		}
+		return slots[i].Posts > slots[j].Posts
+	})
+	if len(slots) > limit {
+		slots = slots[:limit]
+	}
+	best.Recommendations = slots
+}
+
+func roundScore(v float64) float64 {
+	return math.Round(v*100) / 100
+}
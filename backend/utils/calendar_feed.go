@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// calendarFeedPast is how far back the feed lists events; older ones drop off calendars
+	calendarFeedPast = 90 * 24 * time.Hour
+	// calendarEventLength is how long a post or draft appears to last in a calendar
+	calendarEventLength = 15 * time.Minute
+	// calendarSummaryPreview and calendarDescriptionPreview cap the content shown in events, in runes
+	calendarSummaryPreview     = 60
+	calendarDescriptionPreview = 500
+)
+
+// ErrCalendarFeedNotFound is returned for a token that doesn't belong to any calendar feed
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// CalendarFeedOptions selects what a calendar feed lists besides scheduled posts
+type CalendarFeedOptions struct {
+	Drafts bool // drafts with a scheduled time that no scheduled post carries yet
+	Tasks  bool // tasks with a due date
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarFeedToken issues the user's feed token for a workspace, replacing (and so
+// revoking) any earlier one. Only its hash is stored; the token is returned once.
+func CreateCalendarFeedToken(db *sql.DB, workspaceID, userID string) (string, time.Time, error) {
+	token, err := GenerateVerificationToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token: %v", err)
+	}
+	var createdAt time.Time
+	err = db.QueryRow(`
+		INSERT INTO calendar_feed_tokens (workspace_id, user_id, token_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id)
+		DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_used_at = NULL
+		RETURNING created_at
+	`, workspaceID, userID, hashCalendarToken(token)).Scan(&createdAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to save calendar feed token: %v", err)
+	}
+	return token, createdAt, nil
+}
+
+// GetCalendarFeed returns the user's feed of a workspace, without its URL, or nil if none
+func GetCalendarFeed(db *sql.DB, workspaceID, userID string) (*models.CalendarFeed, error) {
+	feed := models.CalendarFeed{WorkspaceID: workspaceID}
+	err := db.QueryRow(`
+		SELECT created_at, last_used_at FROM calendar_feed_tokens WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&feed.CreatedAt, &feed.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar feed: %v", err)
+	}
+	return &feed, nil
+}
+
+// RevokeCalendarFeedToken removes the user's feed token of a workspace; it reports whether
+// there was one
+func RevokeCalendarFeedToken(db *sql.DB, workspaceID, userID string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM calendar_feed_tokens WHERE workspace_id = $1 AND user_id = $2`, workspaceID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke calendar feed: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// LookupCalendarFeedToken returns the workspace and user a feed token was issued for, and
+// records that the feed was used
+func LookupCalendarFeedToken(db *sql.DB, token string) (string, string, error) {
+	var workspaceID, userID string
+	err := db.QueryRow(`
+		UPDATE calendar_feed_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1
+		RETURNING workspace_id::text, user_id::text
+	`, hashCalendarToken(token)).Scan(&workspaceID, &userID)
+	if err == sql.ErrNoRows {
+		return "", "", ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up calendar feed: %v", err)
+	}
+	return workspaceID, userID, nil
+}
+
+// calendarLink returns a link into the web app
+func calendarLink(path string) string {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3000" // fallback
+	}
+	return strings.TrimRight(frontendURL, "/") + path
+}
+
+// CalendarEvents collects a workspace's content calendar: its scheduled posts that are not
+// cancelled, and depending on opts its unscheduled drafts and its tasks. Events older than
+// calendarFeedPast are left out.
+func CalendarEvents(db *sql.DB, workspaceID string, opts CalendarFeedOptions) ([]models.CalendarEvent, error) {
+	since := time.Now().Add(-calendarFeedPast)
+	events := []models.CalendarEvent{}
+
+	rows, err := db.Query(`
+		SELECT id, content, platforms, scheduled_time, status, updated_at
+		FROM scheduled_posts
+		WHERE workspace_id = $1 AND scheduled_time >= $2 AND status <> $3
+		ORDER BY scheduled_time
+	`, workspaceID, since, models.StatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled posts: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var post models.ScheduledPost
+		if err := rows.Scan(&post.ID, &post.Content, &post.Platforms, &post.ScheduledTime, &post.Status, &post.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled post: %v", err)
+		}
+		link := calendarLink(fmt.Sprintf("/home/scheduled-posts?post=%d", post.ID))
+		events = append(events, models.CalendarEvent{
+			UID:         fmt.Sprintf("scheduled-post-%d@socialsync", post.ID),
+			Summary:     postSummary("", post.Platforms, post.Content),
+			Description: postDescription(post.Platforms, post.Status, post.Content, link),
+			URL:         link,
+			Start:       post.ScheduledTime,
+			End:         post.ScheduledTime.Add(calendarEventLength),
+			Categories:  post.Platforms,
+			Modified:    post.UpdatedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read scheduled posts: %v", err)
+	}
+
+	if opts.Drafts {
+		// Drafts carried by a scheduled post already appear as that post
+		rows, err := db.Query(`
+			SELECT id::text, COALESCE(content, ''), COALESCE(platforms, '{}'), scheduled_time, status, updated_at
+			FROM draft_posts
+			WHERE workspace_id = $1 AND scheduled_time IS NOT NULL AND scheduled_time >= $2
+			  AND status NOT IN ($3, $4, $5)
+			ORDER BY scheduled_time
+		`, workspaceID, since, models.DraftStatusScheduled, models.DraftStatusPublished, models.DraftStatusFailed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query drafts: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var draft models.DraftPost
+			var platforms []string
+			if err := rows.Scan(&draft.ID, &draft.Content, pq.Array(&platforms), &draft.ScheduledTime, &draft.Status, &draft.UpdatedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan draft: %v", err)
+			}
+			link := calendarLink("/home/workspace?workspace=" + workspaceID + "&draft=" + draft.ID)
+			events = append(events, models.CalendarEvent{
+				UID:         "draft-" + draft.ID + "@socialsync",
+				Summary:     postSummary("Draft: ", platforms, draft.Content),
+				Description: postDescription(platforms, "draft ("+draft.Status+")", draft.Content, link),
+				URL:         link,
+				Start:       *draft.ScheduledTime,
+				End:         draft.ScheduledTime.Add(calendarEventLength),
+				Categories:  platforms,
+				Modified:    draft.UpdatedAt,
+			})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read drafts: %v", err)
+		}
+	}
+
+	if opts.Tasks {
+		rows, err := db.Query(`
+			SELECT id::text, title, COALESCE(description, ''), COALESCE(status, ''), due_date, updated_at
+			FROM tasks
+			WHERE workspace_id = $1 AND due_date IS NOT NULL AND due_date >= $2
+			ORDER BY due_date
+		`, workspaceID, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tasks: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var task models.Task
+			if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.DueDate, &task.UpdatedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan task: %v", err)
+			}
+			link := calendarLink("/home/workspace?workspace=" + workspaceID + "&task=" + task.ID)
+			description := "Status: " + task.Status
+			if task.Description != "" {
+				description += "\n\n" + previewText(task.Description, calendarDescriptionPreview)
+			}
+			due := task.DueDate.UTC()
+			start := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+			events = append(events, models.CalendarEvent{
+				UID:         "task-" + task.ID + "@socialsync",
+				Summary:     "Task due: " + task.Title,
+				Description: description + "\n\n" + link,
+				URL:         link,
+				Start:       start,
+				End:         start.AddDate(0, 0, 1),
+				AllDay:      true,
+				Modified:    task.UpdatedAt,
+			})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read tasks: %v", err)
+		}
+	}
+	return events, nil
+}
+
+// postSummary is an event title: the platforms and the start of the content
+func postSummary(prefix string, platforms []string, content string) string {
+	summary := prefix
+	if len(platforms) > 0 {
+		summary += "[" + strings.Join(platforms, ", ") + "] "
+	}
+	if content == "" {
+		return summary + "(no text)"
+	}
+	return summary + previewText(content, calendarSummaryPreview)
+}
+
+// postDescription is an event body: platforms, status, a content preview and the link
+func postDescription(platforms []string, status, content, link string) string {
+	var b strings.Builder
+	b.WriteString("Platforms: " + strings.Join(platforms, ", ") + "\n")
+	b.WriteString("Status: " + status + "\n")
+	if content != "" {
+		b.WriteString("\n" + previewText(content, calendarDescriptionPreview) + "\n")
+	}
+	b.WriteString("\n" + link)
+	return b.String()
+}
+
+// previewText shortens s to at most max runes, on one line when it is a title
+func previewText(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if max <= calendarSummaryPreview {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// RenderCalendar writes events as an iCalendar (RFC 5545) document
+func RenderCalendar(name string, events []models.CalendarEvent) []byte {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//SocialSync//Content Calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(name))
+	// Ask subscribers to refresh often so rescheduled posts move soon
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT15M")
+	line("X-PUBLISHED-TTL:PT15M")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+		} else {
+			line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		}
+		if !e.Modified.IsZero() {
+			line("LAST-MODIFIED:" + e.Modified.UTC().Format("20060102T150405Z"))
+			// Calendars that cache events take a higher sequence as a newer version
+			line(fmt.Sprintf("SEQUENCE:%d", e.Modified.Unix()))
+		}
+		line("SUMMARY:" + escapeICalText(e.Summary))
+		line("DESCRIPTION:" + escapeICalText(e.Description))
+		if e.URL != "" {
+			line("URL:" + e.URL)
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				escaped[i] = escapeICalText(c)
+			}
+			line("CATEGORIES:" + strings.Join(escaped, ","))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// escapeICalText escapes a TEXT property value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// foldICalLine splits a content line into 75-octet pieces without breaking a UTF-8 sequence
+func foldICalLine(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(s)
+	return b.String()
+}
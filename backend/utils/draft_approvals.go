@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social-sync-backend/models"
+)
+
+var (
+	// ErrInvalidApprovalTransition is returned when the draft's status doesn't allow the action
+	ErrInvalidApprovalTransition = errors.New("this action is not allowed in the draft's current status")
+	// ErrNotApprover is returned when the user is not one of the draft's required approvers
+	ErrNotApprover = errors.New("you are not an approver for this workspace")
+	// ErrAlreadyApproved is returned when the user already approved the current review round
+	ErrAlreadyApproved = errors.New("you have already approved this draft")
+	// ErrDraftNotApproved is returned when an unapproved draft is published in a workspace with approvals
+	ErrDraftNotApproved = errors.New("draft must be approved before it can be published or scheduled")
+	// ErrApprovalsDisabled is returned for review actions in a workspace without approvals
+	ErrApprovalsDisabled = errors.New("approvals are not enabled for this workspace")
+)
+
+// ApprovalsEnabled reports whether drafts of the workspace need approval before publishing
+func ApprovalsEnabled(q sqlQueryer, workspaceID string) bool {
+	var enabled bool
+	q.QueryRow(`SELECT COALESCE(approvals_enabled, FALSE) FROM workspaces WHERE id = $1`, workspaceID).Scan(&enabled)
+	return enabled
+}
+
+// listApprovers returns the workspace's required approvers in creation order
+func listApprovers(q sqlQueryer, workspaceID string) ([]models.WorkspaceApprover, error) {
+	rows, err := q.Query(`
+		SELECT id, workspace_id::text, user_id::text, role, created_at
+		FROM workspace_approvers
+		WHERE workspace_id = $1
+		ORDER BY id
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approvers: %v", err)
+	}
+	defer rows.Close()
+
+	approvers := []models.WorkspaceApprover{}
+	for rows.Next() {
+		var a models.WorkspaceApprover
+		if err := rows.Scan(&a.ID, &a.WorkspaceID, &a.UserID, &a.Role, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approver: %v", err)
+		}
+		approvers = append(approvers, a)
+	}
+	return approvers, rows.Err()
+}
+
+// GetApprovalSettings returns whether approvals are enabled and who must approve
+func GetApprovalSettings(db *sql.DB, workspaceID string) (*models.ApprovalSettings, error) {
+	approvers, err := listApprovers(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApprovalSettings{
+		WorkspaceID: workspaceID,
+		Enabled:     ApprovalsEnabled(db, workspaceID),
+		Approvers:   approvers,
+	}, nil
+}
+
+// UpdateApprovalSettings replaces the workspace's approval flag and approver list
+func UpdateApprovalSettings(db *sql.DB, workspaceID string, req models.UpdateApprovalSettingsRequest) (*models.ApprovalSettings, error) {
+	for _, a := range req.Approvers {
+		if (a.UserID == nil) == (a.Role == nil) {
+			return nil, fmt.Errorf("each approver needs exactly one of user_id or role")
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE workspaces SET approvals_enabled = $1 WHERE id = $2`, req.Enabled, workspaceID); err != nil {
+		return nil, fmt.Errorf("failed to update workspace: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM workspace_approvers WHERE workspace_id = $1`, workspaceID); err != nil {
+		return nil, fmt.Errorf("failed to clear approvers: %v", err)
+	}
+	for _, a := range req.Approvers {
+		if a.UserID != nil {
+			var member bool
+			tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2)`,
+				workspaceID, *a.UserID).Scan(&member)
+			if !member {
+				return nil, fmt.Errorf("approver %s is not a member of this workspace", *a.UserID)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO workspace_approvers (workspace_id, user_id, role) VALUES ($1, $2, $3)`,
+			workspaceID, a.UserID, a.Role); err != nil {
+			return nil, fmt.Errorf("failed to add approver: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit approval settings: %v", err)
+	}
+	return GetApprovalSettings(db, workspaceID)
+}
+
+// memberRole returns the user's role in the workspace, or "" when not a member
+func memberRole(q sqlQueryer, workspaceID, userID string) string {
+	var role string
+	q.QueryRow(`SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, workspaceID, userID).Scan(&role)
+	return role
+}
+
+// approverMatches reports whether a user with role fills the approver requirement
+func approverMatches(a models.WorkspaceApprover, userID, role string) bool {
+	if a.UserID != nil {
+		return *a.UserID == userID
+	}
+	return a.Role != nil && role != "" && *a.Role == role
+}
+
+// roundApprovals lists who approved since the draft was last submitted, oldest first
+func roundApprovals(q sqlQueryer, draftID string) ([]string, error) {
+	rows, err := q.Query(`
+		SELECT user_id::text
+		FROM draft_approval_log
+		WHERE draft_id = $1 AND action = $2 AND user_id IS NOT NULL
+		  AND id > COALESCE((SELECT MAX(id) FROM draft_approval_log WHERE draft_id = $1 AND action = $3), 0)
+		ORDER BY id
+	`, draftID, models.ApprovalActionApprove, models.ApprovalActionSubmit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approvals: %v", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %v", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// evaluateRequirements assigns each approval of the round to at most one unmet requirement,
+// user-specific requirements first, so two "content_manager" rows need two different people
+func evaluateRequirements(q sqlQueryer, workspaceID string, approvers []models.WorkspaceApprover, approvals []string) []models.ApprovalRequirement {
+	reqs := make([]models.ApprovalRequirement, len(approvers))
+	for i, a := range approvers {
+		reqs[i] = models.ApprovalRequirement{Approver: a}
+	}
+	for _, userID := range approvals {
+		role := memberRole(q, workspaceID, userID)
+		assigned := false
+		for pass := 0; pass < 2 && !assigned; pass++ {
+			for i := range reqs {
+				byUser := reqs[i].Approver.UserID != nil
+				if reqs[i].Satisfied || byUser != (pass == 0) || !approverMatches(reqs[i].Approver, userID, role) {
+					continue
+				}
+				approvedBy := userID
+				reqs[i].Satisfied = true
+				reqs[i].ApprovedBy = &approvedBy
+				assigned = true
+				break
+			}
+		}
+	}
+	return reqs
+}
+
+// GetDraftApprovalStatus returns a draft's approval requirements for the current round and its log
+func GetDraftApprovalStatus(db *sql.DB, draftID, workspaceID, status string) (*models.DraftApprovalStatus, error) {
+	approvers, err := listApprovers(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := roundApprovals(db, draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT l.id, l.draft_id::text, COALESCE(l.user_id::text, ''), u.name, l.action, l.comment,
+		       l.from_status, l.to_status, l.created_at
+		FROM draft_approval_log l
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE l.draft_id = $1
+		ORDER BY l.id
+	`, draftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.DraftApprovalEntry{}
+	for rows.Next() {
+		var e models.DraftApprovalEntry
+		if err := rows.Scan(&e.ID, &e.DraftID, &e.UserID, &e.UserName, &e.Action, &e.Comment,
+			&e.FromStatus, &e.ToStatus, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval log: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate approval log: %v", err)
+	}
+
+	return &models.DraftApprovalStatus{
+		DraftID:      draftID,
+		Status:       status,
+		Enabled:      ApprovalsEnabled(db, workspaceID),
+		Requirements: evaluateRequirements(db, workspaceID, approvers, approvals),
+		Log:          entries,
+	}, nil
+}
+
+// ReviewDraft applies one approval action to a draft inside a transaction and returns the new
+// status. fallbackReviewer says whether the user may review when the workspace has no
+// configured approvers (callers pass whether they hold post:publish).
+func ReviewDraft(db *sql.DB, draftID, userID, action, comment string, fallbackReviewer bool) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var workspaceID, status string
+	err = tx.QueryRow(`SELECT workspace_id::text, status FROM draft_posts WHERE id = $1 FOR UPDATE`, draftID).Scan(&workspaceID, &status)
+	if err != nil {
+		return "", err
+	}
+	if !ApprovalsEnabled(tx, workspaceID) {
+		return "", ErrApprovalsDisabled
+	}
+
+	approvers, err := listApprovers(tx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	role := memberRole(tx, workspaceID, userID)
+	isReviewer := len(approvers) == 0 && fallbackReviewer
+	for _, a := range approvers {
+		isReviewer = isReviewer || approverMatches(a, userID, role)
+	}
+
+	next := status
+	switch action {
+	case models.ApprovalActionSubmit:
+		if status != models.DraftStatusDraft && status != models.DraftStatusChangesRequested && status != models.DraftStatusFailed {
+			return "", ErrInvalidApprovalTransition
+		}
+		next = models.DraftStatusInReview
+
+	case models.ApprovalActionRequestChanges:
+		if status != models.DraftStatusInReview && status != models.DraftStatusApproved {
+			return "", ErrInvalidApprovalTransition
+		}
+		if !isReviewer {
+			return "", ErrNotApprover
+		}
+		if comment == "" {
+			return "", fmt.Errorf("a comment is required when requesting changes")
+		}
+		next = models.DraftStatusChangesRequested
+
+	case models.ApprovalActionApprove:
+		if status != models.DraftStatusInReview {
+			return "", ErrInvalidApprovalTransition
+		}
+		if !isReviewer {
+			return "", ErrNotApprover
+		}
+		approvals, err := roundApprovals(tx, draftID)
+		if err != nil {
+			return "", err
+		}
+		for _, u := range approvals {
+			if u == userID {
+				return "", ErrAlreadyApproved
+			}
+		}
+		approvals = append(approvals, userID)
+		done := true
+		for _, r := range evaluateRequirements(tx, workspaceID, approvers, approvals) {
+			done = done && r.Satisfied
+		}
+		if done {
+			next = models.DraftStatusApproved
+		}
+
+	default:
+		return "", fmt.Errorf("unknown approval action: %s", action)
+	}
+
+	if err := logApproval(tx, draftID, workspaceID, userID, action, comment, status, next); err != nil {
+		return "", err
+	}
+	if next != status {
+		if _, err := tx.Exec(`UPDATE draft_posts SET status = $1, updated_at = $2 WHERE id = $3`, next, time.Now(), draftID); err != nil {
+			return "", fmt.Errorf("failed to update draft status: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit review: %v", err)
+	}
+	return next, nil
+}
+
+// ResetDraftReview sends an in-review or approved draft back to draft after its content
+// changed, so the edited version has to be reviewed again. It returns the new status.
+func ResetDraftReview(db *sql.DB, draftID, workspaceID, userID, status string) (string, error) {
+	if status != models.DraftStatusInReview && status != models.DraftStatusApproved {
+		return status, nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE draft_posts SET status = $1 WHERE id = $2`, models.DraftStatusDraft, draftID); err != nil {
+		return "", fmt.Errorf("failed to reset draft status: %v", err)
+	}
+	if err := logApproval(tx, draftID, workspaceID, userID, models.ApprovalActionReset, "", status, models.DraftStatusDraft); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit review reset: %v", err)
+	}
+	return models.DraftStatusDraft, nil
+}
+
+func logApproval(tx *sql.Tx, draftID, workspaceID, userID, action, comment, from, to string) error {
+	var commentPtr *string
+	if comment != "" {
+		commentPtr = &comment
+	}
+	_, err := tx.Exec(`
+		INSERT INTO draft_approval_log (draft_id, workspace_id, user_id, action, comment, from_status, to_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, draftID, workspaceID, userID, action, commentPtr, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to record approval log: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrDraftInFlight is returned when a draft's scheduled post has not finished yet
+	ErrDraftInFlight = errors.New("draft is already scheduled for publishing")
+	// ErrDraftAlreadyPublished is returned when a draft whose post went out is published again
+	// without asking to republish it
+	ErrDraftAlreadyPublished = errors.New("draft was already published; set republish to publish it again")
+)
+
+// CheckDraftPublish applies the rules a draft must meet to be published and normalizes
+// req.Targets against its media
+func CheckDraftPublish(draft models.DraftPost, req *models.PublishDraftRequest) error {
+	if len(draft.Platforms) == 0 {
+		return fmt.Errorf("draft has no platforms selected")
+	}
+	hasYouTube := false
+	for _, platform := range draft.Platforms {
+		if !IsSupportedPlatform(platform) {
+			return fmt.Errorf("invalid platform: %s", platform)
+		}
+		hasYouTube = hasYouTube || platform == "youtube"
+	}
+	if draft.Content == "" && !hasYouTube {
+		return fmt.Errorf("draft content is empty")
+	}
+	targets, err := NormalizeTargets(req.Targets, draft.Media)
+	if err != nil {
+		return fmt.Errorf("invalid targets: %v", err)
+	}
+	req.Targets = targets
+	return nil
+}
+
+// PublishDraft turns a draft into a scheduled post of its workspace, due at the draft's
+// scheduled_time or immediately when that is unset, past, or req.PublishNow is set. The
+// draft is checked against each platform's rules first; a failing report is returned with
+// no change made, a passing one along with the post. The processor then delivers it like
+// any other scheduled post and SyncDraftWithPost reports the outcome back onto the draft.
+// The draft and req are checked by the caller with CheckDraftPublish.
+func PublishDraft(db *sql.DB, userID string, draft models.DraftPost, req models.PublishDraftRequest) (*models.ScheduledPost, *models.ValidationResult, error) {
+	report := ValidateScheduledPost(db, ValidationInput{
+		Content:   draft.Content,
+		MediaURLs: draft.Media,
+		Platforms: draft.Platforms,
+		Targets:   req.Targets,
+	})
+	if !report.Valid {
+		return nil, &report, fmt.Errorf("draft does not meet the rules of every target platform")
+	}
+
+	now := time.Now()
+	when := now
+	if !req.PublishNow && draft.ScheduledTime != nil && draft.ScheduledTime.After(now) {
+		when = *draft.ScheduledTime
+	}
+	zone, _, err := ResolveTimeZone(db, userID, &draft.WorkspaceID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	targetsJSON, err := json.Marshal(req.Targets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid targets: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the draft so two publishers can't create two deliveries
+	var draftStatus string
+	var linkedStatus *string
+	err = tx.QueryRow(`
+		SELECT d.status, sp.status
+		FROM draft_posts d
+		LEFT JOIN scheduled_posts sp ON sp.id = d.scheduled_post_id
+		WHERE d.id = $1
+		FOR UPDATE OF d
+	`, draft.ID).Scan(&draftStatus, &linkedStatus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock draft: %v", err)
+	}
+	if linkedStatus != nil && (*linkedStatus == models.StatusPending || *linkedStatus == models.StatusProcessing) {
+		return nil, nil, ErrDraftInFlight
+	}
+	// With approvals enabled only approved drafts (or approved ones whose delivery failed) go
+	// out; without, a draft that was already posted only goes out again when asked to
+	if ApprovalsEnabled(tx, draft.WorkspaceID) {
+		if draftStatus != models.DraftStatusApproved && draftStatus != models.DraftStatusFailed {
+			return nil, nil, ErrDraftNotApproved
+		}
+	} else if linkedStatus != nil && *linkedStatus == models.StatusPosted && !req.Republish {
+		return nil, nil, ErrDraftAlreadyPublished
+	}
+
+	post := &models.ScheduledPost{
+		UserID:        userID,
+		WorkspaceID:   &draft.WorkspaceID,
+		Content:       draft.Content,
+		MediaURLs:     pq.StringArray(draft.Media),
+		Platforms:     pq.StringArray(draft.Platforms),
+		ScheduledTime: when,
+		Status:        models.StatusPending,
+		Targets:       req.Targets,
+		TimeZone:      &zone,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO scheduled_posts
+			(user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, timezone, workspace_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`, userID, post.Content, post.MediaURLs, post.Platforms, post.ScheduledTime, post.Status, now,
+		targetsJSON, zone, draft.WorkspaceID).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create scheduled post: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE draft_posts
+		SET status = $1, scheduled_post_id = $2, updated_at = $3, last_updated_by = $4
+		WHERE id = $5
+	`, models.DraftStatusScheduled, post.ID, now, userID, draft.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to link draft: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit draft publish: %v", err)
+	}
+	LocalizeScheduledPost(post)
+	return post, &report, nil
+}
+
+// SyncDraftWithPost copies the outcome of a scheduled post onto the draft it was published
+// from: posted drafts become published and failed ones failed. Cancelled ones go back to
+// approved when the workspace uses approvals (the content was already reviewed), else to draft;
+// so do unpublished ones.
+func SyncDraftWithPost(db *sql.DB, postID int) {
+	_, err := db.Exec(`
+		UPDATE draft_posts d
+		SET status = CASE
+		        WHEN sp.status = $2 THEN $3
+		        WHEN sp.status = $4 THEN $5
+		        WHEN sp.status IN ($6, $9) AND COALESCE(w.approvals_enabled, FALSE) THEN $8
+		        WHEN sp.status IN ($6, $9) THEN $7
+		        ELSE d.status END,
+		    published_time = CASE WHEN sp.status = $2 THEN sp.updated_at ELSE d.published_time END,
+		    updated_at = NOW()
+		FROM scheduled_posts sp, workspaces w
+		WHERE d.scheduled_post_id = sp.id AND w.id = d.workspace_id AND sp.id = $1
+	`, postID,
+		models.StatusPosted, models.DraftStatusPublished,
+		models.StatusFailed, models.DraftStatusFailed,
+		models.StatusCancelled, models.DraftStatusDraft, models.DraftStatusApproved,
+		models.StatusUnpublished)
+	if err != nil {
+		log.Printf("Failed to sync draft of scheduled post %d: %v", postID, err)
+	}
+}
@@ -23,6 +23,38 @@ func SendVerificationEmail(toEmail, token string) error {
 	msg := []byte(from + subject + "\r\n" + body)
 
 
+	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, sender, []string{toEmail}, msg)
+	if err != nil {
+		log.Printf("Error sending email to %s: %v", toEmail, err)
+		return err
+	}
+	return nil
+}
+
+// SendMissedPostEmail tells the author of a scheduled post that it was too late to publish.
+// It does nothing when SMTP isn't configured.
+func SendMissedPostEmail(toEmail, content, scheduledAt string) error {
+	smtpHost := os.Getenv("SMTP_HOST")
+	if smtpHost == "" {
+		return nil
+	}
+	smtpPort := os.Getenv("SMTP_PORT")
+	smtpUser := os.Getenv("SMTP_USERNAME")
+	smtpPass := os.Getenv("SMTP_PASSWORD")
+	sender := os.Getenv("EMAIL_SENDER")
+
+	auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
+
+	preview := []rune(content)
+	if len(preview) > 140 {
+		preview = append(preview[:140], '…')
+	}
+	subject := "Subject: SocialSync: a scheduled post missed its time\r\n"
+	from := fmt.Sprintf("From: SocialSync <%s>\r\n", sender)
+	body := fmt.Sprintf("Your post scheduled for %s could not be published on time and was not sent:\r\n\r\n%s\r\n\r\n"+
+		"Open SocialSync to publish it now, reschedule it or discard it.\r\n", scheduledAt, string(preview))
+	msg := []byte(from + subject + "\r\n" + body)
+
 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, sender, []string{toEmail}, msg)
 	if err != nil {
 		log.Printf("Error sending email to %s: %v", toEmail, err)
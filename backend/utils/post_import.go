@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// MaxImportRows caps the number of posts one CSV import may create
+const MaxImportRows = 500
+
+// importColumns maps the accepted CSV headers to the field they fill
+var importColumns = map[string]string{
+	"content":        "content",
+	"text":           "content",
+	"scheduled_time": "scheduled_time",
+	"time":           "scheduled_time",
+	"timezone":       "timezone",
+	"time_zone":      "timezone",
+	"platforms":      "platforms",
+	"platform":       "platforms",
+	"account_ids":    "account_ids",
+	"accounts":       "account_ids",
+	"media":          "media",
+	"media_urls":     "media",
+	"media_ids":      "media",
+}
+
+// ParseImportCSV reads a scheduled post import. The header names the columns (content,
+// scheduled_time and platforms are required; timezone, account_ids and media are optional;
+// others are ignored). List cells are separated by ";", "," or "|". scheduled_time is either
+// RFC 3339 or a wall-clock time in the row's timezone, else timeZone. media holds URLs or
+// IDs from the workspace's media library. Rows are returned with the post they describe and
+// any problem reading them; the post itself is not validated here.
+func ParseImportCSV(db *sql.DB, userID, workspaceID, timeZone string, r io.Reader) ([]models.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := importColumns[name]; ok {
+			if _, dup := columns[field]; dup {
+				return nil, fmt.Errorf("column %q appears more than once", field)
+			}
+			columns[field] = i
+		}
+	}
+	for _, required := range []string{"content", "scheduled_time", "platforms"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column %q", required)
+		}
+	}
+
+	rows := []models.ImportRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		cell := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("an import can create at most %d posts", MaxImportRows)
+		}
+
+		row := models.ImportRow{Line: line, Errors: []string{}}
+		req, errs := importRequest(db, userID, workspaceID, timeZone, cell)
+		row.Post = req
+		row.Errors = append(row.Errors, errs...)
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the CSV has no posts")
+	}
+	return rows, nil
+}
+
+// importRequest builds the create request of one CSV row
+func importRequest(db *sql.DB, userID, workspaceID, timeZone string, cell func(string) string) (*models.CreateScheduledPostRequest, []string) {
+	var errs []string
+	req := &models.CreateScheduledPostRequest{
+		Content:     cell("content"),
+		Platforms:   []string{},
+		MediaURLs:   []string{},
+		WorkspaceID: &workspaceID,
+		TimeZone:    timeZone,
+	}
+	if zone := cell("timezone"); zone != "" {
+		req.TimeZone = zone
+	}
+
+	switch when := cell("scheduled_time"); {
+	case when == "":
+		errs = append(errs, "scheduled_time is required")
+	default:
+		if t, err := time.Parse(time.RFC3339, when); err == nil {
+			req.ScheduledTime = t
+		} else {
+			req.LocalTime = when
+		}
+	}
+
+	for _, platform := range splitImportList(cell("platforms")) {
+		platform = strings.ToLower(platform)
+		if !containsString(req.Platforms, platform) {
+			req.Platforms = append(req.Platforms, platform)
+		}
+	}
+
+	if ids := splitImportList(cell("account_ids")); len(ids) > 0 {
+		targets, err := importTargets(db, userID, req.Platforms, ids)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.Targets = targets
+	}
+
+	if media := splitImportList(cell("media")); len(media) > 0 {
+		urls, err := importMedia(db, workspaceID, media)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.MediaURLs = urls
+	}
+	return req, errs
+}
+
+// importTargets turns a row's account IDs into per-platform account targets. Each account
+// must belong to the importing user and to one of the row's platforms.
+func importTargets(db *sql.DB, userID string, platforms, accountIDs []string) (map[string]interface{}, error) {
+	rows, err := db.Query(`
+		SELECT id::text, COALESCE(platform, provider, '')
+		FROM social_accounts
+		WHERE user_id = $1 AND id::text = ANY($2)
+	`, userID, pq.Array(accountIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up accounts: %v", err)
+	}
+	defer rows.Close()
+	accountPlatform := map[string]string{}
+	for rows.Next() {
+		var id, platform string
+		if err := rows.Scan(&id, &platform); err != nil {
+			return nil, fmt.Errorf("failed to look up accounts: %v", err)
+		}
+		accountPlatform[id] = platform
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up accounts: %v", err)
+	}
+
+	ids := map[string][]string{}
+	for _, id := range accountIDs {
+		platform, ok := accountPlatform[id]
+		if !ok {
+			return nil, fmt.Errorf("account %s is not one of your connected accounts", id)
+		}
+		if !containsString(platforms, platform) {
+			return nil, fmt.Errorf("account %s is a %s account, but %s is not one of the row's platforms", id, platform, platform)
+		}
+		ids[platform] = append(ids[platform], id)
+	}
+	targets := make(map[string]interface{}, len(ids))
+	for platform, list := range ids {
+		targets[platform] = map[string]interface{}{"ids": list}
+	}
+	return targets, nil
+}
+
+// importMedia resolves a row's media cells to URLs: http(s) URLs are kept, anything else is
+// looked up as an ID in the workspace's media library
+func importMedia(db *sql.DB, workspaceID string, media []string) ([]string, error) {
+	var ids []string
+	for _, m := range media {
+		if u, err := url.Parse(m); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			ids = append(ids, m)
+		}
+	}
+	libraryURLs := map[string]string{}
+	if len(ids) > 0 {
+		rows, err := db.Query(`
+			SELECT id::text, file_url FROM media WHERE workspace_id = $1 AND id::text = ANY($2)
+		`, workspaceID, pq.Array(ids))
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up media: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, fileURL string
+			if err := rows.Scan(&id, &fileURL); err != nil {
+				return nil, fmt.Errorf("failed to look up media: %v", err)
+			}
+			libraryURLs[id] = fileURL
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to look up media: %v", err)
+		}
+	}
+
+	urls := make([]string, 0, len(media))
+	for _, m := range media {
+		if fileURL, ok := libraryURLs[m]; ok {
+			urls = append(urls, fileURL)
+		} else if containsString(ids, m) {
+			return nil, fmt.Errorf("media %q is neither a URL nor an ID in the workspace's media library", m)
+		} else {
+			urls = append(urls, m)
+		}
+	}
+	return urls, nil
+}
+
+// splitImportList splits a list cell on ";", "," or "|"
+func splitImportList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' || r == '|' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ImportScheduledPosts stores the posts of a validated import in one transaction: either
+// every post is created or none is
+func ImportScheduledPosts(db *sql.DB, userID string, reqs []models.CreateScheduledPostRequest) ([]models.ScheduledPost, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	posts := make([]models.ScheduledPost, 0, len(reqs))
+	for i, req := range reqs {
+		targetsJSON, err := json.Marshal(req.Targets)
+		if err != nil {
+			return nil, fmt.Errorf("post %d: invalid targets: %v", i+1, err)
+		}
+		post := models.ScheduledPost{
+			UserID:        userID,
+			Content:       req.Content,
+			MediaURLs:     pq.StringArray(req.MediaURLs),
+			Platforms:     pq.StringArray(req.Platforms),
+			ScheduledTime: req.ScheduledTime,
+			Status:        models.StatusPending,
+			Targets:       req.Targets,
+			WorkspaceID:   req.WorkspaceID,
+		}
+		if req.TimeZone != "" {
+			post.TimeZone = &req.TimeZone
+		}
+		err = tx.QueryRow(`
+			INSERT INTO scheduled_posts (user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, timezone, workspace_id)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), $7, $8, $9)
+			RETURNING id, created_at, updated_at
+		`, userID, post.Content, post.MediaURLs, post.Platforms, post.ScheduledTime, post.Status,
+			targetsJSON, post.TimeZone, post.WorkspaceID).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("post %d: failed to create scheduled post: %v", i+1, err)
+		}
+		LocalizeScheduledPost(&post)
+		post.Variants = PostVariants(post)
+		posts = append(posts, post)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %v", err)
+	}
+	return posts, nil
+}
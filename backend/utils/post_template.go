@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// templatePlaceholder matches {{name}}, allowing spaces inside the braces
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// templateVariableName is the form a variable name must take
+var templateVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var (
+	// ErrTemplateNotFound is returned when a template doesn't exist in the workspace
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateNameTaken is returned when the workspace already has a template of that name
+	ErrTemplateNameTaken = errors.New("a template with this name already exists in the workspace")
+)
+
+// MissingVariablesError is returned when a template is rendered without a value for some of
+// its placeholders
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "missing template variables: " + strings.Join(e.Names, ", ")
+}
+
+// TemplatePlaceholders lists the variable names a template uses, in order of first appearance
+func TemplatePlaceholders(tpl models.PostTemplate) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	collect := func(s string) {
+		for _, m := range templatePlaceholder.FindAllStringSubmatch(s, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	collect(tpl.Content)
+	for _, tag := range tpl.Hashtags {
+		collect(tag)
+	}
+	walkTemplateStrings(tpl.Targets, func(s string) string {
+		collect(s)
+		return s
+	})
+	return names
+}
+
+// RenderPostTemplate fills in the placeholders of a template's content and targets, then
+// appends its default hashtags to the content. Values in vars override the template's
+// defaults; any placeholder left without a value fails with a *MissingVariablesError.
+func RenderPostTemplate(tpl models.PostTemplate, vars map[string]string) (string, map[string]interface{}, error) {
+	values := make(map[string]string, len(tpl.Variables)+len(vars))
+	for name, value := range tpl.Variables {
+		values[name] = value
+	}
+	for name, value := range vars {
+		values[strings.TrimSpace(name)] = value
+	}
+
+	var missing []string
+	for _, name := range TemplatePlaceholders(tpl) {
+		if strings.TrimSpace(values[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", nil, &MissingVariablesError{Names: missing}
+	}
+
+	render := func(s string) string {
+		return templatePlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+			return values[templatePlaceholder.FindStringSubmatch(m)[1]]
+		})
+	}
+
+	content := render(tpl.Content)
+	hashtags := make([]string, 0, len(tpl.Hashtags))
+	for _, tag := range tpl.Hashtags {
+		hashtags = append(hashtags, render(tag))
+	}
+	content = appendHashtags(content, hashtags)
+
+	// Targets are copied through JSON so the template itself is left untouched
+	var targets map[string]interface{}
+	if len(tpl.Targets) > 0 {
+		raw, err := json.Marshal(tpl.Targets)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid template targets: %v", err)
+		}
+		if err := json.Unmarshal(raw, &targets); err != nil {
+			return "", nil, fmt.Errorf("invalid template targets: %v", err)
+		}
+		walkTemplateStrings(targets, render)
+	}
+	return content, targets, nil
+}
+
+// walkTemplateStrings replaces every string inside v (maps and slices included) with fn's result
+func walkTemplateStrings(v interface{}, fn func(string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return fn(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = walkTemplateStrings(item, fn)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = walkTemplateStrings(item, fn)
+		}
+	}
+	return v
+}
+
+// appendHashtags adds the hashtags not already in content on a line of their own
+func appendHashtags(content string, hashtags []string) string {
+	present := map[string]bool{}
+	for _, word := range strings.Fields(strings.ToLower(content)) {
+		present[strings.TrimRight(word, ".,;:!?")] = true
+	}
+
+	var add []string
+	for _, tag := range hashtags {
+		tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+		if tag == "" {
+			continue
+		}
+		tag = "#" + strings.Join(strings.Fields(tag), "")
+		if present[strings.ToLower(tag)] {
+			continue
+		}
+		present[strings.ToLower(tag)] = true
+		add = append(add, tag)
+	}
+	if len(add) == 0 {
+		return content
+	}
+	if strings.TrimSpace(content) == "" {
+		return strings.Join(add, " ")
+	}
+	return strings.TrimRight(content, " \n") + "\n\n" + strings.Join(add, " ")
+}
+
+// CheckPostTemplate applies the rules every stored template must meet
+func CheckPostTemplate(tpl models.PostTemplate) error {
+	if strings.TrimSpace(tpl.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, platform := range tpl.Platforms {
+		if !IsSupportedPlatform(platform) {
+			return fmt.Errorf("invalid platform: %s", platform)
+		}
+	}
+	for name := range tpl.Variables {
+		if !templateVariableName.MatchString(name) {
+			return fmt.Errorf("invalid variable name: %q", name)
+		}
+	}
+	return nil
+}
+
+const postTemplateColumns = `id::text, workspace_id::text, name, content, COALESCE(media_urls, '{}'), COALESCE(platforms, '{}'),
+	COALESCE(targets, '{}'::jsonb), COALESCE(hashtags, '{}'), COALESCE(variables, '{}'::jsonb), created_by::text, created_at, updated_at`
+
+func scanPostTemplate(row interface{ Scan(...interface{}) error }) (*models.PostTemplate, error) {
+	var tpl models.PostTemplate
+	var targetsJSON, variablesJSON []byte
+	err := row.Scan(&tpl.ID, &tpl.WorkspaceID, &tpl.Name, &tpl.Content, pq.Array(&tpl.MediaURLs), pq.Array(&tpl.Platforms),
+		&targetsJSON, pq.Array(&tpl.Hashtags), &variablesJSON, &tpl.CreatedBy, &tpl.CreatedAt, &tpl.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(targetsJSON, &tpl.Targets); err != nil {
+		return nil, fmt.Errorf("invalid template targets: %v", err)
+	}
+	if err := json.Unmarshal(variablesJSON, &tpl.Variables); err != nil {
+		return nil, fmt.Errorf("invalid template variables: %v", err)
+	}
+	tpl.Placeholders = TemplatePlaceholders(tpl)
+	return &tpl, nil
+}
+
+// templateWriteError maps a failed insert or update to the template errors
+func templateWriteError(err error, action string) error {
+	if err == sql.ErrNoRows {
+		return ErrTemplateNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrTemplateNameTaken
+	}
+	return fmt.Errorf("failed to %s template: %v", action, err)
+}
+
+// templateJSON encodes the JSONB columns of a template
+func templateJSON(tpl models.PostTemplate) ([]byte, []byte, error) {
+	targets := tpl.Targets
+	if targets == nil {
+		targets = map[string]interface{}{}
+	}
+	variables := tpl.Variables
+	if variables == nil {
+		variables = map[string]string{}
+	}
+	targetsJSON, err := json.Marshal(targets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid variables: %v", err)
+	}
+	return targetsJSON, variablesJSON, nil
+}
+
+// CreatePostTemplate stores a new template; it is checked by the caller
+func CreatePostTemplate(db *sql.DB, tpl models.PostTemplate) (*models.PostTemplate, error) {
+	targetsJSON, variablesJSON, err := templateJSON(tpl)
+	if err != nil {
+		return nil, err
+	}
+	created, err := scanPostTemplate(db.QueryRow(`
+		INSERT INTO post_templates (workspace_id, name, content, media_urls, platforms, targets, hashtags, variables, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::uuid)
+		RETURNING `+postTemplateColumns,
+		tpl.WorkspaceID, strings.TrimSpace(tpl.Name), tpl.Content, pq.Array(tpl.MediaURLs), pq.Array(tpl.Platforms),
+		targetsJSON, pq.Array(tpl.Hashtags), variablesJSON, tpl.CreatedBy))
+	if err != nil {
+		return nil, templateWriteError(err, "create")
+	}
+	return created, nil
+}
+
+// UpdatePostTemplate saves every field of an existing template; it is checked by the caller
+func UpdatePostTemplate(db *sql.DB, tpl models.PostTemplate) (*models.PostTemplate, error) {
+	targetsJSON, variablesJSON, err := templateJSON(tpl)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := scanPostTemplate(db.QueryRow(`
+		UPDATE post_templates
+		SET name = $1, content = $2, media_urls = $3, platforms = $4, targets = $5, hashtags = $6, variables = $7, updated_at = NOW()
+		WHERE id::text = $8 AND workspace_id = $9
+		RETURNING `+postTemplateColumns,
+		strings.TrimSpace(tpl.Name), tpl.Content, pq.Array(tpl.MediaURLs), pq.Array(tpl.Platforms),
+		targetsJSON, pq.Array(tpl.Hashtags), variablesJSON, tpl.ID, tpl.WorkspaceID))
+	if err != nil {
+		return nil, templateWriteError(err, "update")
+	}
+	return updated, nil
+}
+
+// GetPostTemplate returns one template of a workspace
+func GetPostTemplate(db *sql.DB, workspaceID, templateID string) (*models.PostTemplate, error) {
+	tpl, err := scanPostTemplate(db.QueryRow(`SELECT `+postTemplateColumns+`
+		FROM post_templates WHERE id::text = $1 AND workspace_id = $2`, templateID, workspaceID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template: %v", err)
+	}
+	return tpl, nil
+}
+
+// ListPostTemplates returns the templates of a workspace by name
+func ListPostTemplates(db *sql.DB, workspaceID string) ([]models.PostTemplate, error) {
+	rows, err := db.Query(`SELECT `+postTemplateColumns+`
+		FROM post_templates WHERE workspace_id = $1 ORDER BY LOWER(name), id`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %v", err)
+	}
+	defer rows.Close()
+
+	templates := []models.PostTemplate{}
+	for rows.Next() {
+		tpl, err := scanPostTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %v", err)
+		}
+		templates = append(templates, *tpl)
+	}
+	return templates, rows.Err()
+}
+
+// DeletePostTemplate removes a template; posts already created from it are unaffected
+func DeletePostTemplate(db *sql.DB, workspaceID, templateID string) error {
+	res, err := db.Exec(`DELETE FROM post_templates WHERE id::text = $1 AND workspace_id = $2`, templateID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+
+	"social-sync-backend/models"
+)
+
+// ParseTargets decodes ScheduledPost.Targets into typed per-platform targets. Unknown fields
+// are ignored so posts saved before variants existed keep publishing.
+func ParseTargets(targets map[string]interface{}) (map[string]models.PostTarget, error) {
+	parsed := make(map[string]models.PostTarget, len(targets))
+	for platform, raw := range targets {
+		target, err := parseTarget(raw, false)
+		if err != nil {
+			return parsed, fmt.Errorf("targets.%s: %v", platform, err)
+		}
+		parsed[platform] = target
+	}
+	return parsed, nil
+}
+
+// NormalizeTargets validates the targets of a post against its media and returns them in
+// canonical form, ready to be stored
+func NormalizeTargets(targets map[string]interface{}, mediaURLs []string) (map[string]interface{}, error) {
+	if len(targets) == 0 {
+		return targets, nil
+	}
+	normalized := make(map[string]interface{}, len(targets))
+	for platform, raw := range targets {
+		target, err := parseTarget(raw, true)
+		if err == nil {
+			err = checkTarget(platform, target, mediaURLs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("targets.%s: %v", platform, err)
+		}
+
+		b, err := json.Marshal(target)
+		if err != nil {
+			return nil, fmt.Errorf("targets.%s: %v", platform, err)
+		}
+		var canonical map[string]interface{}
+		if err := json.Unmarshal(b, &canonical); err != nil {
+			return nil, fmt.Errorf("targets.%s: %v", platform, err)
+		}
+		normalized[platform] = canonical
+	}
+	return normalized, nil
+}
+
+// parseTarget decodes one platform target; strict decoding rejects unknown fields
+func parseTarget(raw interface{}, strict bool) (models.PostTarget, error) {
+	var target models.PostTarget
+	if raw == nil {
+		return target, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return target, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&target); err != nil {
+		return target, fmt.Errorf("invalid target: %v", err)
+	}
+	return target, nil
+}
+
+// checkTarget validates the structure of a platform target and its overrides
+func checkTarget(platform string, target models.PostTarget, mediaURLs []string) error {
+	if !IsSupportedPlatform(platform) {
+		return fmt.Errorf("unknown platform %q", platform)
+	}
+	if err := checkVariant(target.ContentVariant, mediaURLs); err != nil {
+		return err
+	}
+	for accountID, variant := range target.Accounts {
+		if accountID == "" {
+			return fmt.Errorf("account overrides need an account ID")
+		}
+		if !target.All && len(target.IDs) > 0 && !containsString(target.IDs, accountID) {
+			return fmt.Errorf("accounts.%s: account is not one of the selected ids", accountID)
+		}
+		if err := checkVariant(variant, mediaURLs); err != nil {
+			return fmt.Errorf("accounts.%s: %v", accountID, err)
+		}
+	}
+	return nil
+}
+
+// checkVariant makes sure a variant only picks media from the post and links are absolute URLs
+func checkVariant(variant models.ContentVariant, mediaURLs []string) error {
+	if variant.MediaURLs != nil {
+		for _, u := range *variant.MediaURLs {
+			if !containsString(mediaURLs, u) {
+				return fmt.Errorf("media_urls: %s is not one of the post's media", u)
+			}
+		}
+	}
+	if variant.Link != nil && *variant.Link != "" {
+		if u, err := url.Parse(*variant.Link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("link must be an http(s) URL")
+		}
+	}
+	if variant.Hashtags != nil {
+		for _, tag := range *variant.Hashtags {
+			if t := strings.TrimPrefix(strings.TrimSpace(tag), "#"); t == "" || strings.ContainsAny(t, " \t\n#") {
+				return fmt.Errorf("hashtags: %q is not a single hashtag", tag)
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveVariant applies the platform override and then the account override (when accountID is
+// set) on top of the post's content and media
+func ResolveVariant(content string, mediaURLs []string, target models.PostTarget, platform string, accountID *string) models.PostVariant {
+	merged := target.ContentVariant
+	if accountID != nil {
+		if override, ok := target.Accounts[*accountID]; ok {
+			merged = mergeVariant(merged, override)
+		}
+	}
+
+	variant := models.PostVariant{Platform: platform, AccountID: accountID, Content: content, MediaURLs: mediaURLs}
+	if merged.Content != nil {
+		variant.Content = *merged.Content
+	}
+	if merged.MediaURLs != nil {
+		variant.MediaURLs = *merged.MediaURLs
+	}
+	if variant.MediaURLs == nil {
+		variant.MediaURLs = []string{}
+	}
+	if merged.Link != nil && *merged.Link != "" && !strings.Contains(variant.Content, *merged.Link) {
+		variant.Content = appendParagraph(variant.Content, *merged.Link)
+	}
+	if merged.Hashtags != nil && len(*merged.Hashtags) > 0 {
+		tags := make([]string, 0, len(*merged.Hashtags))
+		for _, tag := range *merged.Hashtags {
+			tags = append(tags, "#"+strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		}
+		variant.Content = appendParagraph(variant.Content, strings.Join(tags, " "))
+	}
+
+	// Titles and descriptions fall back to the YouTube meta the create-post form sends
+	variant.Title = metaString(target.Meta, "title")
+	if merged.Title != nil {
+		variant.Title = *merged.Title
+	}
+	variant.Description = metaString(target.Meta, "description")
+	if merged.Description != nil {
+		variant.Description = *merged.Description
+	}
+	if merged.FirstComment != nil {
+		variant.FirstComment = strings.TrimSpace(*merged.FirstComment)
+	}
+	return variant
+}
+
+// mergeVariant returns base with every field set in override replaced
+func mergeVariant(base, override models.ContentVariant) models.ContentVariant {
+	if override.Content != nil {
+		base.Content = override.Content
+	}
+	if override.MediaURLs != nil {
+		base.MediaURLs = override.MediaURLs
+	}
+	if override.Hashtags != nil {
+		base.Hashtags = override.Hashtags
+	}
+	if override.Link != nil {
+		base.Link = override.Link
+	}
+	if override.Title != nil {
+		base.Title = override.Title
+	}
+	if override.Description != nil {
+		base.Description = override.Description
+	}
+	if override.FirstComment != nil {
+		base.FirstComment = override.FirstComment
+	}
+	return base
+}
+
+func appendParagraph(text, paragraph string) string {
+	if strings.TrimSpace(text) == "" {
+		return paragraph
+	}
+	return text + "\n\n" + paragraph
+}
+
+func metaString(meta map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := meta[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// PostVariants lists what each platform of a post publishes, followed by each per-account override
+func PostVariants(post models.ScheduledPost) []models.PostVariant {
+	targets, err := ParseTargets(post.Targets)
+	if err != nil {
+		log.Printf("Scheduled post %d has unreadable targets: %v", post.ID, err)
+	}
+	variants := make([]models.PostVariant, 0, len(post.Platforms))
+	for _, platform := range post.Platforms {
+		target := targets[platform]
+		variants = append(variants, ResolveVariant(post.Content, post.MediaURLs, target, platform, nil))
+
+		accountIDs := make([]string, 0, len(target.Accounts))
+		for id := range target.Accounts {
+			accountIDs = append(accountIDs, id)
+		}
+		sort.Strings(accountIDs)
+		for _, id := range accountIDs {
+			id := id
+			variants = append(variants, ResolveVariant(post.Content, post.MediaURLs, target, platform, &id))
+		}
+	}
+	return variants
+}
+
+// variantPublishRequest builds the request a Publisher receives for a resolved variant
+func variantPublishRequest(variant models.PostVariant, target models.PostTarget) PublishRequest {
+	req := PublishRequest{
+		Content:     variant.Content,
+		MediaURLs:   variant.MediaURLs,
+		Title:       variant.Title,
+		Description: variant.Description,
+		Privacy:     metaString(target.Meta, "privacy"),
+		CategoryID:  metaString(target.Meta, "categoryId", "category_id"),
+	}
+	switch tags := target.Meta["tags"].(type) {
+	case string:
+		req.Tags = tags
+	case []interface{}:
+		var list []string
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				list = append(list, s)
+			}
+		}
+		req.Tags = strings.Join(list, ",")
+	}
+	return req
+}
@@ -0,0 +1,394 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Publisher publishes content to a single connected account on one platform.
+// Each platform registers exactly one Publisher; the immediate posting handlers
+// and the ScheduledPostProcessor both dispatch through the registry so platform
+// behaviour only has to be written once.
+type Publisher interface {
+	// Platform returns the platform key used in social_accounts and scheduled_posts (e.g. "twitter")
+	Platform() string
+	// Publish sends req to the given account and returns the remote post reference
+	Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error)
+}
+
+// ThreadPublisher is implemented by publishers that can continue one of their own posts.
+// The scheduler uses it to publish the parts of a multi-part post as a native thread.
+type ThreadPublisher interface {
+	Publisher
+	// PublishReply publishes req as the next part after the remote post parentID
+	PublishReply(ctx context.Context, account PublishAccount, req PublishRequest, parentID string) (*PublishResult, error)
+}
+
+// CommentPublisher is implemented by publishers that can comment on a post they created.
+// The scheduler uses it to post a target's first_comment.
+type CommentPublisher interface {
+	Publisher
+	// PublishComment comments message under the remote post remotePostID
+	PublishComment(ctx context.Context, account PublishAccount, remotePostID, message string) (*PublishResult, error)
+}
+
+// DeletePublisher is implemented by publishers that can remove a post they created.
+// It backs unpublishing a posted scheduled post.
+type DeletePublisher interface {
+	Publisher
+	// DeletePost removes the remote post remotePostID from the account
+	DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error
+}
+
+// EditPublisher is implemented by publishers that can change a post after it went out.
+// It backs editing published scheduled posts; platforms without it are immutable.
+type EditPublisher interface {
+	Publisher
+	// EditPost replaces the text (and YouTube metadata) of remotePostID with req's. Media is
+	// left as published; req.MediaURLs only describes what the post carries.
+	EditPost(ctx context.Context, account PublishAccount, remotePostID string, req PublishRequest) error
+}
+
+// PublishAccount is a connected social account resolved from social_accounts
+type PublishAccount struct {
+	ID           string `json:"id"`          // social_accounts.id
+	UserID       string `json:"user_id"`     // owner of the connection
+	Platform     string `json:"platform"`    // facebook, instagram, twitter, mastodon, telegram, youtube
+	ExternalID   string `json:"external_id"` // page ID, IG user ID, chat ID, channel ID or instance:account
+	DisplayName  string `json:"display_name"`
+	AccessToken  string `json:"-"`
+	RefreshToken string `json:"-"`
+}
+
+// PublishRequest is the platform-agnostic content handed to a Publisher
+type PublishRequest struct {
+	Content     string
+	MediaURLs   []string
+	Title       string // YouTube only; falls back to Content
+	Description string // YouTube only; falls back to Content
+	Tags        string // YouTube only; comma separated
+	Privacy     string // YouTube only; defaults to private
+	CategoryID  string // YouTube only; defaults to 22 (People & Blogs)
+
+	// Remote IDs of the posts of a multi-step publish (Telegram album messages, Facebook
+	// videos) that an earlier attempt already made, in order. Those steps are skipped.
+	Published []string
+}
+
+// PublishResult describes the post created on the remote platform
+type PublishResult struct {
+	RemotePostID string `json:"remote_post_id,omitempty"`
+	Permalink    string `json:"permalink,omitempty"`
+
+	// Remote IDs of every post of a multi-step publish made so far, including the skipped
+	// ones. A failed publish returns it with its error so a retry can resume after them,
+	// and unpublishing deletes each of them.
+	Parts []string `json:"parts,omitempty"`
+}
+
+// PublishOutcome is the result of publishing to one account
+type PublishOutcome struct {
+	Account PublishAccount
+	Result  *PublishResult
+	Err     error
+}
+
+var (
+	publishersMu sync.RWMutex
+	publishers   = make(map[string]Publisher)
+)
+
+// RegisterPublisher adds a platform adapter to the registry, replacing any previous one
+func RegisterPublisher(p Publisher) {
+	publishersMu.Lock()
+	defer publishersMu.Unlock()
+	publishers[p.Platform()] = p
+}
+
+// GetPublisher returns the registered adapter for a platform
+func GetPublisher(platform string) (Publisher, error) {
+	publishersMu.RLock()
+	defer publishersMu.RUnlock()
+	p, ok := publishers[platform]
+	if !ok {
+		return nil, fmt.Errorf("unsupported platform: %s", platform)
+	}
+	return p, nil
+}
+
+// SupportedPlatforms lists the platforms that have a registered Publisher
+func SupportedPlatforms() []string {
+	publishersMu.RLock()
+	defer publishersMu.RUnlock()
+	platforms := make([]string, 0, len(publishers))
+	for platform := range publishers {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// SupportsThreads reports whether platform's Publisher can publish multi-part posts as threads
+func SupportsThreads(platform string) bool {
+	p, err := GetPublisher(platform)
+	if err != nil {
+		return false
+	}
+	_, ok := p.(ThreadPublisher)
+	return ok
+}
+
+// SupportsComments reports whether platform's Publisher can post a first comment
+func SupportsComments(platform string) bool {
+	p, err := GetPublisher(platform)
+	if err != nil {
+		return false
+	}
+	_, ok := p.(CommentPublisher)
+	return ok
+}
+
+// SupportsDeletion reports whether platform's Publisher can delete a published post
+func SupportsDeletion(platform string) bool {
+	p, err := GetPublisher(platform)
+	if err != nil {
+		return false
+	}
+	_, ok := p.(DeletePublisher)
+	return ok
+}
+
+// SupportsEditing reports whether platform's Publisher can edit a published post
+func SupportsEditing(platform string) bool {
+	p, err := GetPublisher(platform)
+	if err != nil {
+		return false
+	}
+	_, ok := p.(EditPublisher)
+	return ok
+}
+
+// IsSupportedPlatform reports whether a Publisher is registered for platform
+func IsSupportedPlatform(platform string) bool {
+	_, err := GetPublisher(platform)
+	return err == nil
+}
+
+// ResolvePublishAccounts loads the accounts a post should be sent to.
+// Explicit account IDs win; otherwise all=true selects every connected account
+// and the fallback is the user's default (or most recently connected) account.
+func ResolvePublishAccounts(db *sql.DB, userID, platform string, accountIDs []string, all bool) ([]PublishAccount, error) {
+	query := `
+		SELECT id::text, user_id::text, COALESCE(platform, provider, ''),
+		       COALESCE(external_account_id, social_id, ''),
+		       COALESCE(display_name, profile_name, ''),
+		       COALESCE(NULLIF(access_token, ''), access_token_enc, ''),
+		       COALESCE(NULLIF(refresh_token, ''), refresh_token_enc, '')
+		FROM social_accounts
+		WHERE user_id = $1 AND (platform = $2 OR provider = $2)
+	`
+	args := []interface{}{userID, platform}
+
+	switch {
+	case len(accountIDs) > 0:
+		query += " AND id = ANY($3::uuid[])"
+		args = append(args, pq.Array(accountIDs))
+	case all:
+		query += " ORDER BY connected_at DESC"
+	default:
+		query += " ORDER BY is_default DESC NULLS LAST, connected_at DESC LIMIT 1"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s accounts: %v", platform, err)
+	}
+	defer rows.Close()
+
+	var accounts []PublishAccount
+	for rows.Next() {
+		var a PublishAccount
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Platform, &a.ExternalID, &a.DisplayName, &a.AccessToken, &a.RefreshToken); err != nil {
+			return nil, fmt.Errorf("failed to scan %s account: %v", platform, err)
+		}
+		a.Platform = platform
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s accounts: %v", platform, err)
+	}
+	return accounts, nil
+}
+
+// PublishJob is one account and the content to publish to it
+type PublishJob struct {
+	Account PublishAccount
+	Request PublishRequest
+}
+
+// PublishToAccounts publishes req to every account through the platform's
+// registered Publisher and returns one outcome per account, in order
+func PublishToAccounts(ctx context.Context, platform string, accounts []PublishAccount, req PublishRequest) ([]PublishOutcome, error) {
+	jobs := make([]PublishJob, len(accounts))
+	for i, account := range accounts {
+		jobs[i] = PublishJob{Account: account, Request: req}
+	}
+	return PublishJobs(ctx, platform, jobs)
+}
+
+// PublishJobs publishes every job through the platform's registered Publisher and returns
+// one outcome per job, in order. Jobs run concurrently; each first waits for the platform
+// and account rate limiters, which do the pacing.
+func PublishJobs(ctx context.Context, platform string, jobs []PublishJob) ([]PublishOutcome, error) {
+	publisher, err := GetPublisher(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]PublishOutcome, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, account PublishAccount, req PublishRequest) {
+			defer wg.Done()
+			// Respect the platform and per-account token buckets
+			if err := WaitForPublishSlot(ctx, platform, account.ID); err != nil {
+				outcomes[i] = PublishOutcome{Account: account, Err: fmt.Errorf("rate limit wait aborted: %v", err)}
+				return
+			}
+			result, pubErr := publisher.Publish(ctx, account, req)
+			if pubErr != nil {
+				log.Printf("Publisher: %s account %s failed: %v", platform, account.ID, pubErr)
+			} else {
+				log.Printf("Publisher: %s account %s succeeded", platform, account.ID)
+			}
+			outcomes[i] = PublishOutcome{Account: account, Result: result, Err: pubErr}
+		}(i, job.Account, job.Request)
+	}
+	wg.Wait()
+	return outcomes, nil
+}
+
+// isVideoMediaURL determines if a media URL points to a video file
+func isVideoMediaURL(mediaURL string) bool {
+	lower := strings.ToLower(mediaURL)
+
+	// Cloudinary stores videos under /video/upload/
+	if strings.Contains(lower, "/video/") || strings.Contains(lower, "video_upload") {
+		return true
+	}
+
+	// Strip query string before checking the extension
+	if i := strings.Index(lower, "?"); i >= 0 {
+		lower = lower[:i]
+	}
+	for _, ext := range []string{".mp4", ".mov", ".avi", ".mkv", ".wmv", ".flv", ".webm", ".m4v"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMediaByType separates image URLs from video URLs, preserving order
+func splitMediaByType(mediaURLs []string) (images, videos []string) {
+	for _, u := range mediaURLs {
+		if isVideoMediaURL(u) {
+			videos = append(videos, u)
+		} else {
+			images = append(images, u)
+		}
+	}
+	return images, videos
+}
+
+// doPublishRequest executes req and decodes a successful JSON response into out (which may be nil).
+// Non-2xx responses are returned as errors that include the status code and body.
+func doPublishRequest(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %v", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
+
+// downloadMedia fetches a media file (usually from Cloudinary) into memory
+func downloadMedia(ctx context.Context, mediaURL string, timeout time.Duration) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create download request: %v", err)
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("media download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read media data: %v", err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// updateAccountAccessToken persists a refreshed token on a single social account
+func updateAccountAccessToken(db *sql.DB, accountID, accessToken string) {
+	if db == nil || accountID == "" {
+		return
+	}
+	_, err := db.Exec(`
+		UPDATE social_accounts
+		SET access_token = $1, access_token_enc = $1, last_synced_at = NOW(), updated_at = NOW()
+		WHERE id = $2::uuid
+	`, accessToken, accountID)
+	if err != nil {
+		log.Printf("WARNING: Failed to persist refreshed token for account %s: %v", accountID, err)
+	}
+}
+
+// isAuthError reports whether a platform error looks like an expired or revoked token
+func isAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "status 401") ||
+		strings.Contains(msg, "UNAUTHENTICATED") ||
+		strings.Contains(msg, "Invalid Credentials") ||
+		strings.Contains(msg, "OAuthException")
+}
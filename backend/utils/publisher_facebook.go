@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// facebookGraphURL is the Graph API base used for page publishing
+const facebookGraphURL = "https://graph.facebook.com/v20.0"
+
+// FacebookPublisher publishes to Facebook pages via the Graph API
+type FacebookPublisher struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterPublisher(&FacebookPublisher{client: &http.Client{Timeout: 120 * time.Second}})
+}
+
+// Platform implements Publisher
+func (p *FacebookPublisher) Platform() string { return "facebook" }
+
+// Publish posts text, a single photo/video, an image carousel, or mixed media to a page.
+// Mixed media is published as one carousel post for the images plus one post per video;
+// the returned RemotePostID is the first post created and Parts lists every post. Posts
+// made by an earlier attempt (req.Published) are not made again, so when a later video
+// fails the retry resumes at that video.
+func (p *FacebookPublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	if account.AccessToken == "" || account.ExternalID == "" {
+		return nil, fmt.Errorf("facebook page %s is missing a token or page ID", account.ID)
+	}
+
+	pageID := account.ExternalID
+	images, videos := splitMediaByType(req.MediaURLs)
+
+	if len(images) == 0 && len(videos) == 0 {
+		form := url.Values{}
+		form.Set("message", req.Content)
+		return p.postForm(ctx, pageID, "feed", account.AccessToken, form)
+	}
+
+	// Each post made is one step: the images first, then the videos in order
+	var steps []func() (*PublishResult, error)
+	if len(images) > 0 {
+		steps = append(steps, func() (*PublishResult, error) {
+			res, err := p.postImages(ctx, pageID, account.AccessToken, req.Content, images)
+			if err != nil {
+				return nil, fmt.Errorf("images: %v", err)
+			}
+			return res, nil
+		})
+	}
+	for i, videoURL := range videos {
+		steps = append(steps, func() (*PublishResult, error) {
+			description := req.Content
+			if len(videos) > 1 {
+				description = fmt.Sprintf("%s\n\n[Video %d/%d]", req.Content, i+1, len(videos))
+			}
+			form := url.Values{}
+			form.Set("file_url", videoURL)
+			form.Set("description", description)
+			res, err := p.postForm(ctx, pageID, "videos", account.AccessToken, form)
+			if err != nil {
+				return nil, fmt.Errorf("video %d: %v", i+1, err)
+			}
+			return res, nil
+		})
+	}
+
+	var first *PublishResult
+	parts := []string{}
+	for i, step := range steps {
+		if i < len(req.Published) {
+			parts = append(parts, req.Published[i])
+			if first == nil {
+				first = &PublishResult{RemotePostID: req.Published[i]}
+			}
+			continue
+		}
+		res, err := step()
+		if err != nil {
+			if first != nil {
+				first.Parts = parts
+			}
+			return first, err
+		}
+		parts = append(parts, res.RemotePostID)
+		if first == nil {
+			first = res
+		}
+	}
+	if len(steps) > 1 {
+		first.Parts = parts
+	}
+	return first, nil
+}
+
+// postImages publishes one photo directly, or several photos as a single attached_media post
+func (p *FacebookPublisher) postImages(ctx context.Context, pageID, accessToken, message string, images []string) (*PublishResult, error) {
+	if len(images) == 1 {
+		form := url.Values{}
+		form.Set("url", images[0])
+		form.Set("message", message)
+		return p.postForm(ctx, pageID, "photos", accessToken, form)
+	}
+
+	// Upload each image unpublished, then attach them to one feed post
+	form := url.Values{}
+	form.Set("message", message)
+	for i, imageURL := range images {
+		upload := url.Values{}
+		upload.Set("url", imageURL)
+		upload.Set("published", "false")
+		res, err := p.postForm(ctx, pageID, "photos", accessToken, upload)
+		if err != nil {
+			return nil, fmt.Errorf("image upload failed: %v", err)
+		}
+		form.Set(fmt.Sprintf("attached_media[%d]", i), fmt.Sprintf(`{"media_fbid":"%s"}`, res.RemotePostID))
+	}
+	return p.postForm(ctx, pageID, "feed", accessToken, form)
+}
+
+// PublishComment implements CommentPublisher by commenting on the page post as the page
+func (p *FacebookPublisher) PublishComment(ctx context.Context, account PublishAccount, remotePostID, message string) (*PublishResult, error) {
+	form := url.Values{}
+	form.Set("message", message)
+	result, err := p.postForm(ctx, remotePostID, "comments", account.AccessToken, form)
+	if err != nil {
+		return nil, fmt.Errorf("comment failed: %v", err)
+	}
+	result.Permalink = ""
+	return result, nil
+}
+
+// DeletePost implements DeletePublisher by deleting the post remotePostID. The posts of a
+// mixed-media publish are the published parts of its delivery and are deleted one by one.
+func (p *FacebookPublisher) DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error {
+	endpoint := fmt.Sprintf("%s/%s?access_token=%s", facebookGraphURL, remotePostID, url.QueryEscape(account.AccessToken))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := doPublishRequest(p.client, httpReq, nil); err != nil {
+		return fmt.Errorf("facebook delete failed: %v", err)
+	}
+	return nil
+}
+
+// EditPost implements EditPublisher. Posts carry their text in message; a video published on
+// its own is a video object, whose text is its description.
+func (p *FacebookPublisher) EditPost(ctx context.Context, account PublishAccount, remotePostID string, req PublishRequest) error {
+	field := "message"
+	if images, videos := splitMediaByType(req.MediaURLs); len(images) == 0 && len(videos) > 0 {
+		field = "description"
+	}
+	form := url.Values{}
+	form.Set(field, req.Content)
+	form.Set("access_token", account.AccessToken)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, facebookGraphURL+"/"+remotePostID, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var updated struct {
+		Success bool `json:"success"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &updated); err != nil {
+		return fmt.Errorf("facebook edit failed: %v", err)
+	}
+	if !updated.Success {
+		return fmt.Errorf("facebook did not update post %s", remotePostID)
+	}
+	return nil
+}
+
+// postForm sends a form to /{pageID}/{edge} and returns the created object's ID
+func (p *FacebookPublisher) postForm(ctx context.Context, pageID, edge, accessToken string, form url.Values) (*PublishResult, error) {
+	form.Set("access_token", accessToken)
+	endpoint := fmt.Sprintf("%s/%s/%s", facebookGraphURL, pageID, edge)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var created struct {
+		ID     string `json:"id"`
+		PostID string `json:"post_id"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &created); err != nil {
+		return nil, err
+	}
+
+	// Photos return both the photo id and the feed post_id; prefer the post
+	remoteID := created.PostID
+	if remoteID == "" {
+		remoteID = created.ID
+	}
+	if remoteID == "" {
+		return nil, fmt.Errorf("facebook did not return an ID")
+	}
+	return &PublishResult{
+		RemotePostID: remoteID,
+		Permalink:    "https://www.facebook.com/" + remoteID,
+	}, nil
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"social-sync-backend/lib"
+)
+
+// instagramGraphURL is the Graph API base used for Instagram content publishing
+const instagramGraphURL = "https://graph.facebook.com/v19.0"
+
+// InstagramPublisher publishes to Instagram business accounts via container → publish
+type InstagramPublisher struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterPublisher(&InstagramPublisher{client: &http.Client{Timeout: 60 * time.Second}})
+}
+
+// Platform implements Publisher
+func (p *InstagramPublisher) Platform() string { return "instagram" }
+
+// Publish creates one media container per item (or a carousel for 2–10 items),
+// waits for processing and publishes it. An expired token is exchanged once via
+// the user's Facebook connection before giving up.
+func (p *InstagramPublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	if len(req.MediaURLs) == 0 {
+		return nil, fmt.Errorf("instagram requires at least one media")
+	}
+	if len(req.MediaURLs) > 10 {
+		return nil, fmt.Errorf("instagram carousel posts can have at most 10 media items")
+	}
+	if account.ExternalID == "" {
+		return nil, fmt.Errorf("instagram account %s has no business account ID", account.ID)
+	}
+
+	result, err := p.publish(ctx, account.ExternalID, account.AccessToken, req.Content, req.MediaURLs)
+	if err == nil || !isAuthError(err) {
+		return result, err
+	}
+
+	log.Printf("Instagram: token rejected for account %s, attempting refresh via Facebook", account.ID)
+	newToken, refreshErr := p.refreshToken(ctx, account.UserID)
+	if refreshErr != nil {
+		return nil, fmt.Errorf("%v (token refresh failed: %v)", err, refreshErr)
+	}
+	updateAccountAccessToken(lib.DB, account.ID, newToken)
+	return p.publish(ctx, account.ExternalID, newToken, req.Content, req.MediaURLs)
+}
+
+func (p *InstagramPublisher) publish(ctx context.Context, igUserID, accessToken, caption string, mediaURLs []string) (*PublishResult, error) {
+	isCarousel := len(mediaURLs) > 1
+	containerIDs := make([]string, 0, len(mediaURLs))
+
+	for _, mediaURL := range mediaURLs {
+		form := url.Values{}
+		if isCarousel {
+			form.Set("is_carousel_item", "true")
+		} else {
+			form.Set("caption", caption)
+		}
+		if p.isVideo(ctx, mediaURL) {
+			// VIDEO is deprecated for feed publishing; videos go out as REELS
+			form.Set("video_url", mediaURL)
+			form.Set("media_type", "REELS")
+		} else {
+			form.Set("image_url", mediaURL)
+		}
+
+		id, err := p.postForm(ctx, igUserID+"/media", accessToken, form)
+		if err != nil {
+			return nil, fmt.Errorf("media container creation failed: %v", err)
+		}
+		if err := p.waitForMediaReady(ctx, id, accessToken); err != nil {
+			return nil, fmt.Errorf("media item failed to process: %v", err)
+		}
+		containerIDs = append(containerIDs, id)
+	}
+
+	creationID := containerIDs[0]
+	if isCarousel {
+		form := url.Values{}
+		form.Set("media_type", "CAROUSEL")
+		form.Set("children", strings.Join(containerIDs, ","))
+		form.Set("caption", caption)
+		id, err := p.postForm(ctx, igUserID+"/media", accessToken, form)
+		if err != nil {
+			return nil, fmt.Errorf("carousel container creation failed: %v", err)
+		}
+		if err := p.waitForMediaReady(ctx, id, accessToken); err != nil {
+			return nil, fmt.Errorf("carousel post failed to process: %v", err)
+		}
+		creationID = id
+	}
+
+	form := url.Values{}
+	form.Set("creation_id", creationID)
+	mediaID, err := p.postForm(ctx, igUserID+"/media_publish", accessToken, form)
+	if err != nil {
+		return nil, fmt.Errorf("publish failed: %v", err)
+	}
+
+	return &PublishResult{RemotePostID: mediaID, Permalink: p.permalink(ctx, mediaID, accessToken)}, nil
+}
+
+// PublishComment implements CommentPublisher by commenting on the published media. The
+// token is refreshed once through Facebook if Instagram rejects it.
+func (p *InstagramPublisher) PublishComment(ctx context.Context, account PublishAccount, remotePostID, message string) (*PublishResult, error) {
+	form := url.Values{}
+	form.Set("message", message)
+	commentID, err := p.postForm(ctx, remotePostID+"/comments", account.AccessToken, form)
+	if err != nil && isAuthError(err) {
+		newToken, refreshErr := p.refreshToken(ctx, account.UserID)
+		if refreshErr != nil {
+			return nil, fmt.Errorf("%v (token refresh failed: %v)", err, refreshErr)
+		}
+		updateAccountAccessToken(lib.DB, account.ID, newToken)
+		form = url.Values{}
+		form.Set("message", message)
+		commentID, err = p.postForm(ctx, remotePostID+"/comments", newToken, form)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("comment failed: %v", err)
+	}
+	return &PublishResult{RemotePostID: commentID}, nil
+}
+
+// postForm posts to a Graph edge and returns the created object's ID
+func (p *InstagramPublisher) postForm(ctx context.Context, edge, accessToken string, form url.Values) (string, error) {
+	form.Set("access_token", accessToken)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, instagramGraphURL+"/"+edge, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &created); err != nil {
+		return "", err
+	}
+	if created.ID == "" {
+		return "", fmt.Errorf("instagram did not return an ID")
+	}
+	return created.ID, nil
+}
+
+// waitForMediaReady polls a media container until Instagram finishes processing it
+func (p *InstagramPublisher) waitForMediaReady(ctx context.Context, containerID, accessToken string) error {
+	const maxRetries = 30
+	const delay = 5 * time.Second
+	const initialDelay = 3 * time.Second
+
+	statusURL := fmt.Sprintf("%s/%s?fields=status_code&access_token=%s", instagramGraphURL, containerID, url.QueryEscape(accessToken))
+
+	wait := initialDelay
+	for i := 0; i < maxRetries; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = delay
+
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create status request: %v", err)
+		}
+		var status struct {
+			StatusCode string `json:"status_code"`
+		}
+		if err := doPublishRequest(p.client, httpReq, &status); err != nil {
+			return fmt.Errorf("media status check failed: %v", err)
+		}
+
+		switch status.StatusCode {
+		case "FINISHED":
+			return nil
+		case "ERROR":
+			return fmt.Errorf("media upload failed with status 'ERROR'")
+		}
+	}
+
+	return fmt.Errorf("media not ready for ID %s after %d retries (%s total wait)", containerID, maxRetries, time.Duration(maxRetries)*delay)
+}
+
+// permalink looks up the public URL of a published media; failures are not fatal
+func (p *InstagramPublisher) permalink(ctx context.Context, mediaID, accessToken string) string {
+	endpoint := fmt.Sprintf("%s/%s?fields=permalink&access_token=%s", instagramGraphURL, mediaID, url.QueryEscape(accessToken))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return ""
+	}
+	var res struct {
+		Permalink string `json:"permalink"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &res); err != nil {
+		log.Printf("Instagram: could not fetch permalink for %s: %v", mediaID, err)
+		return ""
+	}
+	return res.Permalink
+}
+
+// isVideo detects videos by URL first and falls back to a HEAD request for the content type
+func (p *InstagramPublisher) isVideo(ctx context.Context, mediaURL string) bool {
+	if isVideoMediaURL(mediaURL) {
+		return true
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodHead, mediaURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "video/")
+}
+
+// refreshToken exchanges the user's Facebook token for a new long-lived token
+func (p *InstagramPublisher) refreshToken(ctx context.Context, userID string) (string, error) {
+	var fbToken string
+	err := lib.DB.QueryRow(`
+		SELECT COALESCE(NULLIF(access_token, ''), access_token_enc, '')
+		FROM social_accounts
+		WHERE user_id = $1 AND platform = 'facebook'
+		ORDER BY is_default DESC NULLS LAST, connected_at DESC
+		LIMIT 1
+	`, userID).Scan(&fbToken)
+	if err == sql.ErrNoRows || fbToken == "" {
+		return "", fmt.Errorf("no Facebook token available")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to load Facebook token: %v", err)
+	}
+
+	q := url.Values{}
+	q.Set("grant_type", "fb_exchange_token")
+	q.Set("client_id", os.Getenv("FACEBOOK_APP_ID"))
+	q.Set("client_secret", os.Getenv("FACEBOOK_APP_SECRET"))
+	q.Set("fb_exchange_token", fbToken)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, instagramGraphURL+"/oauth/access_token?"+q.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create refresh request: %v", err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("received empty access token from facebook")
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// MastodonPublisher publishes statuses to the account's own Mastodon instance
+type MastodonPublisher struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterPublisher(&MastodonPublisher{client: &http.Client{Timeout: 60 * time.Second}})
+}
+
+// Platform implements Publisher
+func (p *MastodonPublisher) Platform() string { return "mastodon" }
+
+// Publish uploads any media and creates a public status
+func (p *MastodonPublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	return p.publish(ctx, account, req, "")
+}
+
+// PublishReply implements ThreadPublisher by replying to the status parentID
+func (p *MastodonPublisher) PublishReply(ctx context.Context, account PublishAccount, req PublishRequest, parentID string) (*PublishResult, error) {
+	return p.publish(ctx, account, req, parentID)
+}
+
+// publish creates a status, as a reply to inReplyTo when it is set
+func (p *MastodonPublisher) publish(ctx context.Context, account PublishAccount, req PublishRequest, inReplyTo string) (*PublishResult, error) {
+	instanceURL, err := mastodonInstanceURL(account.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaIDs []string
+	for i, mediaURL := range req.MediaURLs {
+		if mediaURL == "" {
+			continue
+		}
+		mediaID, err := p.uploadMedia(ctx, instanceURL, account.AccessToken, mediaURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload media %d: %v", i+1, err)
+		}
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+
+	payload := map[string]interface{}{
+		"status":     req.Content,
+		"visibility": "public",
+	}
+	if len(mediaIDs) > 0 {
+		payload["media_ids"] = mediaIDs
+	}
+	if inReplyTo != "" {
+		payload["in_reply_to_id"] = inReplyTo
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, instanceURL+"/api/v1/statuses", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+
+	var status struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &status); err != nil {
+		return nil, fmt.Errorf("mastodon API error: %v", err)
+	}
+	return &PublishResult{RemotePostID: status.ID, Permalink: status.URL}, nil
+}
+
+// DeletePost implements DeletePublisher by deleting the status remotePostID
+func (p *MastodonPublisher) DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error {
+	instanceURL, err := mastodonInstanceURL(account.ExternalID)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, instanceURL+"/api/v1/statuses/"+url.PathEscape(remotePostID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+	if err := doPublishRequest(p.client, httpReq, nil); err != nil {
+		return fmt.Errorf("mastodon API error: %v", err)
+	}
+	return nil
+}
+
+// EditPost implements EditPublisher with PUT /api/v1/statuses/:id. The status's current
+// attachments are sent back so the edit doesn't drop them.
+func (p *MastodonPublisher) EditPost(ctx context.Context, account PublishAccount, remotePostID string, req PublishRequest) error {
+	instanceURL, err := mastodonInstanceURL(account.ExternalID)
+	if err != nil {
+		return err
+	}
+	statusURL := instanceURL + "/api/v1/statuses/" + url.PathEscape(remotePostID)
+
+	getReq, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	getReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+	var current struct {
+		MediaAttachments []struct {
+			ID string `json:"id"`
+		} `json:"media_attachments"`
+	}
+	if err := doPublishRequest(p.client, getReq, &current); err != nil {
+		return fmt.Errorf("mastodon API error: %v", err)
+	}
+
+	payload := map[string]interface{}{"status": req.Content}
+	if len(current.MediaAttachments) > 0 {
+		mediaIDs := make([]string, 0, len(current.MediaAttachments))
+		for _, m := range current.MediaAttachments {
+			mediaIDs = append(mediaIDs, m.ID)
+		}
+		payload["media_ids"] = mediaIDs
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	putReq, err := http.NewRequestWithContext(ctx, http.MethodPut, statusURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	putReq.Header.Set("Content-Type", "application/json")
+	putReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+	if err := doPublishRequest(p.client, putReq, nil); err != nil {
+		return fmt.Errorf("mastodon API error: %v", err)
+	}
+	return nil
+}
+
+// uploadMedia downloads mediaURL and uploads it to the instance's media endpoint
+func (p *MastodonPublisher) uploadMedia(ctx context.Context, instanceURL, accessToken, mediaURL string) (string, error) {
+	data, _, err := downloadMedia(ctx, mediaURL, 60*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	filename := path.Base(strings.SplitN(mediaURL, "?", 2)[0])
+	if filename == "" || filename == "." || filename == "/" {
+		filename = "media"
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write media data: %v", err)
+	}
+	writer.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, instanceURL+"/api/v1/media", &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var media struct {
+		ID string `json:"id"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &media); err != nil {
+		return "", fmt.Errorf("mastodon media upload failed: %v", err)
+	}
+	if media.ID == "" {
+		return "", fmt.Errorf("mastodon media upload returned no ID")
+	}
+	return media.ID, nil
+}
+
+// mastodonInstanceURL extracts the instance base URL from a social_id of the form
+// "https://instance.example:accountID" (or the older "instance.example:accountID")
+func mastodonInstanceURL(socialID string) (string, error) {
+	lastColon := strings.LastIndex(socialID, ":")
+	if lastColon <= 0 || strings.HasPrefix(socialID[lastColon:], "://") {
+		return "", fmt.Errorf("invalid mastodon social_id format: %s", socialID)
+	}
+
+	instanceURL := socialID[:lastColon]
+	if !strings.HasPrefix(instanceURL, "http://") && !strings.HasPrefix(instanceURL, "https://") {
+		instanceURL = "https://" + instanceURL
+	}
+	if instanceURL == "https://" || instanceURL == "http://" {
+		return "", fmt.Errorf("invalid mastodon social_id format: %s", socialID)
+	}
+	return strings.TrimRight(instanceURL, "/"), nil
+}
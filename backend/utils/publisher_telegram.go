@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramMediaGroupLimit is the maximum number of items Telegram accepts in one sendMediaGroup
+const telegramMediaGroupLimit = 10
+
+// TelegramPublisher sends messages to a channel or group through the bot API
+type TelegramPublisher struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterPublisher(&TelegramPublisher{client: &http.Client{Timeout: 60 * time.Second}})
+}
+
+// Platform implements Publisher
+func (p *TelegramPublisher) Platform() string { return "telegram" }
+
+// Publish sends text, a single photo/video, or media albums of 2 to 10 items each.
+// The caption is attached to the first album; RemotePostID is the first message ID and
+// Parts lists the ID of every album message. Albums sent by an earlier attempt (whose
+// messages are in req.Published) are not sent again.
+func (p *TelegramPublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	botToken, err := telegramBotToken(account)
+	if err != nil {
+		return nil, err
+	}
+	chatID := account.ExternalID
+	if chatID == "" {
+		return nil, fmt.Errorf("telegram account %s has no chat ID", account.ID)
+	}
+
+	var messageID int
+	switch len(req.MediaURLs) {
+	case 0:
+		messageID, err = p.call(ctx, botToken, "sendMessage", map[string]interface{}{
+			"chat_id": chatID,
+			"text":    req.Content,
+		})
+	case 1:
+		method, field := "sendPhoto", "photo"
+		if isVideoMediaURL(req.MediaURLs[0]) {
+			method, field = "sendVideo", "video"
+		}
+		messageID, err = p.call(ctx, botToken, method, map[string]interface{}{
+			"chat_id": chatID,
+			field:     req.MediaURLs[0],
+			"caption": req.Content,
+		})
+	default:
+		parts, err := p.sendAlbums(ctx, botToken, chatID, req.Content, req.MediaURLs, req.Published)
+		if len(parts) == 0 {
+			return nil, err
+		}
+		messageID, _ = strconv.Atoi(parts[0])
+		return &PublishResult{
+			RemotePostID: parts[0],
+			Permalink:    telegramPermalink(chatID, messageID),
+			Parts:        parts,
+		}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublishResult{
+		RemotePostID: strconv.Itoa(messageID),
+		Permalink:    telegramPermalink(chatID, messageID),
+	}, nil
+}
+
+// PublishReply implements ThreadPublisher. Channels have no reply chains, so the parts of a
+// multi-part post are sent as sequential messages.
+func (p *TelegramPublisher) PublishReply(ctx context.Context, account PublishAccount, req PublishRequest, parentID string) (*PublishResult, error) {
+	return p.Publish(ctx, account, req)
+}
+
+// DeletePost implements DeletePublisher by deleting the message remotePostID. The messages of
+// an album are the published parts of its delivery and are deleted one by one.
+func (p *TelegramPublisher) DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error {
+	botToken, err := telegramBotToken(account)
+	if err != nil {
+		return err
+	}
+	messageID, err := strconv.Atoi(remotePostID)
+	if err != nil {
+		return fmt.Errorf("invalid telegram message ID %q", remotePostID)
+	}
+	_, err = p.call(ctx, botToken, "deleteMessage", map[string]interface{}{
+		"chat_id":    account.ExternalID,
+		"message_id": messageID,
+	})
+	return err
+}
+
+// EditPost implements EditPublisher. Text messages are edited with editMessageText and media
+// messages, whose text is a caption, with editMessageCaption.
+func (p *TelegramPublisher) EditPost(ctx context.Context, account PublishAccount, remotePostID string, req PublishRequest) error {
+	botToken, err := telegramBotToken(account)
+	if err != nil {
+		return err
+	}
+	messageID, err := strconv.Atoi(remotePostID)
+	if err != nil {
+		return fmt.Errorf("invalid telegram message ID %q", remotePostID)
+	}
+
+	method, field := "editMessageText", "text"
+	if len(req.MediaURLs) > 0 {
+		method, field = "editMessageCaption", "caption"
+	}
+	_, err = p.call(ctx, botToken, method, map[string]interface{}{
+		"chat_id":    account.ExternalID,
+		"message_id": messageID,
+		field:        req.Content,
+	})
+	// Telegram rejects edits that change nothing; the message already says what we want
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+// sendAlbums sends media as the albums of telegramAlbums, skipping the ones already sent,
+// and returns the message IDs of every album sent so far. Each media item is one message,
+// so the first len(published) items are the ones already sent.
+func (p *TelegramPublisher) sendAlbums(ctx context.Context, botToken, chatID, caption string, mediaURLs, published []string) ([]string, error) {
+	parts := append([]string(nil), published...)
+	start := 0
+	for i, album := range telegramAlbums(mediaURLs) {
+		end := start + len(album)
+		if end <= len(published) {
+			start = end
+			continue
+		}
+
+		media := make([]map[string]interface{}, 0, len(album))
+		for j, mediaURL := range album {
+			item := map[string]interface{}{"type": "photo", "media": mediaURL}
+			if isVideoMediaURL(mediaURL) {
+				item["type"] = "video"
+			}
+			if i == 0 && j == 0 && caption != "" {
+				item["caption"] = caption
+			}
+			media = append(media, item)
+		}
+
+		ids, err := p.callMessages(ctx, botToken, "sendMediaGroup", map[string]interface{}{
+			"chat_id": chatID,
+			"media":   media,
+		})
+		if err != nil {
+			return parts, fmt.Errorf("failed to send media %d-%d: %v", start+1, end, err)
+		}
+		for _, id := range ids {
+			parts = append(parts, strconv.Itoa(id))
+		}
+		start = end
+	}
+	return parts, nil
+}
+
+// telegramAlbums splits media into albums of at most telegramMediaGroupLimit items. Telegram
+// needs at least 2 items in an album, so a single leftover item takes one from the album
+// before it (21 items go out as 10, 9 and 2).
+func telegramAlbums(mediaURLs []string) [][]string {
+	var albums [][]string
+	for start := 0; start < len(mediaURLs); start += telegramMediaGroupLimit {
+		end := start + telegramMediaGroupLimit
+		if end > len(mediaURLs) {
+			end = len(mediaURLs)
+		}
+		albums = append(albums, mediaURLs[start:end])
+	}
+	if n := len(albums); n > 1 && len(albums[n-1]) == 1 {
+		prev := albums[n-2]
+		albums[n-2] = prev[:len(prev)-1]
+		albums[n-1] = mediaURLs[len(mediaURLs)-2:]
+	}
+	return albums
+}
+
+// telegramBotToken returns the account's bot token, falling back to the app-wide bot
+func telegramBotToken(account PublishAccount) (string, error) {
+	botToken := account.AccessToken
+	if botToken == "" {
+		botToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if botToken == "" {
+		return "", fmt.Errorf("telegram bot not configured")
+	}
+	return botToken, nil
+}
+
+// call invokes a bot API method and returns the message_id in the result, the first one
+// when it has several
+func (p *TelegramPublisher) call(ctx context.Context, botToken, method string, payload map[string]interface{}) (int, error) {
+	ids, err := p.callMessages(ctx, botToken, method, payload)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// callMessages invokes a bot API method and returns every message_id in the result
+func (p *TelegramPublisher) callMessages(ctx context.Context, botToken, method string, payload map[string]interface{}) ([]int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/%s", botToken, method)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &resp); err != nil {
+		// Don't leak the bot token in error messages
+		return nil, fmt.Errorf("telegram %s failed: %s", method, strings.ReplaceAll(err.Error(), botToken, "***"))
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("telegram API error: %s", resp.Description)
+	}
+
+	// sendMediaGroup returns an array of messages, everything else a single message
+	var single struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &single); err == nil && single.MessageID != 0 {
+		return []int{single.MessageID}, nil
+	}
+	var many []struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &many); err != nil {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(many))
+	for _, m := range many {
+		ids = append(ids, m.MessageID)
+	}
+	return ids, nil
+}
+
+// telegramPermalink builds a t.me link for public channels; private chats (-100…) use the c/ form
+func telegramPermalink(chatID string, messageID int) string {
+	if messageID == 0 {
+		return ""
+	}
+	if strings.HasPrefix(chatID, "@") {
+		return fmt.Sprintf("https://t.me/%s/%d", strings.TrimPrefix(chatID, "@"), messageID)
+	}
+	if strings.HasPrefix(chatID, "-100") {
+		return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(chatID, "-100"), messageID)
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	twitterTweetsURL = "https://api.twitter.com/2/tweets"
+	twitterUploadURL = "https://upload.twitter.com/1.1/media/upload.json"
+)
+
+// TwitterPublisher publishes tweets with the v2 API using the account's OAuth 2.0 bearer token
+type TwitterPublisher struct {
+	client *http.Client
+}
+
+func init() {
+	RegisterPublisher(&TwitterPublisher{client: &http.Client{Timeout: 60 * time.Second}})
+}
+
+// Platform implements Publisher
+func (p *TwitterPublisher) Platform() string { return "twitter" }
+
+// Publish creates a tweet. Media that cannot be uploaded is skipped; if none of it
+// uploads, the media URLs are appended to the text so the tweet still carries them.
+func (p *TwitterPublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	return p.publish(ctx, account, req, "")
+}
+
+// PublishReply implements ThreadPublisher by replying to the tweet parentID
+func (p *TwitterPublisher) PublishReply(ctx context.Context, account PublishAccount, req PublishRequest, parentID string) (*PublishResult, error) {
+	return p.publish(ctx, account, req, parentID)
+}
+
+// publish creates a tweet, as a reply to inReplyTo when it is set
+func (p *TwitterPublisher) publish(ctx context.Context, account PublishAccount, req PublishRequest, inReplyTo string) (*PublishResult, error) {
+	if account.AccessToken == "" {
+		return nil, fmt.Errorf("twitter account %s is not properly connected", account.ID)
+	}
+
+	payload := map[string]interface{}{"text": req.Content}
+	if inReplyTo != "" {
+		payload["reply"] = map[string]interface{}{"in_reply_to_tweet_id": inReplyTo}
+	}
+
+	if len(req.MediaURLs) > 0 {
+		var mediaIDs []string
+		for _, mediaURL := range req.MediaURLs {
+			mediaID, err := p.uploadMedia(ctx, account.AccessToken, mediaURL)
+			if err != nil {
+				log.Printf("Twitter: media upload failed for account %s: %v", account.ID, err)
+				continue
+			}
+			mediaIDs = append(mediaIDs, mediaID)
+		}
+
+		if len(mediaIDs) > 0 {
+			payload["media"] = map[string]interface{}{"media_ids": mediaIDs}
+		} else {
+			payload["text"] = req.Content + "\n\n📸 " + strings.Join(req.MediaURLs, " ")
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, twitterTweetsURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &created); err != nil {
+		return nil, fmt.Errorf("twitter API error: %v", err)
+	}
+	if created.Data.ID == "" {
+		return nil, fmt.Errorf("twitter did not return a tweet ID")
+	}
+
+	return &PublishResult{
+		RemotePostID: created.Data.ID,
+		Permalink:    "https://twitter.com/i/web/status/" + created.Data.ID,
+	}, nil
+}
+
+// DeletePost implements DeletePublisher by deleting the tweet remotePostID
+func (p *TwitterPublisher) DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error {
+	if account.AccessToken == "" {
+		return fmt.Errorf("twitter account %s is not properly connected", account.ID)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, twitterTweetsURL+"/"+remotePostID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+account.AccessToken)
+
+	var deleted struct {
+		Data struct {
+			Deleted bool `json:"deleted"`
+		} `json:"data"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &deleted); err != nil {
+		return fmt.Errorf("twitter API error: %v", err)
+	}
+	if !deleted.Data.Deleted {
+		return fmt.Errorf("twitter did not delete tweet %s", remotePostID)
+	}
+	return nil
+}
+
+// uploadMedia downloads mediaURL and uploads it through the v1.1 media endpoint
+func (p *TwitterPublisher) uploadMedia(ctx context.Context, accessToken, mediaURL string) (string, error) {
+	data, _, err := downloadMedia(ctx, mediaURL, 60*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("media", "media")
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write media data: %v", err)
+	}
+	writer.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, twitterUploadURL, &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var uploaded struct {
+		MediaID string `json:"media_id_string"`
+	}
+	if err := doPublishRequest(p.client, httpReq, &uploaded); err != nil {
+		return "", fmt.Errorf("twitter media upload failed: %v", err)
+	}
+	if uploaded.MediaID == "" {
+		return "", fmt.Errorf("twitter media upload returned no media ID")
+	}
+	return uploaded.MediaID, nil
+}
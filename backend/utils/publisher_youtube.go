@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-sync-backend/lib"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	youtubeUploadURL = "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part=snippet,status"
+	youtubeVideosURL = "https://www.googleapis.com/youtube/v3/videos"
+	youtubeMaxTitle  = 100
+)
+
+// YouTubePublisher uploads a video to the account's channel with a resumable upload
+type YouTubePublisher struct {
+	client *http.Client
+}
+
+func init() {
+	// Large uploads can take a long time; per-request deadlines come from ctx
+	RegisterPublisher(&YouTubePublisher{client: &http.Client{Timeout: 20 * time.Minute}})
+}
+
+// Platform implements Publisher
+func (p *YouTubePublisher) Platform() string { return "youtube" }
+
+// Publish uploads the first video in req.MediaURLs. On an auth failure the
+// account's refresh token is used once and the new access token is persisted.
+func (p *YouTubePublisher) Publish(ctx context.Context, account PublishAccount, req PublishRequest) (*PublishResult, error) {
+	videoURL := ""
+	for _, u := range req.MediaURLs {
+		if isVideoMediaURL(u) {
+			videoURL = u
+			break
+		}
+	}
+	if videoURL == "" && len(req.MediaURLs) > 0 {
+		videoURL = req.MediaURLs[0]
+	}
+	if videoURL == "" {
+		return nil, fmt.Errorf("YouTube requires a video file")
+	}
+
+	video, _, err := downloadMedia(ctx, videoURL, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := youtubeMetadataFor(req)
+	videoID, err := p.upload(ctx, account.AccessToken, video, meta)
+	if err != nil && isAuthError(err) && account.RefreshToken != "" {
+		log.Printf("YouTube: access token rejected for account %s, refreshing", account.ID)
+		newToken, refreshErr := refreshYouTubeAccessToken(ctx, account.RefreshToken)
+		if refreshErr != nil {
+			return nil, fmt.Errorf("YouTube token refresh failed: %v - please reconnect YouTube account", refreshErr)
+		}
+		updateAccountAccessToken(lib.DB, account.ID, newToken)
+		account.AccessToken = newToken
+		videoID, err = p.upload(ctx, newToken, video, meta)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "uploadLimitExceeded") || strings.Contains(err.Error(), "exceeded the number of videos") {
+			return nil, fmt.Errorf("YouTube daily upload limit exceeded. Please try again tomorrow or verify your YouTube account to increase limits")
+		}
+		return nil, err
+	}
+
+	// Some uploads drop the snippet; set it again defensively
+	if err := updateYouTubeVideoSnippet(ctx, p.client, account.AccessToken, videoID, meta); err != nil {
+		log.Printf("WARNING: Failed to update YouTube snippet post-upload: %v", err)
+	}
+
+	return &PublishResult{
+		RemotePostID: videoID,
+		Permalink:    "https://www.youtube.com/watch?v=" + videoID,
+	}, nil
+}
+
+// DeletePost implements DeletePublisher by deleting the video
+func (p *YouTubePublisher) DeletePost(ctx context.Context, account PublishAccount, remotePostID string) error {
+	return withYouTubeToken(ctx, account, func(accessToken string) error {
+		return p.deleteVideo(ctx, accessToken, remotePostID)
+	})
+}
+
+// EditPost implements EditPublisher by replacing the video's title, description, tags and category
+func (p *YouTubePublisher) EditPost(ctx context.Context, account PublishAccount, remotePostID string, req PublishRequest) error {
+	meta := youtubeMetadataFor(req)
+	return withYouTubeToken(ctx, account, func(accessToken string) error {
+		return updateYouTubeVideoSnippet(ctx, p.client, accessToken, remotePostID, meta)
+	})
+}
+
+// withYouTubeToken calls fn with the account's access token and, like Publish, refreshes the
+// token once and retries when YouTube rejects it
+func withYouTubeToken(ctx context.Context, account PublishAccount, fn func(accessToken string) error) error {
+	err := fn(account.AccessToken)
+	if err != nil && isAuthError(err) && account.RefreshToken != "" {
+		log.Printf("YouTube: access token rejected for account %s, refreshing", account.ID)
+		newToken, refreshErr := refreshYouTubeAccessToken(ctx, account.RefreshToken)
+		if refreshErr != nil {
+			return fmt.Errorf("YouTube token refresh failed: %v - please reconnect YouTube account", refreshErr)
+		}
+		updateAccountAccessToken(lib.DB, account.ID, newToken)
+		err = fn(newToken)
+	}
+	return err
+}
+
+func (p *YouTubePublisher) deleteVideo(ctx context.Context, accessToken, videoID string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, youtubeVideosURL+"?id="+videoID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	if err := doPublishRequest(p.client, httpReq, nil); err != nil {
+		return fmt.Errorf("YouTube delete failed: %v", err)
+	}
+	return nil
+}
+
+// youtubeMetadata is the snippet/status sent with an upload
+type youtubeMetadata struct {
+	Title       string
+	Description string
+	Tags        []string
+	CategoryID  string
+	Privacy     string
+}
+
+// youtubeMetadataFor applies YouTube defaults to a generic publish request
+func youtubeMetadataFor(req PublishRequest) youtubeMetadata {
+	meta := youtubeMetadata{
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		CategoryID:  req.CategoryID,
+		Privacy:     req.Privacy,
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSpace(req.Content)
+	}
+	if r := []rune(meta.Title); len(r) > youtubeMaxTitle {
+		meta.Title = string(r[:youtubeMaxTitle])
+	}
+	if meta.Description == "" {
+		meta.Description = req.Content
+	}
+	if meta.CategoryID == "" {
+		meta.CategoryID = "22" // People & Blogs
+	}
+	if meta.Privacy == "" {
+		meta.Privacy = "private" // Default to private for safety
+	}
+	for _, t := range strings.Split(req.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			meta.Tags = append(meta.Tags, t)
+		}
+	}
+	return meta
+}
+
+// upload runs the two-step resumable upload and returns the new video ID
+func (p *YouTubePublisher) upload(ctx context.Context, accessToken string, video []byte, meta youtubeMetadata) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"snippet": map[string]interface{}{
+			"title":       meta.Title,
+			"description": meta.Description,
+			"tags":        meta.Tags,
+			"categoryId":  meta.CategoryID,
+		},
+		"status": map[string]interface{}{
+			"privacyStatus": meta.Privacy,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata: %v", err)
+	}
+
+	initReq, err := http.NewRequestWithContext(ctx, http.MethodPost, youtubeUploadURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create init request: %v", err)
+	}
+	initReq.Header.Set("Authorization", "Bearer "+accessToken)
+	initReq.Header.Set("Content-Type", "application/json")
+	initReq.Header.Set("X-Upload-Content-Type", "video/*")
+	initReq.Header.Set("X-Upload-Content-Length", strconv.Itoa(len(video)))
+
+	initResp, err := p.client.Do(initReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize upload: %v", err)
+	}
+	initResp.Body.Close()
+	if initResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to initialize upload: API returned status %d", initResp.StatusCode)
+	}
+
+	location := initResp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("no upload URL received from YouTube")
+	}
+
+	uploadReq, err := http.NewRequestWithContext(ctx, http.MethodPut, location, bytes.NewReader(video))
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %v", err)
+	}
+	uploadReq.Header.Set("Content-Type", "video/*")
+
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if err := doPublishRequest(p.client, uploadReq, &uploaded); err != nil {
+		return "", fmt.Errorf("video upload failed: %v", err)
+	}
+	if uploaded.ID == "" {
+		return "", fmt.Errorf("YouTube did not return a video ID")
+	}
+	return uploaded.ID, nil
+}
+
+// updateYouTubeVideoSnippet sets the title/description/tags/category of an existing video
+func updateYouTubeVideoSnippet(ctx context.Context, client *http.Client, accessToken, videoID string, meta youtubeMetadata) error {
+	if videoID == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id": videoID,
+		"snippet": map[string]interface{}{
+			"title":       meta.Title,
+			"description": meta.Description,
+			"categoryId":  meta.CategoryID,
+			"tags":        meta.Tags,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal snippet: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, youtubeVideosURL+"?part=snippet", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	if err := doPublishRequest(client, req, nil); err != nil {
+		return fmt.Errorf("videos.update failed: %v", err)
+	}
+	return nil
+}
+
+// youtubeOAuthConfig mirrors the config used by the YouTube connect flow
+func youtubeOAuthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URI"),
+		Scopes: []string{
+			"https://www.googleapis.com/auth/youtube.upload",
+			"https://www.googleapis.com/auth/youtube",
+		},
+		Endpoint: google.Endpoint,
+	}
+}
+
+// refreshYouTubeAccessToken exchanges a refresh token for a new access token
+func refreshYouTubeAccessToken(ctx context.Context, refreshToken string) (string, error) {
+	token, err := youtubeOAuthConfig().TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return "", fmt.Errorf("OAuth2 token refresh failed: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("received empty access token from refresh")
+	}
+	return token.AccessToken, nil
+}
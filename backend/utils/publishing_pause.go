@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"social-sync-backend/models"
+)
+
+// pauseRecheckInterval is how soon a post held back by a pause is looked at again, so
+// resumed targets go out within about this long
+const pauseRecheckInterval = time.Minute
+
+var (
+	// ErrAlreadyPaused is returned when the same target already has an active pause
+	ErrAlreadyPaused = errors.New("publishing is already paused for this target")
+	// ErrPauseNotActive is returned when resuming a pause that doesn't exist or was already lifted
+	ErrPauseNotActive = errors.New("pause not found or already resumed")
+)
+
+const publishingPauseColumns = `id, workspace_id::text, scope, platform, social_account_id::text, reason,
+	paused_by::text, paused_at, resumed_by::text, resumed_at, resume_reason`
+
+func scanPublishingPause(row interface{ Scan(...interface{}) error }) (*models.PublishingPause, error) {
+	var p models.PublishingPause
+	err := row.Scan(&p.ID, &p.WorkspaceID, &p.Scope, &p.Platform, &p.SocialAccountID, &p.Reason,
+		&p.PausedBy, &p.PausedAt, &p.ResumedBy, &p.ResumedAt, &p.ResumeReason)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PausePublishing records an active pause; scope, target and reason are checked by the caller.
+// It takes effect on the processor's next pass; a target that is being published at that
+// moment still completes.
+func PausePublishing(db *sql.DB, pause models.PublishingPause) (*models.PublishingPause, error) {
+	created, err := scanPublishingPause(db.QueryRow(`
+		INSERT INTO publishing_pauses (workspace_id, scope, platform, social_account_id, reason, paused_by)
+		VALUES ($1, $2, $3, $4::uuid, $5, $6::uuid)
+		ON CONFLICT DO NOTHING
+		RETURNING `+publishingPauseColumns,
+		pause.WorkspaceID, pause.Scope, pause.Platform, pause.SocialAccountID, pause.Reason, pause.PausedBy))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlreadyPaused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause publishing: %v", err)
+	}
+	log.Printf("Publishing paused in workspace %s (%s): %s", created.WorkspaceID, describePause(*created), created.Reason)
+	return created, nil
+}
+
+// ResumePublishing lifts an active pause of a workspace. The held posts are picked up again
+// on their next check.
+func ResumePublishing(db *sql.DB, workspaceID string, pauseID int, resumedBy, reason string) (*models.PublishingPause, error) {
+	pause, err := scanPublishingPause(db.QueryRow(`
+		UPDATE publishing_pauses SET resumed_by = $1::uuid, resumed_at = NOW(), resume_reason = $2
+		WHERE id = $3 AND workspace_id = $4 AND resumed_at IS NULL
+		RETURNING `+publishingPauseColumns,
+		resumedBy, reason, pauseID, workspaceID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPauseNotActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume publishing: %v", err)
+	}
+	log.Printf("Publishing resumed in workspace %s (%s): %s", workspaceID, describePause(*pause), reason)
+	return pause, nil
+}
+
+// GetPublishingPauses returns a workspace's active pauses or, with history, every pause and
+// resume ever made there, newest first
+func GetPublishingPauses(db *sql.DB, workspaceID string, history bool) ([]models.PublishingPause, error) {
+	query := `SELECT ` + publishingPauseColumns + ` FROM publishing_pauses WHERE workspace_id = $1`
+	if !history {
+		query += ` AND resumed_at IS NULL`
+	}
+	rows, err := db.Query(query+` ORDER BY paused_at DESC, id DESC`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query publishing pauses: %v", err)
+	}
+	defer rows.Close()
+
+	pauses := []models.PublishingPause{}
+	for rows.Next() {
+		pause, err := scanPublishingPause(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan publishing pause: %v", err)
+		}
+		pauses = append(pauses, *pause)
+	}
+	return pauses, rows.Err()
+}
+
+func describePause(p models.PublishingPause) string {
+	switch {
+	case p.Platform != nil:
+		return "platform " + *p.Platform
+	case p.SocialAccountID != nil:
+		return "account " + *p.SocialAccountID
+	}
+	return "all posts"
+}
+
+// publishingPauses are the pauses that apply to one post while the processor works on it.
+// Targets held back are counted so the post can be left pending instead of finalized.
+type publishingPauses struct {
+	workspace bool
+	platforms map[string]bool
+	accounts  map[string]bool
+	held      int32
+}
+
+// loadPublishingPauses reads the active pauses of a post's workspace. If they can't be read
+// everything is held: during an emergency, publishing by mistake is worse than waiting.
+func loadPublishingPauses(db *sql.DB, workspaceID string) *publishingPauses {
+	p := &publishingPauses{platforms: map[string]bool{}, accounts: map[string]bool{}}
+	pauses, err := GetPublishingPauses(db, workspaceID, false)
+	if err != nil {
+		log.Printf("Failed to load publishing pauses of workspace %s, holding its posts: %v", workspaceID, err)
+		p.workspace = true
+		return p
+	}
+	for _, pause := range pauses {
+		switch {
+		case pause.Scope == models.PauseWorkspace:
+			p.workspace = true
+		case pause.Scope == models.PausePlatform && pause.Platform != nil:
+			p.platforms[*pause.Platform] = true
+		case pause.Scope == models.PauseAccount && pause.SocialAccountID != nil:
+			p.accounts[*pause.SocialAccountID] = true
+		}
+	}
+	return p
+}
+
+// holdsPlatform reports whether every target on platform is paused
+func (p *publishingPauses) holdsPlatform(platform string) bool {
+	return p.workspace || p.platforms[platform]
+}
+
+// holdsAccount reports whether the targets of a social account are paused
+func (p *publishingPauses) holdsAccount(accountID string) bool {
+	return p.accounts[accountID]
+}
+
+// hold records that a target was held back; it is safe to call from the platform goroutines
+func (p *publishingPauses) hold() {
+	atomic.AddInt32(&p.held, 1)
+}
+
+// holding reports whether any target was held back
+func (p *publishingPauses) holding() bool {
+	return atomic.LoadInt32(&p.held) > 0
+}
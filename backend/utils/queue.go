@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// queueHorizonWeeks bounds how far ahead slots are searched for a queued post
+	queueHorizonWeeks = 52
+	// queueMinLead keeps slot assignments clear of posts the processor is about to claim
+	queueMinLead = time.Minute
+)
+
+// ErrNoQueueSlot is returned when a queued post has no free matching slot within the horizon
+var ErrNoQueueSlot = errors.New("no free queue slot; add slots for this workspace")
+
+// queuedPost is the part of a scheduled post needed to match it to slots
+type queuedPost struct {
+	id        int
+	platforms []string
+	targets   map[string]interface{}
+}
+
+// slotTime is one concrete occurrence of a weekly slot
+type slotTime struct {
+	slot models.QueueSlot
+	at   time.Time
+}
+
+// workspaceLocation is the time zone queue slots are expressed in; UTC if the workspace has none
+func workspaceLocation(q sqlQueryer, workspaceID string) *time.Location {
+	var zone string
+	q.QueryRow(`SELECT COALESCE(timezone, '') FROM workspaces WHERE id = $1`, workspaceID).Scan(&zone)
+	if loc, err := LoadTimeZone(zone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// sqlQueryer is satisfied by both *sql.DB and *sql.Tx
+type sqlQueryer interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+	QueryRow(string, ...interface{}) *sql.Row
+	Exec(string, ...interface{}) (sql.Result, error)
+}
+
+// ListQueueSlots returns a workspace's slots ordered by weekday and time
+func ListQueueSlots(q sqlQueryer, workspaceID string) ([]models.QueueSlot, error) {
+	rows, err := q.Query(`
+		SELECT id, workspace_id::text, platform, social_account_id::text, day_of_week,
+		       to_char(time_of_day, 'HH24:MI'), created_by::text, created_at
+		FROM workspace_queue_slots
+		WHERE workspace_id = $1
+		ORDER BY day_of_week, time_of_day
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queue slots: %v", err)
+	}
+	defer rows.Close()
+
+	slots := []models.QueueSlot{}
+	for rows.Next() {
+		var s models.QueueSlot
+		if err := rows.Scan(&s.ID, &s.WorkspaceID, &s.Platform, &s.SocialAccountID, &s.DayOfWeek,
+			&s.TimeOfDay, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queue slot: %v", err)
+		}
+		slots = append(slots, s)
+	}
+	return slots, rows.Err()
+}
+
+// slotMatches reports whether a slot may be used by a post
+func slotMatches(slot models.QueueSlot, post queuedPost) bool {
+	if slot.Platform != nil {
+		found := false
+		for _, p := range post.platforms {
+			if p == *slot.Platform {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if slot.SocialAccountID == nil {
+		return true
+	}
+	// An account slot only matches posts explicitly targeting that account
+	for platform, t := range post.targets {
+		if slot.Platform != nil && platform != *slot.Platform {
+			continue
+		}
+		mp, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ids, _ := mp["ids"].([]interface{})
+		for _, id := range ids {
+			if s, ok := id.(string); ok && s == *slot.SocialAccountID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upcomingSlotTimes expands weekly slots into concrete times after from, in order.
+// Slot times are wall-clock times in loc, so they don't drift when DST changes.
+func upcomingSlotTimes(slots []models.QueueSlot, from time.Time, loc *time.Location) []slotTime {
+	local := from.In(loc)
+	y, m, d := local.Date()
+	var times []slotTime
+	for day := 0; day < queueHorizonWeeks*7; day++ {
+		date := time.Date(y, m, d+day, 0, 0, 0, 0, loc)
+		for _, slot := range slots {
+			if int(date.Weekday()) != slot.DayOfWeek {
+				continue
+			}
+			tod, err := time.Parse("15:04", slot.TimeOfDay)
+			if err != nil {
+				continue
+			}
+			at := time.Date(date.Year(), date.Month(), date.Day(), tod.Hour(), tod.Minute(), 0, 0, loc)
+			if at.After(from) {
+				times = append(times, slotTime{slot: slot, at: at})
+			}
+		}
+	}
+	sort.SliceStable(times, func(i, j int) bool { return times[i].at.Before(times[j].at) })
+	return times
+}
+
+// RebalanceQueue renumbers a workspace's queued pending posts 1..n (closing gaps) and
+// assigns each, in queue order, the earliest free slot that matches its platforms/accounts.
+// Run it inside the transaction that changed the queue.
+func RebalanceQueue(tx *sql.Tx, workspaceID string) error {
+	rows, err := tx.Query(`
+		SELECT id, platforms, targets
+		FROM scheduled_posts
+		WHERE workspace_id = $1 AND queue_position IS NOT NULL AND status = $2
+		ORDER BY queue_position, id
+		FOR UPDATE
+	`, workspaceID, models.StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to load queue: %v", err)
+	}
+	var posts []queuedPost
+	for rows.Next() {
+		var p queuedPost
+		var platforms pq.StringArray
+		var rawTargets []byte
+		if err := rows.Scan(&p.id, &platforms, &rawTargets); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan queued post: %v", err)
+		}
+		p.platforms = platforms
+		if len(rawTargets) > 0 {
+			json.Unmarshal(rawTargets, &p.targets)
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+
+	slots, err := ListQueueSlots(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	times := upcomingSlotTimes(slots, time.Now().Add(queueMinLead), workspaceLocation(tx, workspaceID))
+	used := make([]bool, len(times))
+
+	for i, post := range posts {
+		assigned := -1
+		for j, st := range times {
+			if !used[j] && slotMatches(st.slot, post) {
+				assigned = j
+				break
+			}
+		}
+		if assigned < 0 {
+			return ErrNoQueueSlot
+		}
+		used[assigned] = true
+		if _, err := tx.Exec(`
+			UPDATE scheduled_posts SET queue_position = $1, scheduled_time = $2, updated_at = NOW()
+			WHERE id = $3
+		`, i+1, times[assigned].at, post.id); err != nil {
+			return fmt.Errorf("failed to assign queue slot: %v", err)
+		}
+	}
+	return nil
+}
+
+// RebalanceQueueNow runs RebalanceQueue in its own transaction
+func RebalanceQueueNow(db *sql.DB, workspaceID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if err := RebalanceQueue(tx, workspaceID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EnqueueScheduledPost appends a post to the end of its workspace queue and assigns slots,
+// returning the stored post with its queue position and scheduled time
+func EnqueueScheduledPost(db *sql.DB, userID, workspaceID string, req models.CreateScheduledPostRequest) (*models.ScheduledPost, error) {
+	targetsJSON, err := json.Marshal(req.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	threadJSON, err := EncodeThread(req.Thread)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize queue changes per workspace
+	if _, err := tx.Exec(`SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID); err != nil {
+		return nil, fmt.Errorf("failed to lock workspace queue: %v", err)
+	}
+
+	post := &models.ScheduledPost{
+		UserID:       userID,
+		Content:      req.Content,
+		MediaURLs:    pq.StringArray(req.MediaURLs),
+		Platforms:    pq.StringArray(req.Platforms),
+		Status:       models.StatusPending,
+		Targets:      req.Targets,
+		WorkspaceID:  &workspaceID,
+		Thread:       req.Thread,
+		MissedWindow: req.MissedWindow,
+	}
+	if req.TimeZone != "" {
+		post.TimeZone = &req.TimeZone
+	}
+	// The placeholder time is replaced by RebalanceQueue before the row becomes visible
+	err = tx.QueryRow(`
+		INSERT INTO scheduled_posts
+			(user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, workspace_id, queue_position, timezone, thread, missed_window_minutes)
+		VALUES ($1, $2, $3, $4, NOW(), $5, NOW(), NOW(), $6, $7,
+		        (SELECT COALESCE(MAX(queue_position), 0) + 1 FROM scheduled_posts
+		         WHERE workspace_id = $7 AND queue_position IS NOT NULL AND status = $5), $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`, userID, post.Content, post.MediaURLs, post.Platforms, post.Status, targetsJSON, workspaceID, post.TimeZone, threadJSON, post.MissedWindow).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue post: %v", err)
+	}
+
+	if err := RebalanceQueue(tx, workspaceID); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(`SELECT scheduled_time, queue_position FROM scheduled_posts WHERE id = $1`, post.ID).
+		Scan(&post.ScheduledTime, &post.QueuePosition); err != nil {
+		return nil, fmt.Errorf("failed to read queue assignment: %v", err)
+	}
+	LocalizeScheduledPost(post)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit queue: %v", err)
+	}
+	return post, nil
+}
+
+// ReorderQueue moves the given posts to the front of the queue in the given order (the
+// remaining queued posts keep their relative order after them) and reassigns slots
+func ReorderQueue(db *sql.DB, workspaceID string, postIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID); err != nil {
+		return fmt.Errorf("failed to lock workspace queue: %v", err)
+	}
+
+	// Push everything back, then number the requested posts from 1
+	if _, err := tx.Exec(`
+		UPDATE scheduled_posts SET queue_position = queue_position + $1
+		WHERE workspace_id = $2 AND queue_position IS NOT NULL AND status = $3
+	`, len(postIDs), workspaceID, models.StatusPending); err != nil {
+		return fmt.Errorf("failed to shift queue: %v", err)
+	}
+	for i, id := range postIDs {
+		res, err := tx.Exec(`
+			UPDATE scheduled_posts SET queue_position = $1
+			WHERE id = $2 AND workspace_id = $3 AND queue_position IS NOT NULL AND status = $4
+		`, i+1, id, workspaceID, models.StatusPending)
+		if err != nil {
+			return fmt.Errorf("failed to reorder queue: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("post %d is not in this workspace queue", id)
+		}
+	}
+
+	if err := RebalanceQueue(tx, workspaceID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListQueuedPosts returns the workspace's queued pending posts in queue order
+func ListQueuedPosts(db *sql.DB, workspaceID string) ([]models.ScheduledPost, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, content, media_urls, platforms, scheduled_time, status, queue_position, created_at, updated_at, targets, timezone
+		FROM scheduled_posts
+		WHERE workspace_id = $1 AND queue_position IS NOT NULL AND status = $2
+		ORDER BY queue_position
+	`, workspaceID, models.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queue: %v", err)
+	}
+	defer rows.Close()
+
+	posts := []models.ScheduledPost{}
+	for rows.Next() {
+		var post models.ScheduledPost
+		var rawTargets []byte
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.MediaURLs, &post.Platforms,
+			&post.ScheduledTime, &post.Status, &post.QueuePosition, &post.CreatedAt, &post.UpdatedAt, &rawTargets, &post.TimeZone); err != nil {
+			return nil, fmt.Errorf("failed to scan queued post: %v", err)
+		}
+		if len(rawTargets) > 0 {
+			json.Unmarshal(rawTargets, &post.Targets)
+		}
+		post.WorkspaceID = &workspaceID
+		LocalizeScheduledPost(&post)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
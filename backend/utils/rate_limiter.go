@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Burst requests at once, refilled at PerMinute
+type RateLimit struct {
+	PerMinute float64
+	Burst     int
+}
+
+// Platform-wide and per-account publish limits. They sit comfortably under the
+// documented API quotas so bulk schedules are spread out instead of rejected.
+var (
+	defaultPlatformRateLimit = RateLimit{PerMinute: 60, Burst: 10}
+	defaultAccountRateLimit  = RateLimit{PerMinute: 10, Burst: 3}
+
+	platformRateLimits = map[string]RateLimit{
+		"twitter":   {PerMinute: 10, Burst: 5},
+		"facebook":  {PerMinute: 30, Burst: 5},
+		"instagram": {PerMinute: 20, Burst: 5},
+		"youtube":   {PerMinute: 5, Burst: 2},
+		"telegram":  {PerMinute: 30, Burst: 10},
+	}
+	accountRateLimits = map[string]RateLimit{
+		"twitter":   {PerMinute: 3, Burst: 2},
+		"instagram": {PerMinute: 2, Burst: 2}, // paces bursts only; the 24h publishing quota is enforced by the API
+		"youtube":   {PerMinute: 1, Burst: 1},
+		"telegram":  {PerMinute: 20, Burst: 5},
+	}
+)
+
+// tokenBucket is a mutex-guarded token bucket limiter
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens per second
+	last     time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		capacity: burst,
+		tokens:   burst,
+		rate:     limit.PerMinute / 60,
+		last:     time.Now(),
+	}
+}
+
+// reserve takes a token if one is available, otherwise returns how long until one is
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Minute
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait blocks until a token is taken or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		delay := b.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+var (
+	rateBucketsMu sync.Mutex
+	rateBuckets   = map[string]*tokenBucket{}
+)
+
+// rateBucket returns the shared bucket for key, creating it with limit on first use
+func rateBucket(key string, limit RateLimit) *tokenBucket {
+	rateBucketsMu.Lock()
+	defer rateBucketsMu.Unlock()
+	b, ok := rateBuckets[key]
+	if !ok {
+		b = newTokenBucket(limit)
+		rateBuckets[key] = b
+	}
+	return b
+}
+
+// rateLimitFor looks up a limit and applies <PLATFORM>_<SCOPE>_RATE_PER_MINUTE and
+// <PLATFORM>_<SCOPE>_RATE_BURST overrides, e.g. TWITTER_ACCOUNT_RATE_PER_MINUTE=2
+func rateLimitFor(platform, scope string, limits map[string]RateLimit, fallback RateLimit) RateLimit {
+	limit, ok := limits[platform]
+	if !ok {
+		limit = fallback
+	}
+	prefix := strings.ToUpper(platform) + "_" + scope + "_RATE_"
+	if v, err := strconv.ParseFloat(os.Getenv(prefix+"PER_MINUTE"), 64); err == nil && v > 0 {
+		limit.PerMinute = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "BURST")); err == nil && v > 0 {
+		limit.Burst = v
+	}
+	return limit
+}
+
+// WaitForPublishSlot blocks until both the platform-wide and the account's token
+// bucket allow another publish, or ctx is cancelled
+func WaitForPublishSlot(ctx context.Context, platform, accountID string) error {
+	platformBucket := rateBucket(platform, rateLimitFor(platform, "PLATFORM", platformRateLimits, defaultPlatformRateLimit))
+	if err := platformBucket.wait(ctx); err != nil {
+		return err
+	}
+	accountBucket := rateBucket(platform+"|"+accountID, rateLimitFor(platform, "ACCOUNT", accountRateLimits, defaultAccountRateLimit))
+	return accountBucket.wait(ctx)
+}
@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Recurrence yields the occurrence times of a recurring post
+type Recurrence interface {
+	// Next returns the first occurrence strictly after t, or the zero time when there are no more
+	Next(t time.Time) time.Time
+}
+
+// maxRecurrencePeriods bounds how many FREQ periods Next scans before giving up
+const maxRecurrencePeriods = 100000
+
+// ParseRecurrence parses an iCalendar RRULE ("FREQ=WEEKLY;BYDAY=MO,WE", optionally prefixed
+// with "RRULE:") or a standard 5-field cron expression / descriptor ("0 9 * * 1", "@daily").
+// dtstart anchors the rule: RRULE occurrences keep its wall-clock time and both kinds are
+// evaluated in its location.
+func ParseRecurrence(rule string, dtstart time.Time) (Recurrence, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return nil, fmt.Errorf("recurrence rule is empty")
+	}
+	upper := strings.ToUpper(rule)
+	if strings.HasPrefix(upper, "RRULE:") || strings.Contains(upper, "FREQ=") {
+		return parseRRule(strings.TrimPrefix(upper, "RRULE:"), dtstart)
+	}
+
+	schedule, err := cron.ParseStandard(rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %v", err)
+	}
+	return cronRecurrence{schedule: schedule, loc: dtstart.Location()}, nil
+}
+
+// FirstOccurrence returns the first occurrence of rec at or after dtstart, or the zero time if
+// there is none. dtstart itself is only an occurrence when it matches the rule.
+func FirstOccurrence(rec Recurrence, dtstart time.Time) time.Time {
+	return rec.Next(dtstart.Add(-time.Second))
+}
+
+// RecurrenceCount returns the COUNT of an RRULE, or 0 when the rule has none (or is cron)
+func RecurrenceCount(rule string) int {
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:"), ";") {
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 && kv[0] == "COUNT" {
+			n, _ := strconv.Atoi(kv[1])
+			return n
+		}
+	}
+	return 0
+}
+
+// cronRecurrence adapts a robfig/cron schedule
+type cronRecurrence struct {
+	schedule cron.Schedule
+	loc      *time.Location
+}
+
+func (c cronRecurrence) Next(t time.Time) time.Time {
+	return c.schedule.Next(t.In(c.loc))
+}
+
+// rrule is the subset of RFC 5545 recurrence rules used for posting schedules:
+// FREQ (HOURLY..YEARLY), INTERVAL, BYDAY, BYMONTHDAY, BYHOUR, BYMINUTE, COUNT and UNTIL.
+// COUNT is enforced by the series, not by Next.
+type rrule struct {
+	freq       string
+	interval   int
+	byDay      []time.Weekday
+	byMonthDay []int
+	byHour     []int
+	byMinute   []int
+	until      time.Time
+	dtstart    time.Time
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRRule(rule string, dtstart time.Time) (*rrule, error) {
+	r := &rrule{interval: 1, dtstart: dtstart.Truncate(time.Minute)}
+	for _, part := range strings.Split(rule, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part: %s", part)
+		}
+		key, value := kv[0], kv[1]
+		switch key {
+		case "FREQ":
+			switch value {
+			case "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported RRULE FREQ: %s", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE INTERVAL: %s", value)
+			}
+			r.interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE BYDAY value: %s", d)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "BYMONTHDAY":
+			days, err := parseRRuleInts(value, -31, 31)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYMONTHDAY: %v", err)
+			}
+			r.byMonthDay = days
+		case "BYHOUR":
+			hours, err := parseRRuleInts(value, 0, 23)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYHOUR: %v", err)
+			}
+			r.byHour = hours
+		case "BYMINUTE":
+			minutes, err := parseRRuleInts(value, 0, 59)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYMINUTE: %v", err)
+			}
+			r.byMinute = minutes
+		case "UNTIL":
+			until, err := parseRRuleUntil(value, dtstart.Location())
+			if err != nil {
+				return nil, err
+			}
+			r.until = until
+		case "COUNT":
+			if n, err := strconv.Atoi(value); err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE COUNT: %s", value)
+			}
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part: %s", key)
+		}
+	}
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE requires FREQ")
+	}
+	// Without BYHOUR, HOURLY rules run every hour and the others at dtstart's hour
+	if len(r.byHour) == 0 && r.freq != "HOURLY" {
+		r.byHour = []int{r.dtstart.Hour()}
+	}
+	if len(r.byMinute) == 0 {
+		r.byMinute = []int{r.dtstart.Minute()}
+	}
+	return r, nil
+}
+
+func parseRRuleInts(value string, min, max int) ([]int, error) {
+	var out []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max || n == 0 && min < 0 {
+			return nil, fmt.Errorf("value out of range: %s", s)
+		}
+		out = append(out, n)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+func parseRRuleUntil(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		var t time.Time
+		var err error
+		if strings.HasSuffix(layout, "Z") {
+			t, err = time.Parse(layout, value)
+		} else {
+			t, err = time.ParseInLocation(layout, value, loc)
+		}
+		if err == nil {
+			if layout == "20060102" {
+				t = t.Add(24*time.Hour - time.Second) // inclusive of the whole day
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid RRULE UNTIL: %s", value)
+}
+
+// Next implements Recurrence by walking FREQ periods from dtstart and expanding each one
+func (r *rrule) Next(t time.Time) time.Time {
+	loc := r.dtstart.Location()
+	start := r.dtstart
+	for k := 0; k < maxRecurrencePeriods; k++ {
+		for _, candidate := range r.expand(start, k*r.interval, loc) {
+			if candidate.Before(r.dtstart) || !candidate.After(t) {
+				continue
+			}
+			if !r.until.IsZero() && candidate.After(r.until) {
+				return time.Time{}
+			}
+			return candidate
+		}
+		if !r.until.IsZero() && r.periodStart(start, k*r.interval, loc).After(r.until) {
+			return time.Time{}
+		}
+	}
+	return time.Time{}
+}
+
+// periodStart returns the first day (or hour) of the period offset periods after dtstart's
+func (r *rrule) periodStart(start time.Time, offset int, loc *time.Location) time.Time {
+	y, m, d := start.Date()
+	switch r.freq {
+	case "HOURLY":
+		return time.Date(y, m, d, start.Hour()+offset, start.Minute(), 0, 0, loc)
+	case "DAILY":
+		return time.Date(y, m, d+offset, 0, 0, 0, 0, loc)
+	case "WEEKLY":
+		// Monday of dtstart's week
+		back := (int(start.Weekday()) + 6) % 7
+		return time.Date(y, m, d-back+7*offset, 0, 0, 0, 0, loc)
+	case "MONTHLY":
+		return time.Date(y, m+time.Month(offset), 1, 0, 0, 0, 0, loc)
+	default: // YEARLY
+		return time.Date(y+offset, 1, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// expand lists the occurrences inside one period in chronological order.
+// Wall-clock times are built with time.Date so they stay fixed across DST changes.
+func (r *rrule) expand(start time.Time, offset int, loc *time.Location) []time.Time {
+	period := r.periodStart(start, offset, loc)
+	y, m, d := period.Date()
+
+	if r.freq == "HOURLY" {
+		// BYDAY, BYMONTHDAY and BYHOUR limit which hours are used; BYMINUTE expands each one
+		if len(r.byDay) > 0 && !containsWeekday(r.byDay, period.Weekday()) {
+			return nil
+		}
+		if len(r.byMonthDay) > 0 && !r.matchesMonthDay(period) {
+			return nil
+		}
+		if len(r.byHour) > 0 && !containsInt(r.byHour, period.Hour()) {
+			return nil
+		}
+		out := make([]time.Time, 0, len(r.byMinute))
+		for _, min := range r.byMinute {
+			out = append(out, time.Date(y, m, d, period.Hour(), min, 0, 0, loc))
+		}
+		return out
+	}
+
+	var days []time.Time
+	switch r.freq {
+	case "DAILY":
+		if len(r.byDay) == 0 || containsWeekday(r.byDay, period.Weekday()) {
+			days = append(days, period)
+		}
+	case "WEEKLY":
+		weekdays := r.byDay
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{r.dtstart.Weekday()}
+		}
+		for i := 0; i < 7; i++ {
+			day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+			if containsWeekday(weekdays, day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		days = r.monthDays(y, m, loc)
+	case "YEARLY":
+		days = r.monthDays(y, r.dtstart.Month(), loc)
+	}
+
+	var out []time.Time
+	for _, day := range days {
+		dy, dm, dd := day.Date()
+		for _, h := range r.byHour {
+			for _, min := range r.byMinute {
+				out = append(out, time.Date(dy, dm, dd, h, min, 0, 0, loc))
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// monthDays returns the matching days of one month; days that don't exist (e.g. the 31st) are skipped
+func (r *rrule) monthDays(y int, m time.Month, loc *time.Location) []time.Time {
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, loc).Day()
+	var days []time.Time
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, md := range r.byMonthDay {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md >= 1 && md <= last {
+				days = append(days, time.Date(y, m, md, 0, 0, 0, 0, loc))
+			}
+		}
+	case len(r.byDay) > 0:
+		for md := 1; md <= last; md++ {
+			day := time.Date(y, m, md, 0, 0, 0, 0, loc)
+			if containsWeekday(r.byDay, day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+	default:
+		if md := r.dtstart.Day(); md <= last {
+			days = append(days, time.Date(y, m, md, 0, 0, 0, 0, loc))
+		}
+	}
+	return days
+}
+
+// matchesMonthDay reports whether day is one of BYMONTHDAY's days, counting negative ones from the month's end
+func (r *rrule) matchesMonthDay(day time.Time) bool {
+	y, m, d := day.Date()
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, md := range r.byMonthDay {
+		if md == d || md < 0 && last+md+1 == d {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
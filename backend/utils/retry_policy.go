@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how often and how quickly a failed delivery is retried
+type RetryPolicy struct {
+	MaxAttempts int           // total publish attempts per target, including the first
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // upper bound for the exponential delay
+	Jitter      float64       // fraction of the delay randomised in either direction (0-1)
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Minute,
+	MaxDelay:    30 * time.Minute,
+	Jitter:      0.2,
+}
+
+var (
+	retryPoliciesMu sync.RWMutex
+	// Platform defaults; rate-limited APIs back off longer
+	retryPolicies = map[string]RetryPolicy{
+		"twitter":   {MaxAttempts: 4, BaseDelay: 5 * time.Minute, MaxDelay: time.Hour, Jitter: 0.2},
+		"instagram": {MaxAttempts: 4, BaseDelay: 2 * time.Minute, MaxDelay: 30 * time.Minute, Jitter: 0.2},
+		"youtube":   {MaxAttempts: 3, BaseDelay: 10 * time.Minute, MaxDelay: 2 * time.Hour, Jitter: 0.2},
+	}
+)
+
+// SetRetryPolicy overrides the retry policy of a platform
+func SetRetryPolicy(platform string, policy RetryPolicy) {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+	retryPolicies[platform] = policy
+}
+
+// RetryPolicyFor returns the retry policy of a platform. Each field can be overridden
+// with <PLATFORM>_RETRY_MAX_ATTEMPTS, <PLATFORM>_RETRY_BASE_DELAY and <PLATFORM>_RETRY_MAX_DELAY,
+// e.g. TWITTER_RETRY_BASE_DELAY=10m.
+func RetryPolicyFor(platform string) RetryPolicy {
+	retryPoliciesMu.RLock()
+	policy, ok := retryPolicies[platform]
+	retryPoliciesMu.RUnlock()
+	if !ok {
+		policy = defaultRetryPolicy
+	}
+
+	prefix := strings.ToUpper(platform) + "_RETRY_"
+	if v, err := strconv.Atoi(os.Getenv(prefix + "MAX_ATTEMPTS")); err == nil && v > 0 {
+		policy.MaxAttempts = v
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "BASE_DELAY")); err == nil && d > 0 {
+		policy.BaseDelay = d
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "MAX_DELAY")); err == nil && d > 0 {
+		policy.MaxDelay = d
+	}
+	return policy
+}
+
+// CanRetry reports whether a target that has made attempts publish attempts may be tried again
+func (p RetryPolicy) CanRetry(attempts int) bool {
+	return attempts < p.MaxAttempts
+}
+
+// Backoff returns the delay before the next attempt after attempts failures:
+// BaseDelay * 2^(attempts-1), capped at MaxDelay, with ±Jitter applied
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempts-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
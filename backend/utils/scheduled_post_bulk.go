@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// MaxBulkPosts caps the number of posts one bulk operation may touch
+const MaxBulkPosts = 500
+
+// ErrPostChanged is returned when a post left the status an operation expects while it ran
+var ErrPostChanged = errors.New("scheduled post changed status; reload it and try again")
+
+// bulkPostColumns are the scheduled_posts columns scanned by scanBulkPost
+const bulkPostColumns = `id, user_id, content, media_urls, platforms, scheduled_time, status, retry_count,
+	error_message, created_at, updated_at, targets, series_id, occurrence_at, timezone, workspace_id::text,
+	queue_position, thread, missed_window_minutes, missed_at`
+
+// SelectBulkPosts loads the posts a bulk operation applies to: the given IDs, in order, or
+// every post of the filter's workspace matching it (pending ones unless the filter names a
+// status), oldest first. Whether the user may change each post is left to the caller.
+func SelectBulkPosts(db *sql.DB, ids []int, filter *models.BulkPostFilter) ([]models.ScheduledPost, error) {
+	var query string
+	var args []interface{}
+	if len(ids) > 0 {
+		query = `SELECT ` + bulkPostColumns + ` FROM scheduled_posts
+			WHERE id = ANY($1) ORDER BY array_position($1::int[], id)`
+		args = []interface{}{pq.Array(ids)}
+	} else {
+		if filter == nil {
+			return nil, fmt.Errorf("ids or filter is required")
+		}
+		if filter.WorkspaceID == "" {
+			return nil, fmt.Errorf("filter.workspace_id is required")
+		}
+		status := filter.Status
+		if status == "" {
+			status = models.StatusPending
+		}
+		args = []interface{}{status, filter.WorkspaceID}
+		conds := []string{"status = $1", "workspace_id = $2"}
+		add := func(cond string, arg interface{}) {
+			args = append(args, arg)
+			conds = append(conds, fmt.Sprintf(cond, len(args)))
+		}
+		if filter.Platform != "" {
+			add("$%d = ANY(platforms)", filter.Platform)
+		}
+		if filter.AccountID != "" {
+			add(`EXISTS (SELECT 1 FROM jsonb_each(COALESCE(targets, '{}'::jsonb)) t
+				WHERE jsonb_typeof(t.value->'ids') = 'array' AND t.value->'ids' ? $%d)`, filter.AccountID)
+		}
+		if filter.From != nil {
+			add("scheduled_time >= $%d", *filter.From)
+		}
+		if filter.To != nil {
+			add("scheduled_time < $%d", *filter.To)
+		}
+		query = `SELECT ` + bulkPostColumns + ` FROM scheduled_posts
+			WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY scheduled_time, id`
+	}
+	query += fmt.Sprintf(" LIMIT %d", MaxBulkPosts+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select scheduled posts: %v", err)
+	}
+	defer rows.Close()
+
+	posts := []models.ScheduledPost{}
+	for rows.Next() {
+		var post models.ScheduledPost
+		var rawTargets, rawThread []byte
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.MediaURLs, &post.Platforms, &post.ScheduledTime,
+			&post.Status, &post.RetryCount, &post.ErrorMessage, &post.CreatedAt, &post.UpdatedAt, &rawTargets,
+			&post.SeriesID, &post.OccurrenceAt, &post.TimeZone, &post.WorkspaceID, &post.QueuePosition, &rawThread,
+			&post.MissedWindow, &post.MissedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled post: %v", err)
+		}
+		if len(rawTargets) > 0 {
+			json.Unmarshal(rawTargets, &post.Targets)
+		}
+		post.Thread = DecodeThread(rawThread)
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read scheduled posts: %v", err)
+	}
+	if len(posts) > MaxBulkPosts {
+		return nil, fmt.Errorf("a bulk operation can change at most %d posts; narrow the selection", MaxBulkPosts)
+	}
+	return posts, nil
+}
+
+// CheckBulkTime validates the time fields of a bulk request: at most one of scheduled_time,
+// local_time and offset_minutes, and one of them when required is set
+func CheckBulkTime(req models.BulkPostRequest, required bool) error {
+	given := 0
+	if req.ScheduledTime != nil {
+		given++
+	}
+	if req.LocalTime != "" {
+		given++
+	}
+	if req.OffsetMinutes != nil {
+		given++
+	}
+	if given > 1 {
+		return fmt.Errorf("use only one of scheduled_time, local_time and offset_minutes")
+	}
+	if given == 0 && required {
+		return fmt.Errorf("scheduled_time, local_time or offset_minutes is required")
+	}
+	if req.TimeZone != "" {
+		if _, err := LoadTimeZone(req.TimeZone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkPostTime works out the new time of one post from a bulk request (see CheckBulkTime). An
+// offset moves the post's wall-clock time in its own zone, so a post at 09:00 stays at 09:00
+// when shifted by whole days across a DST change. Without any time the post keeps its own.
+func BulkPostTime(db *sql.DB, userID string, post models.ScheduledPost, req models.BulkPostRequest) (time.Time, error) {
+	zone := req.TimeZone
+	if zone == "" && post.TimeZone != nil {
+		zone = *post.TimeZone
+	}
+	switch {
+	case req.ScheduledTime != nil:
+		return *req.ScheduledTime, nil
+	case req.LocalTime != "":
+		_, loc, err := ResolveTimeZone(db, userID, post.WorkspaceID, zone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return ParseLocalTime(req.LocalTime, loc)
+	case req.OffsetMinutes != nil:
+		loc := time.UTC
+		if post.TimeZone != nil {
+			if l, err := LoadTimeZone(*post.TimeZone); err == nil {
+				loc = l
+			}
+		}
+		wall := post.ScheduledTime.In(loc)
+		shifted := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute()+*req.OffsetMinutes,
+			wall.Second(), wall.Nanosecond(), loc)
+		return shifted, nil
+	}
+	return post.ScheduledTime, nil
+}
+
+// RescheduleScheduledPost moves a pending post to at. A queued post leaves its queue, and the
+// posts behind it move up.
+func RescheduleScheduledPost(db *sql.DB, post *models.ScheduledPost, at time.Time) error {
+	res, err := db.Exec(`
+		UPDATE scheduled_posts SET scheduled_time = $1, queue_position = NULL, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+	`, at, post.ID, models.StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule post: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPostChanged
+	}
+
+	leftQueue := post.QueuePosition != nil
+	post.ScheduledTime = at
+	post.QueuePosition = nil
+	if leftQueue && post.WorkspaceID != nil {
+		if err := RebalanceQueueNow(db, *post.WorkspaceID); err != nil {
+			log.Printf("Failed to rebalance queue of workspace %s: %v", *post.WorkspaceID, err)
+		}
+	}
+	return nil
+}
+
+// CancelScheduledPost cancels a pending or failed post. Its draft goes back to being a draft,
+// a queue closes the gap it leaves, and a recurring series moves on to its next occurrence.
+func CancelScheduledPost(db *sql.DB, post *models.ScheduledPost) error {
+	res, err := db.Exec(`
+		UPDATE scheduled_posts SET status = $1, updated_at = NOW() WHERE id = $2 AND status IN ($3, $4)
+	`, models.StatusCancelled, post.ID, models.StatusPending, models.StatusFailed)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled post: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPostChanged
+	}
+	post.Status = models.StatusCancelled
+
+	SyncDraftWithPost(db, post.ID)
+	if post.WorkspaceID != nil && post.QueuePosition != nil {
+		if err := RebalanceQueueNow(db, *post.WorkspaceID); err != nil {
+			log.Printf("Failed to rebalance queue of workspace %s: %v", *post.WorkspaceID, err)
+		}
+	}
+	if post.SeriesID != nil {
+		if _, err := MaterializeNextOccurrence(db, *post.SeriesID); err != nil {
+			log.Printf("Failed to materialize next occurrence of series %d: %v", *post.SeriesID, err)
+		}
+	}
+	return nil
+}
+
+// DeleteScheduledPost removes a post for good, with its delivery records. A pending or failed
+// post is cancelled first so its draft, queue and series are left consistent.
+func DeleteScheduledPost(db *sql.DB, post *models.ScheduledPost) error {
+	if post.Status != models.StatusCancelled {
+		if err := CancelScheduledPost(db, post); err != nil {
+			return err
+		}
+	}
+	res, err := db.Exec(`DELETE FROM scheduled_posts WHERE id = $1 AND status = $2`, post.ID, models.StatusCancelled)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled post: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPostChanged
+	}
+	return nil
+}
+
+// DuplicateScheduledPost schedules a copy of post at at, as a new pending post of the same
+// author and workspace. The copy is independent: it has no queue slot, series or draft.
+func DuplicateScheduledPost(db *sql.DB, post models.ScheduledPost, at time.Time) (*models.ScheduledPost, error) {
+	targetsJSON, err := json.Marshal(post.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	threadJSON, err := EncodeThread(post.Thread)
+	if err != nil {
+		return nil, err
+	}
+
+	dup := models.ScheduledPost{
+		UserID:        post.UserID,
+		Content:       post.Content,
+		MediaURLs:     post.MediaURLs,
+		Platforms:     post.Platforms,
+		ScheduledTime: at,
+		Status:        models.StatusPending,
+		Targets:       post.Targets,
+		TimeZone:      post.TimeZone,
+		WorkspaceID:   post.WorkspaceID,
+		Thread:        post.Thread,
+		MissedWindow:  post.MissedWindow,
+	}
+	err = db.QueryRow(`
+		INSERT INTO scheduled_posts (user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, timezone, workspace_id, thread, missed_window_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at
+	`, dup.UserID, dup.Content, dup.MediaURLs, dup.Platforms, dup.ScheduledTime, dup.Status, targetsJSON,
+		dup.TimeZone, dup.WorkspaceID, threadJSON, dup.MissedWindow).Scan(&dup.ID, &dup.CreatedAt, &dup.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to duplicate scheduled post: %v", err)
+	}
+	return &dup, nil
+}
+
+// RetargetScheduledPost replaces the platforms and targets of a pending post. They are checked
+// against each platform's rules first; a failing report is returned with no change made.
+func RetargetScheduledPost(db *sql.DB, post *models.ScheduledPost, platforms []string, targets map[string]interface{}) (*models.ValidationResult, error) {
+	for _, platform := range platforms {
+		if !IsSupportedPlatform(platform) {
+			return nil, fmt.Errorf("invalid platform: %s", platform)
+		}
+	}
+	report := ValidateScheduledPost(db, ValidationInput{
+		Content:   post.Content,
+		MediaURLs: post.MediaURLs,
+		Platforms: platforms,
+		Targets:   targets,
+		Thread:    post.Thread,
+	})
+	if !report.Valid {
+		return &report, fmt.Errorf("post does not meet the rules of every target platform")
+	}
+	targets, err := NormalizeTargets(targets, post.MediaURLs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	targetsJSON, err := json.Marshal(targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+
+	res, err := db.Exec(`
+		UPDATE scheduled_posts SET platforms = $1, targets = $2, updated_at = NOW() WHERE id = $3 AND status = $4
+	`, pq.Array(platforms), targetsJSON, post.ID, models.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retarget post: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrPostChanged
+	}
+	post.Platforms = pq.StringArray(platforms)
+	post.Targets = targets
+	for _, target := range report.Targets {
+		if len(target.Warnings) > 0 {
+			return &report, nil
+		}
+	}
+	return nil, nil
+}
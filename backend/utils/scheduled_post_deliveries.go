@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// noAccountUUID stands in for a NULL social_account_id in the deliveries unique index
+const noAccountUUID = "00000000-0000-0000-0000-000000000000"
+
+// GetScheduledPostDeliveries returns the per-target delivery rows of a scheduled post
+func GetScheduledPostDeliveries(db *sql.DB, postID int) ([]models.ScheduledPostDelivery, error) {
+	rows, err := db.Query(`
+		SELECT id, scheduled_post_id, platform, social_account_id::text, account_name, status, attempts,
+		       remote_post_id, permalink, last_error, next_attempt_at, delivered_at, created_at, updated_at,
+		       first_comment_status, first_comment_id, first_comment_error, first_comment_at, published_parts
+		FROM scheduled_post_deliveries
+		WHERE scheduled_post_id = $1
+		ORDER BY platform, id
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.ScheduledPostDelivery{}
+	for rows.Next() {
+		var d models.ScheduledPostDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.ScheduledPostID,
+			&d.Platform,
+			&d.SocialAccountID,
+			&d.AccountName,
+			&d.Status,
+			&d.Attempts,
+			&d.RemotePostID,
+			&d.Permalink,
+			&d.LastError,
+			&d.NextAttemptAt,
+			&d.DeliveredAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+			&d.FirstCommentStatus,
+			&d.FirstCommentID,
+			&d.FirstCommentError,
+			&d.FirstCommentAt,
+			&d.PublishedParts,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %v", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := attachThreadParts(db, postID, deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// recordDelivery stores the outcome of one publish attempt for a platform/account target.
+// account may be nil when the platform could not be resolved to any connected account.
+// A failed target is given a next_attempt_at from the platform's retry policy while it
+// still has attempts left.
+func (spp *ScheduledPostProcessor) recordDelivery(postID int, platform string, account *PublishAccount, result *PublishResult, pubErr error) {
+	var accountID, accountName, remoteID, permalink, lastError *string
+	var deliveredAt *time.Time
+	var parts interface{}
+	status := models.StatusPosted
+
+	if account != nil {
+		accountID = &account.ID
+		if account.DisplayName != "" {
+			accountName = &account.DisplayName
+		}
+	}
+	if result != nil {
+		if result.RemotePostID != "" {
+			remoteID = &result.RemotePostID
+		}
+		if result.Permalink != "" {
+			permalink = &result.Permalink
+		}
+		if len(result.Parts) > 0 {
+			parts = pq.Array(result.Parts)
+		}
+	}
+	if pubErr != nil {
+		status = models.StatusFailed
+		msg := pubErr.Error()
+		lastError = &msg
+	} else {
+		now := time.Now()
+		deliveredAt = &now
+	}
+
+	var deliveryID, attempts int
+	err := spp.db.QueryRow(`
+		INSERT INTO scheduled_post_deliveries
+			(scheduled_post_id, platform, social_account_id, account_name, status, attempts,
+			 remote_post_id, permalink, last_error, next_attempt_at, delivered_at, published_parts, created_at, updated_at)
+		VALUES ($1, $2, $3::uuid, $4, $5, 1, $6, $7, $8, NULL, $9, $10, NOW(), NOW())
+		ON CONFLICT (scheduled_post_id, platform, (COALESCE(social_account_id, '`+noAccountUUID+`'::uuid)))
+		DO UPDATE SET
+			account_name = COALESCE(EXCLUDED.account_name, scheduled_post_deliveries.account_name),
+			status = EXCLUDED.status,
+			attempts = scheduled_post_deliveries.attempts + 1,
+			remote_post_id = COALESCE(EXCLUDED.remote_post_id, scheduled_post_deliveries.remote_post_id),
+			permalink = COALESCE(EXCLUDED.permalink, scheduled_post_deliveries.permalink),
+			last_error = EXCLUDED.last_error,
+			next_attempt_at = NULL,
+			delivered_at = COALESCE(EXCLUDED.delivered_at, scheduled_post_deliveries.delivered_at),
+			published_parts = COALESCE(EXCLUDED.published_parts, scheduled_post_deliveries.published_parts),
+			updated_at = NOW()
+		RETURNING id, attempts
+	`, postID, platform, accountID, accountName, status, remoteID, permalink, lastError, deliveredAt, parts).Scan(&deliveryID, &attempts)
+	if err != nil {
+		log.Printf("Failed to record %s delivery for post %d: %v", platform, postID, err)
+		return
+	}
+
+	if pubErr == nil {
+		return
+	}
+	policy := RetryPolicyFor(platform)
+	if !policy.CanRetry(attempts) {
+		log.Printf("Delivery %d (%s) for post %d failed after %d attempts, giving up", deliveryID, platform, postID, attempts)
+		return
+	}
+	next := time.Now().Add(policy.Backoff(attempts))
+	if _, err := spp.db.Exec(`UPDATE scheduled_post_deliveries SET next_attempt_at = $1 WHERE id = $2`, next, deliveryID); err != nil {
+		log.Printf("Failed to schedule retry of delivery %d: %v", deliveryID, err)
+		return
+	}
+	log.Printf("Delivery %d (%s) for post %d failed (attempt %d/%d), retrying at %s",
+		deliveryID, platform, postID, attempts, policy.MaxAttempts, next.Format(time.RFC3339))
+}
+
+// postFirstComment posts the target's first_comment under a freshly published post and stores
+// the outcome on the account's delivery row. A failed comment never fails the delivery.
+func (spp *ScheduledPostProcessor) postFirstComment(ctx context.Context, post models.ScheduledPost, platform string, target models.PostTarget, account PublishAccount, remotePostID string) {
+	id := account.ID
+	comment := ResolveVariant(post.Content, post.MediaURLs, target, platform, &id).FirstComment
+	if comment == "" {
+		return
+	}
+	publisher, err := GetPublisher(platform)
+	if err != nil {
+		return
+	}
+	commenter, ok := publisher.(CommentPublisher)
+	if !ok {
+		log.Printf("Publisher: %s has no comments, skipping first comment of post %d", platform, post.ID)
+		return
+	}
+
+	var result *PublishResult
+	if err = WaitForPublishSlot(ctx, platform, account.ID); err == nil {
+		result, err = commenter.PublishComment(ctx, account, remotePostID, comment)
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	status := models.StatusPosted
+	var commentID, lastError *string
+	var postedAt *time.Time
+	if err != nil {
+		log.Printf("Publisher: first comment on %s post %s failed: %v", platform, remotePostID, err)
+		status = models.StatusFailed
+		msg := err.Error()
+		lastError = &msg
+	} else {
+		now := time.Now()
+		postedAt = &now
+		commentID = &result.RemotePostID
+	}
+	_, dbErr := spp.db.Exec(`
+		UPDATE scheduled_post_deliveries
+		SET first_comment_status = $1, first_comment_id = $2, first_comment_error = $3, first_comment_at = $4, updated_at = NOW()
+		WHERE scheduled_post_id = $5 AND platform = $6 AND social_account_id = $7::uuid
+	`, status, commentID, lastError, postedAt, post.ID, platform, account.ID)
+	if dbErr != nil {
+		log.Printf("Failed to record first comment of %s delivery for post %d: %v", platform, post.ID, dbErr)
+	}
+}
+
+// deliveryKey identifies a target within a post; platform-level rows use noAccountUUID
+func deliveryKey(platform string, accountID *string) string {
+	if accountID == nil {
+		return platform + "|" + noAccountUUID
+	}
+	return platform + "|" + *accountID
+}
+
+// deliveryDue reports whether a target with an existing delivery row should be attempted now:
+// never again once posted (or unpublished) or out of attempts, otherwise once its backoff has passed
+func deliveryDue(d models.ScheduledPostDelivery, now time.Time) bool {
+	switch d.Status {
+	case models.StatusPosted, models.StatusUnpublished:
+		return false
+	case models.StatusFailed:
+		if !RetryPolicyFor(d.Platform).CanRetry(d.Attempts) {
+			return false
+		}
+		return d.NextAttemptAt == nil || !d.NextAttemptAt.After(now)
+	}
+	return true
+}
+
+// clearUnresolvedDelivery removes a platform-level failure row once accounts resolve again
+func (spp *ScheduledPostProcessor) clearUnresolvedDelivery(postID int, platform string) {
+	_, err := spp.db.Exec(`
+		DELETE FROM scheduled_post_deliveries
+		WHERE scheduled_post_id = $1 AND platform = $2 AND social_account_id IS NULL
+	`, postID, platform)
+	if err != nil {
+		log.Printf("Failed to clear unresolved %s delivery for post %d: %v", platform, postID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"social-sync-backend/models"
+)
+
+// ErrNothingPublished is returned when a post has no delivered target to change
+var ErrNothingPublished = errors.New("scheduled post has no published targets")
+
+// EditPublishedPost pushes new content and targets to every delivered target of post (the
+// stored version) whose platform allows edits, and records the edit in the post's history.
+// Only the main post of a thread is edited. The stored post takes the new content once at
+// least one target was edited, so it keeps describing what is live.
+func EditPublishedPost(ctx context.Context, db *sql.DB, post models.ScheduledPost, content string, targets map[string]interface{}, editedBy string) (*models.PostEdit, error) {
+	deliveries, err := GetScheduledPostDeliveries(db, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParseTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &models.PostEdit{
+		ScheduledPostID: post.ID,
+		EditedBy:        &editedBy,
+		PreviousContent: post.Content,
+		Content:         content,
+		PreviousTargets: post.Targets,
+		Targets:         targets,
+		Results:         []models.PostEditResult{},
+	}
+	edited := false
+	for _, d := range deliveries {
+		if d.Status != models.StatusPosted || d.RemotePostID == nil || d.SocialAccountID == nil {
+			continue
+		}
+		variant := ResolveVariant(content, post.MediaURLs, parsed[d.Platform], d.Platform, d.SocialAccountID)
+		req := variantPublishRequest(variant, parsed[d.Platform])
+		result := editDelivery(ctx, db, d, post.UserID, req)
+		edited = edited || result.Status == models.EditApplied
+		edit.Results = append(edit.Results, result)
+	}
+	if len(edit.Results) == 0 {
+		return nil, ErrNothingPublished
+	}
+
+	targetsJSON, err := json.Marshal(targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	if edited {
+		if _, err := db.Exec(`
+			UPDATE scheduled_posts SET content = $1, targets = $2, updated_at = NOW() WHERE id = $3
+		`, content, targetsJSON, post.ID); err != nil {
+			return nil, fmt.Errorf("failed to save edited post: %v", err)
+		}
+	}
+
+	previousJSON, err := json.Marshal(post.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	resultsJSON, err := json.Marshal(edit.Results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode edit results: %v", err)
+	}
+	err = db.QueryRow(`
+		INSERT INTO scheduled_post_edits
+			(scheduled_post_id, edited_by, previous_content, content, previous_targets, targets, results)
+		VALUES ($1, $2::uuid, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, post.ID, editedBy, post.Content, content, previousJSON, targetsJSON, resultsJSON).Scan(&edit.ID, &edit.CreatedAt)
+	if err != nil {
+		// The platforms already changed; losing the history entry must not hide that
+		log.Printf("Failed to record edit of scheduled post %d: %v", post.ID, err)
+	}
+	return edit, nil
+}
+
+// editDelivery pushes req to one delivered target
+func editDelivery(ctx context.Context, db *sql.DB, d models.ScheduledPostDelivery, ownerID string, req PublishRequest) models.PostEditResult {
+	result := models.PostEditResult{
+		DeliveryID:      d.ID,
+		Platform:        d.Platform,
+		SocialAccountID: d.SocialAccountID,
+		AccountName:     d.AccountName,
+		RemotePostID:    d.RemotePostID,
+	}
+	fail := func(status string, err error) models.PostEditResult {
+		result.Status = status
+		msg := err.Error()
+		result.Error = &msg
+		return result
+	}
+
+	publisher, err := GetPublisher(d.Platform)
+	if err != nil {
+		return fail(models.EditImmutable, err)
+	}
+	editor, ok := publisher.(EditPublisher)
+	if !ok {
+		return fail(models.EditImmutable, fmt.Errorf("%s posts cannot be changed once published", d.Platform))
+	}
+	accounts, err := ResolvePublishAccounts(db, ownerID, d.Platform, []string{*d.SocialAccountID}, false)
+	if err != nil {
+		return fail(models.EditFailed, err)
+	}
+	if len(accounts) == 0 {
+		return fail(models.EditFailed, fmt.Errorf("the %s account is no longer connected", d.Platform))
+	}
+
+	err = WaitForPublishSlot(ctx, d.Platform, accounts[0].ID)
+	if err == nil {
+		err = editor.EditPost(ctx, accounts[0], *d.RemotePostID, req)
+	}
+	if err != nil {
+		log.Printf("Edit: %s post %s failed: %v", d.Platform, *d.RemotePostID, err)
+		return fail(models.EditFailed, err)
+	}
+	result.Status = models.EditApplied
+	return result
+}
+
+// GetPostEdits returns the edit history of a published post, newest first
+func GetPostEdits(db *sql.DB, postID int) ([]models.PostEdit, error) {
+	rows, err := db.Query(`
+		SELECT id, scheduled_post_id, edited_by::text, previous_content, content,
+		       previous_targets, targets, results, created_at
+		FROM scheduled_post_edits
+		WHERE scheduled_post_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edit history: %v", err)
+	}
+	defer rows.Close()
+
+	edits := []models.PostEdit{}
+	for rows.Next() {
+		var e models.PostEdit
+		var previousTargets, targets, results []byte
+		if err := rows.Scan(&e.ID, &e.ScheduledPostID, &e.EditedBy, &e.PreviousContent, &e.Content,
+			&previousTargets, &targets, &results, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %v", err)
+		}
+		if len(previousTargets) > 0 {
+			json.Unmarshal(previousTargets, &e.PreviousTargets)
+		}
+		if len(targets) > 0 {
+			json.Unmarshal(targets, &e.Targets)
+		}
+		if err := json.Unmarshal(results, &e.Results); err != nil {
+			log.Printf("Failed to decode results of edit %d: %v", e.ID, err)
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"social-sync-backend/models"
+)
+
+// maxMissedWindow caps a staleness window at one week, in minutes
+const maxMissedWindow = 7 * 24 * 60
+
+// ErrPostNotMissed is returned when resolving a post that is not (or no longer) missed
+var ErrPostNotMissed = errors.New("scheduled post is not missed")
+
+var (
+	missedNotifiersMu sync.RWMutex
+	missedNotifiers   []func(models.ScheduledPost)
+)
+
+// OnScheduledPostMissed registers fn to be told about every post the processor marks missed.
+// It lets packages that own a notification channel (such as the WebSocket hubs) subscribe.
+func OnScheduledPostMissed(fn func(models.ScheduledPost)) {
+	missedNotifiersMu.Lock()
+	defer missedNotifiersMu.Unlock()
+	missedNotifiers = append(missedNotifiers, fn)
+}
+
+// CheckMissedWindow validates a staleness window in minutes
+func CheckMissedWindow(minutes int) error {
+	if minutes < 1 || minutes > maxMissedWindow {
+		return fmt.Errorf("missed_window_minutes must be between 1 and %d", maxMissedWindow)
+	}
+	return nil
+}
+
+// markMissedPosts moves due pending posts that are past their staleness window (the post's,
+// else its workspace's) to missed instead of letting them be claimed. Only posts that were
+// never attempted can be missed; a post retrying failed targets keeps retrying. Posts of a
+// paused workspace are left alone until it is resumed.
+func (spp *ScheduledPostProcessor) markMissedPosts(now time.Time) {
+	rows, err := spp.db.Query(`
+		UPDATE scheduled_posts sp
+		SET status = $2, missed_at = $1, queue_position = NULL, updated_at = $1
+		WHERE sp.status = $3 AND sp.scheduled_time <= $1 AND sp.next_attempt_at IS NULL
+		  AND sp.scheduled_time + make_interval(mins => COALESCE(sp.missed_window_minutes,
+		        (SELECT w.missed_window_minutes FROM workspaces w WHERE w.id = sp.workspace_id))) < $1
+		  AND NOT EXISTS (SELECT 1 FROM scheduled_post_deliveries d WHERE d.scheduled_post_id = sp.id)
+		  AND NOT EXISTS (SELECT 1 FROM publishing_pauses pp
+		                  WHERE pp.workspace_id = sp.workspace_id AND pp.scope = 'workspace' AND pp.resumed_at IS NULL)
+		RETURNING sp.id, sp.user_id, sp.content, sp.platforms, sp.scheduled_time, sp.timezone,
+		          sp.workspace_id::text, sp.series_id, sp.missed_window_minutes, sp.missed_at
+	`, now, models.StatusMissed, models.StatusPending)
+	if err != nil {
+		log.Printf("Error marking missed scheduled posts: %v", err)
+		return
+	}
+
+	var missed []models.ScheduledPost
+	for rows.Next() {
+		var post models.ScheduledPost
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Content, &post.Platforms, &post.ScheduledTime, &post.TimeZone,
+			&post.WorkspaceID, &post.SeriesID, &post.MissedWindow, &post.MissedAt); err != nil {
+			log.Printf("Error scanning missed scheduled post: %v", err)
+			continue
+		}
+		post.Status = models.StatusMissed
+		LocalizeScheduledPost(&post)
+		missed = append(missed, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading missed scheduled posts: %v", err)
+	}
+
+	rebalanced := map[string]bool{}
+	for _, post := range missed {
+		log.Printf("Scheduled post %d missed its schedule (due %s, now %s)", post.ID,
+			post.ScheduledTime.Format(time.RFC3339), now.Format(time.RFC3339))
+
+		// A missed post may have held a queue slot; the posts behind it move up
+		if post.WorkspaceID != nil && !rebalanced[*post.WorkspaceID] {
+			rebalanced[*post.WorkspaceID] = true
+			if err := RebalanceQueueNow(spp.db, *post.WorkspaceID); err != nil {
+				log.Printf("Failed to rebalance queue of workspace %s: %v", *post.WorkspaceID, err)
+			}
+		}
+		// A missed occurrence doesn't hold up the rest of its series
+		if post.SeriesID != nil {
+			if _, err := MaterializeNextOccurrence(spp.db, *post.SeriesID); err != nil {
+				log.Printf("Failed to materialize next occurrence of series %d: %v", *post.SeriesID, err)
+			}
+		}
+		spp.notifyMissed(post)
+	}
+}
+
+// notifyMissed tells the registered notifiers about a missed post and emails its author
+func (spp *ScheduledPostProcessor) notifyMissed(post models.ScheduledPost) {
+	missedNotifiersMu.RLock()
+	notifiers := append([]func(models.ScheduledPost){}, missedNotifiers...)
+	missedNotifiersMu.RUnlock()
+	for _, notify := range notifiers {
+		notify(post)
+	}
+
+	var email string
+	if err := spp.db.QueryRow(`SELECT email FROM users WHERE id = $1`, post.UserID).Scan(&email); err != nil {
+		log.Printf("Failed to look up author of missed post %d: %v", post.ID, err)
+		return
+	}
+	scheduledAt := post.ScheduledTime.Format(time.RFC3339)
+	if post.LocalTime != "" {
+		scheduledAt = post.LocalTime
+	}
+	go SendMissedPostEmail(email, post.Content, scheduledAt)
+}
+
+// ResolveMissedPost applies a member's decision to a missed post: publish_now and reschedule
+// put it back in line for the processor at scheduledTime, discard cancels it
+func ResolveMissedPost(db *sql.DB, postID int, action string, scheduledTime time.Time) error {
+	var res sql.Result
+	var err error
+	switch action {
+	case models.MissedPublishNow, models.MissedReschedule:
+		res, err = db.Exec(`
+			UPDATE scheduled_posts
+			SET status = $1, scheduled_time = $2, next_attempt_at = NULL, updated_at = NOW()
+			WHERE id = $3 AND status = $4
+		`, models.StatusPending, scheduledTime, postID, models.StatusMissed)
+	case models.MissedDiscard:
+		res, err = db.Exec(`
+			UPDATE scheduled_posts SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3
+		`, models.StatusCancelled, postID, models.StatusMissed)
+	default:
+		return fmt.Errorf("unknown action %q; use publish_now, reschedule or discard", action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve missed post: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPostNotMissed
+	}
+	if action == models.MissedDiscard {
+		SyncDraftWithPost(db, postID)
+	}
+	return nil
+}
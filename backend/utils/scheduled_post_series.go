@@ -0,0 +1,544 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrOccurrenceNotFound is returned when a time is not an upcoming occurrence of the series
+	ErrOccurrenceNotFound = errors.New("occurrence not found in series")
+	// ErrOccurrenceLocked is returned when an occurrence is already being published or done
+	ErrOccurrenceLocked = errors.New("occurrence is already being published")
+)
+
+// seriesException is a skip or edit of one not-yet-materialized occurrence
+type seriesException struct {
+	skipped       bool
+	content       *string
+	mediaURLs     pq.StringArray
+	scheduledTime *time.Time
+}
+
+// seriesCursor walks a series' occurrences from its current position, enforcing
+// ends_at and max_occurrences
+type seriesCursor struct {
+	rec     Recurrence
+	created int
+	last    time.Time
+	max     int
+	until   *time.Time
+}
+
+func newSeriesCursor(series *models.ScheduledPostSeries) (*seriesCursor, error) {
+	// Evaluate the rule in the series' zone so occurrences keep their wall-clock time across DST
+	loc := seriesLocation(series)
+	rec, err := ParseRecurrence(series.RecurrenceRule, series.StartsAt.In(loc))
+	if err != nil {
+		return nil, err
+	}
+	c := &seriesCursor{rec: rec, created: series.OccurrencesCreated, last: series.StartsAt.In(loc), until: series.EndsAt}
+	if series.LastOccurrenceAt != nil {
+		c.last = series.LastOccurrenceAt.In(loc)
+	}
+	if series.MaxOccurrences != nil {
+		c.max = *series.MaxOccurrences
+	}
+	return c, nil
+}
+
+// seriesLocation returns the series' time zone, UTC if unset or invalid
+func seriesLocation(series *models.ScheduledPostSeries) *time.Location {
+	if loc, err := LoadTimeZone(series.TimeZone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// advance moves to the next occurrence; ok is false once the series is exhausted
+func (c *seriesCursor) advance() (time.Time, bool) {
+	if c.max > 0 && c.created >= c.max {
+		return time.Time{}, false
+	}
+	next := c.rec.Next(c.last)
+	if next.IsZero() || (c.until != nil && next.After(*c.until)) {
+		return time.Time{}, false
+	}
+	c.created++
+	c.last = next
+	return next, true
+}
+
+// CreateScheduledPostSeries stores a recurring post starting at req.ScheduledTime and materializes
+// its first occurrence, the first time at or after it that matches the rule. The rule is
+// evaluated in req.TimeZone (UTC when empty).
+func CreateScheduledPostSeries(db *sql.DB, userID string, req models.CreateScheduledPostRequest) (*models.ScheduledPostSeries, *models.ScheduledPost, error) {
+	rule := req.Recurrence.Rule
+	zone := req.TimeZone
+	if zone == "" {
+		zone = DefaultTimeZone
+	}
+	loc, err := LoadTimeZone(zone)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec, err := ParseRecurrence(rule, req.ScheduledTime.In(loc))
+	if err != nil {
+		return nil, nil, err
+	}
+	first := FirstOccurrence(rec, req.ScheduledTime.In(loc))
+	if first.IsZero() {
+		return nil, nil, fmt.Errorf("recurrence rule has no occurrences")
+	}
+	var maxOccurrences *int
+	if req.Recurrence.Count != nil {
+		if *req.Recurrence.Count < 1 {
+			return nil, nil, fmt.Errorf("recurrence count must be at least 1")
+		}
+		maxOccurrences = req.Recurrence.Count
+	} else if n := RecurrenceCount(rule); n > 0 {
+		maxOccurrences = &n
+	}
+	if req.Recurrence.Until != nil && req.Recurrence.Until.Before(first) {
+		return nil, nil, fmt.Errorf("recurrence end must be after the first occurrence")
+	}
+
+	targetsJSON, err := json.Marshal(req.Targets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid targets: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	series := &models.ScheduledPostSeries{
+		UserID:             userID,
+		WorkspaceID:        req.WorkspaceID,
+		Content:            req.Content,
+		MediaURLs:          pq.StringArray(req.MediaURLs),
+		Platforms:          pq.StringArray(req.Platforms),
+		Targets:            req.Targets,
+		RecurrenceRule:     rule,
+		StartsAt:           req.ScheduledTime,
+		TimeZone:           zone,
+		EndsAt:             req.Recurrence.Until,
+		MaxOccurrences:     maxOccurrences,
+		OccurrencesCreated: 1,
+		LastOccurrenceAt:   &first,
+		Status:             models.SeriesStatusActive,
+		MissedWindow:       req.MissedWindow,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO scheduled_post_series
+			(user_id, content, media_urls, platforms, targets, recurrence_rule, starts_at, timezone, ends_at,
+			 max_occurrences, occurrences_created, last_occurrence_at, status, created_at, updated_at, workspace_id, missed_window_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 1, $11, $12, NOW(), NOW(), $13, $14)
+		RETURNING id, created_at, updated_at
+	`, userID, series.Content, series.MediaURLs, series.Platforms, targetsJSON, rule, series.StartsAt, zone,
+		series.EndsAt, series.MaxOccurrences, first, series.Status, series.WorkspaceID, series.MissedWindow).Scan(&series.ID, &series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create series: %v", err)
+	}
+
+	post, err := insertOccurrence(tx, series, first, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit series: %v", err)
+	}
+	return series, post, nil
+}
+
+// insertOccurrence materializes one occurrence as a pending scheduled post
+func insertOccurrence(tx *sql.Tx, series *models.ScheduledPostSeries, occurrenceAt time.Time, exc *seriesException) (*models.ScheduledPost, error) {
+	post := &models.ScheduledPost{
+		UserID:        series.UserID,
+		WorkspaceID:   series.WorkspaceID,
+		Content:       series.Content,
+		MediaURLs:     series.MediaURLs,
+		Platforms:     series.Platforms,
+		ScheduledTime: occurrenceAt,
+		Status:        models.StatusPending,
+		Targets:       series.Targets,
+		SeriesID:      &series.ID,
+		OccurrenceAt:  &occurrenceAt,
+		TimeZone:      &series.TimeZone,
+		MissedWindow:  series.MissedWindow,
+	}
+	if exc != nil {
+		if exc.content != nil {
+			post.Content = *exc.content
+		}
+		if exc.mediaURLs != nil {
+			post.MediaURLs = exc.mediaURLs
+		}
+		if exc.scheduledTime != nil {
+			post.ScheduledTime = *exc.scheduledTime
+		}
+	}
+
+	targetsJSON, err := json.Marshal(post.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %v", err)
+	}
+	err = tx.QueryRow(`
+		INSERT INTO scheduled_posts
+			(user_id, content, media_urls, platforms, scheduled_time, status, created_at, updated_at, targets, series_id, occurrence_at, timezone, workspace_id, missed_window_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`, post.UserID, post.Content, post.MediaURLs, post.Platforms, post.ScheduledTime, post.Status,
+		targetsJSON, series.ID, occurrenceAt, series.TimeZone, series.WorkspaceID, series.MissedWindow).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to materialize occurrence: %v", err)
+	}
+	return post, nil
+}
+
+// scanSeries reads a scheduled_post_series row selected with seriesColumns
+func scanSeries(row interface{ Scan(...interface{}) error }) (*models.ScheduledPostSeries, error) {
+	var s models.ScheduledPostSeries
+	var rawTargets []byte
+	err := row.Scan(&s.ID, &s.UserID, &s.WorkspaceID, &s.Content, &s.MediaURLs, &s.Platforms, &rawTargets, &s.RecurrenceRule,
+		&s.StartsAt, &s.TimeZone, &s.EndsAt, &s.MaxOccurrences, &s.OccurrencesCreated, &s.LastOccurrenceAt, &s.Status,
+		&s.MissedWindow, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawTargets) > 0 {
+		var tgt map[string]interface{}
+		if uErr := json.Unmarshal(rawTargets, &tgt); uErr == nil {
+			s.Targets = tgt
+		}
+	}
+	return &s, nil
+}
+
+const seriesColumns = `id, user_id, workspace_id::text, content, media_urls, platforms, targets, recurrence_rule, starts_at, timezone, ends_at,
+	max_occurrences, occurrences_created, last_occurrence_at, status, missed_window_minutes, created_at, updated_at`
+
+// GetScheduledPostSeries loads a series; sql.ErrNoRows when it doesn't exist.
+// Callers check ownership or workspace permissions.
+func GetScheduledPostSeries(db *sql.DB, seriesID int) (*models.ScheduledPostSeries, error) {
+	return scanSeries(db.QueryRow(`SELECT `+seriesColumns+` FROM scheduled_post_series WHERE id = $1`, seriesID))
+}
+
+// loadSeriesExceptions returns the series' exceptions keyed by occurrence time (Unix seconds)
+func loadSeriesExceptions(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, seriesID int) (map[int64]seriesException, error) {
+	rows, err := q.Query(`
+		SELECT occurrence_at, skipped, content, media_urls, scheduled_time
+		FROM scheduled_post_series_exceptions
+		WHERE series_id = $1
+	`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load series exceptions: %v", err)
+	}
+	defer rows.Close()
+
+	exceptions := map[int64]seriesException{}
+	for rows.Next() {
+		var at time.Time
+		var e seriesException
+		if err := rows.Scan(&at, &e.skipped, &e.content, &e.mediaURLs, &e.scheduledTime); err != nil {
+			return nil, fmt.Errorf("failed to scan series exception: %v", err)
+		}
+		exceptions[at.Unix()] = e
+	}
+	return exceptions, rows.Err()
+}
+
+// MaterializeNextOccurrence creates the series' next pending scheduled post, applying any
+// skip or edit recorded for it. It does nothing if the series is not active or an occurrence
+// is still pending, and marks the series completed once its rule is exhausted.
+func MaterializeNextOccurrence(db *sql.DB, seriesID int) (*models.ScheduledPost, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the series so concurrent workers can't materialize the same occurrence
+	series, err := scanSeries(tx.QueryRow(`SELECT `+seriesColumns+` FROM scheduled_post_series WHERE id = $1 FOR UPDATE`, seriesID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load series %d: %v", seriesID, err)
+	}
+	if series.Status != models.SeriesStatusActive {
+		return nil, nil
+	}
+
+	var open int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM scheduled_posts
+		WHERE series_id = $1 AND status IN ($2, $3)
+	`, seriesID, models.StatusPending, models.StatusProcessing).Scan(&open); err != nil {
+		return nil, fmt.Errorf("failed to check open occurrences: %v", err)
+	}
+	if open > 0 {
+		return nil, nil
+	}
+
+	cursor, err := newSeriesCursor(series)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := loadSeriesExceptions(tx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences that came due while nothing was running (an outage, or a long-running
+	// previous occurrence) are passed over rather than published late one after another
+	now := time.Now()
+	passed := 0
+	var post *models.ScheduledPost
+	status := models.SeriesStatusActive
+	for {
+		next, ok := cursor.advance()
+		if !ok {
+			status = models.SeriesStatusCompleted
+			break
+		}
+		exc, hasExc := exceptions[next.Unix()]
+		if hasExc && exc.skipped {
+			continue // skipped occurrences still count towards max_occurrences
+		}
+		at := next
+		if hasExc && exc.scheduledTime != nil {
+			at = *exc.scheduledTime
+		}
+		if !at.After(now) {
+			passed++ // and so do the ones passed over
+			continue
+		}
+		var excPtr *seriesException
+		if hasExc {
+			excPtr = &exc
+		}
+		post, err = insertOccurrence(tx, series, next, excPtr)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	_, err = tx.Exec(`
+		UPDATE scheduled_post_series
+		SET occurrences_created = $1, last_occurrence_at = $2, status = $3, updated_at = NOW()
+		WHERE id = $4
+	`, cursor.created, cursor.last, status, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance series: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit series advance: %v", err)
+	}
+	if passed > 0 {
+		log.Printf("Series %d passed over %d occurrence(s) already in the past", seriesID, passed)
+	}
+	if post != nil {
+		log.Printf("Materialized occurrence %s of series %d as post %d", post.OccurrenceAt.Format(time.RFC3339), seriesID, post.ID)
+	} else {
+		log.Printf("Series %d completed", seriesID)
+	}
+	return post, nil
+}
+
+// UpcomingOccurrences lists up to limit occurrences from the currently materialized one onwards,
+// including skipped ones so they can be restored
+func UpcomingOccurrences(db *sql.DB, series *models.ScheduledPostSeries, limit int) ([]models.ScheduledPostOccurrence, error) {
+	occurrences := []models.ScheduledPostOccurrence{}
+
+	rows, err := db.Query(`
+		SELECT id, occurrence_at, scheduled_time, status, content, media_urls
+		FROM scheduled_posts
+		WHERE series_id = $1 AND status IN ($2, $3)
+		ORDER BY occurrence_at
+	`, series.ID, models.StatusPending, models.StatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load materialized occurrences: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o models.ScheduledPostOccurrence
+		var postID int
+		if err := rows.Scan(&postID, &o.OccurrenceAt, &o.ScheduledTime, &o.Status, &o.Content, &o.MediaURLs); err != nil {
+			return nil, fmt.Errorf("failed to scan occurrence: %v", err)
+		}
+		o.PostID = &postID
+		o.Edited = !o.ScheduledTime.Equal(o.OccurrenceAt) || o.Content != series.Content
+		occurrences = append(occurrences, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if series.Status != models.SeriesStatusActive {
+		return occurrences, nil
+	}
+	cursor, err := newSeriesCursor(series)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := loadSeriesExceptions(db, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for len(occurrences) < limit {
+		next, ok := cursor.advance()
+		if !ok {
+			break
+		}
+		if !next.After(now) {
+			if exc, ok := exceptions[next.Unix()]; !ok || exc.scheduledTime == nil || !exc.scheduledTime.After(now) {
+				continue // passed over when the next occurrence is materialized
+			}
+		}
+		o := models.ScheduledPostOccurrence{
+			OccurrenceAt:  next,
+			ScheduledTime: next,
+			Status:        models.OccurrenceStatusPlanned,
+			Content:       series.Content,
+			MediaURLs:     series.MediaURLs,
+		}
+		if exc, ok := exceptions[next.Unix()]; ok {
+			if exc.skipped {
+				o.Status = models.OccurrenceStatusSkipped
+			}
+			if exc.content != nil {
+				o.Content = *exc.content
+				o.Edited = true
+			}
+			if exc.mediaURLs != nil {
+				o.MediaURLs = exc.mediaURLs
+				o.Edited = true
+			}
+			if exc.scheduledTime != nil {
+				o.ScheduledTime = *exc.scheduledTime
+				o.Edited = true
+			}
+		}
+		occurrences = append(occurrences, o)
+	}
+	return occurrences, nil
+}
+
+// UpdateOccurrence skips or edits one occurrence without touching the rest of the series.
+// A materialized occurrence is changed in place (skipping cancels it and materializes the next);
+// a future one is recorded as an exception that is applied when it is materialized.
+func UpdateOccurrence(db *sql.DB, series *models.ScheduledPostSeries, req models.UpdateOccurrenceRequest) error {
+	var postID int
+	var status string
+	err := db.QueryRow(`
+		SELECT id, status FROM scheduled_posts WHERE series_id = $1 AND occurrence_at = $2
+	`, series.ID, req.OccurrenceAt).Scan(&postID, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up occurrence: %v", err)
+	}
+
+	if err == nil {
+		if status != models.StatusPending {
+			return ErrOccurrenceLocked
+		}
+		if req.Skip {
+			if _, err := db.Exec(`
+				UPDATE scheduled_posts SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3
+			`, models.StatusCancelled, postID, models.StatusPending); err != nil {
+				return fmt.Errorf("failed to skip occurrence: %v", err)
+			}
+			_, err := MaterializeNextOccurrence(db, series.ID)
+			return err
+		}
+		_, err := db.Exec(`
+			UPDATE scheduled_posts
+			SET content = COALESCE($1, content), media_urls = COALESCE($2, media_urls),
+			    scheduled_time = COALESCE($3, scheduled_time), updated_at = NOW()
+			WHERE id = $4 AND status = $5
+		`, req.Content, occurrenceMedia(req.MediaURLs), req.ScheduledTime, postID, models.StatusPending)
+		if err != nil {
+			return fmt.Errorf("failed to edit occurrence: %v", err)
+		}
+		return nil
+	}
+
+	// Not materialized yet: it must be one of the series' future occurrences
+	if series.Status != models.SeriesStatusActive {
+		return ErrOccurrenceNotFound
+	}
+	cursor, err := newSeriesCursor(series)
+	if err != nil {
+		return err
+	}
+	found := false
+	for {
+		next, ok := cursor.advance()
+		if !ok || next.After(req.OccurrenceAt) {
+			break
+		}
+		if next.Equal(req.OccurrenceAt) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrOccurrenceNotFound
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO scheduled_post_series_exceptions
+			(series_id, occurrence_at, skipped, content, media_urls, scheduled_time, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (series_id, occurrence_at) DO UPDATE SET
+			skipped = EXCLUDED.skipped,
+			content = COALESCE(EXCLUDED.content, scheduled_post_series_exceptions.content),
+			media_urls = COALESCE(EXCLUDED.media_urls, scheduled_post_series_exceptions.media_urls),
+			scheduled_time = COALESCE(EXCLUDED.scheduled_time, scheduled_post_series_exceptions.scheduled_time),
+			updated_at = NOW()
+	`, series.ID, req.OccurrenceAt, req.Skip, req.Content, occurrenceMedia(req.MediaURLs), req.ScheduledTime)
+	if err != nil {
+		return fmt.Errorf("failed to save occurrence exception: %v", err)
+	}
+	return nil
+}
+
+// occurrenceMedia converts an optional media list to a nullable SQL array
+func occurrenceMedia(mediaURLs *[]string) interface{} {
+	if mediaURLs == nil {
+		return nil
+	}
+	return pq.Array(*mediaURLs)
+}
+
+// CancelScheduledPostSeries stops a series and cancels its pending occurrence
+func CancelScheduledPostSeries(db *sql.DB, seriesID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE scheduled_post_series SET status = $1, updated_at = NOW() WHERE id = $2
+	`, models.SeriesStatusCancelled, seriesID); err != nil {
+		return fmt.Errorf("failed to cancel series: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE scheduled_posts SET status = $1, updated_at = NOW() WHERE series_id = $2 AND status = $3
+	`, models.StatusCancelled, seriesID, models.StatusPending); err != nil {
+		return fmt.Errorf("failed to cancel pending occurrences: %v", err)
+	}
+	return tx.Commit()
+}
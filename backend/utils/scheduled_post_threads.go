@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"social-sync-backend/models"
+)
+
+// publishThread publishes the main post to one account and then every thread part as a reply
+// to the part before it. Parts posted by an earlier attempt are skipped, so a retry resumes at
+// the part that failed; a failed part stops the chain. The outcome carries the main post's
+// result, which is what the account's delivery row records.
+func (spp *ScheduledPostProcessor) publishThread(ctx context.Context, post models.ScheduledPost, platform string, account PublishAccount, target models.PostTarget) PublishOutcome {
+	outcome := PublishOutcome{Account: account}
+	publisher, err := GetPublisher(platform)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	threads, ok := publisher.(ThreadPublisher)
+	if !ok {
+		outcome.Err = fmt.Errorf("%s does not support threads", platform)
+		return outcome
+	}
+
+	posted, err := threadPartsPosted(spp.db, post.ID, platform, account.ID)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+
+	id := account.ID
+	variant := ResolveVariant(post.Content, post.MediaURLs, target, platform, &id)
+	reqs := []PublishRequest{variantPublishRequest(variant, target)}
+	for _, part := range post.Thread {
+		reqs = append(reqs, PublishRequest{Content: part.Content, MediaURLs: part.MediaURLs})
+	}
+
+	parentID := ""
+	for i, req := range reqs {
+		if done, ok := posted[i]; ok {
+			parentID = *done.RemotePostID
+			if i == 0 {
+				outcome.Result = &PublishResult{RemotePostID: parentID}
+				if done.Permalink != nil {
+					outcome.Result.Permalink = *done.Permalink
+				}
+			}
+			continue
+		}
+
+		if err := WaitForPublishSlot(ctx, platform, account.ID); err != nil {
+			outcome.Err = fmt.Errorf("rate limit wait aborted: %v", err)
+			return outcome
+		}
+		var result *PublishResult
+		if i == 0 {
+			result, err = threads.Publish(ctx, account, req)
+		} else {
+			result, err = threads.PublishReply(ctx, account, req, parentID)
+		}
+		if err == nil && (result == nil || result.RemotePostID == "") {
+			err = fmt.Errorf("no remote ID returned to continue the thread from")
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				spp.recordThreadPart(post.ID, platform, account.ID, i, nil, err)
+			}
+			log.Printf("Publisher: %s account %s failed on part %d/%d: %v", platform, account.ID, i+1, len(reqs), err)
+			if i > 0 {
+				err = fmt.Errorf("part %d/%d: %v", i+1, len(reqs), err)
+			}
+			outcome.Err = err
+			return outcome
+		}
+
+		spp.recordThreadPart(post.ID, platform, account.ID, i, result, nil)
+		if i == 0 {
+			outcome.Result = result
+		}
+		parentID = result.RemotePostID
+	}
+	log.Printf("Publisher: %s account %s published %d part(s)", platform, account.ID, len(reqs))
+	return outcome
+}
+
+// threadPartsPosted returns the parts already posted to an account, keyed by part index
+func threadPartsPosted(db *sql.DB, postID int, platform, accountID string) (map[int]models.ThreadPartDelivery, error) {
+	rows, err := db.Query(`
+		SELECT part_index, status, remote_post_id, permalink, last_error, posted_at
+		FROM scheduled_post_thread_parts
+		WHERE scheduled_post_id = $1 AND platform = $2 AND social_account_id = $3::uuid
+		  AND status = $4 AND remote_post_id IS NOT NULL
+	`, postID, platform, accountID, models.StatusPosted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load thread parts: %v", err)
+	}
+	defer rows.Close()
+
+	posted := map[int]models.ThreadPartDelivery{}
+	for rows.Next() {
+		var p models.ThreadPartDelivery
+		if err := rows.Scan(&p.PartIndex, &p.Status, &p.RemotePostID, &p.Permalink, &p.LastError, &p.PostedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan thread part: %v", err)
+		}
+		posted[p.PartIndex] = p
+	}
+	return posted, rows.Err()
+}
+
+// recordThreadPart stores the outcome of publishing one part of a thread to one account
+func (spp *ScheduledPostProcessor) recordThreadPart(postID int, platform, accountID string, partIndex int, result *PublishResult, pubErr error) {
+	status := models.StatusPosted
+	var remoteID, permalink, lastError *string
+	var postedAt *time.Time
+	if pubErr != nil {
+		status = models.StatusFailed
+		msg := pubErr.Error()
+		lastError = &msg
+	} else {
+		now := time.Now()
+		postedAt = &now
+		remoteID = &result.RemotePostID
+		if result.Permalink != "" {
+			permalink = &result.Permalink
+		}
+	}
+
+	_, err := spp.db.Exec(`
+		INSERT INTO scheduled_post_thread_parts
+			(scheduled_post_id, platform, social_account_id, part_index, status, remote_post_id, permalink, last_error, posted_at)
+		VALUES ($1, $2, $3::uuid, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (scheduled_post_id, platform, social_account_id, part_index)
+		DO UPDATE SET
+			status = EXCLUDED.status,
+			remote_post_id = COALESCE(EXCLUDED.remote_post_id, scheduled_post_thread_parts.remote_post_id),
+			permalink = COALESCE(EXCLUDED.permalink, scheduled_post_thread_parts.permalink),
+			last_error = EXCLUDED.last_error,
+			posted_at = COALESCE(EXCLUDED.posted_at, scheduled_post_thread_parts.posted_at),
+			updated_at = NOW()
+	`, postID, platform, accountID, partIndex, status, remoteID, permalink, lastError, postedAt)
+	if err != nil {
+		log.Printf("Failed to record part %d of %s thread for post %d: %v", partIndex, platform, postID, err)
+	}
+}
+
+// attachThreadParts adds the per-part results of a multi-part post to its delivery rows
+func attachThreadParts(db *sql.DB, postID int, deliveries []models.ScheduledPostDelivery) error {
+	rows, err := db.Query(`
+		SELECT platform, social_account_id::text, part_index, status, remote_post_id, permalink, last_error, posted_at
+		FROM scheduled_post_thread_parts
+		WHERE scheduled_post_id = $1
+		ORDER BY platform, social_account_id, part_index
+	`, postID)
+	if err != nil {
+		return fmt.Errorf("failed to query thread parts: %v", err)
+	}
+	defer rows.Close()
+
+	parts := map[string][]models.ThreadPartDelivery{}
+	for rows.Next() {
+		var platform, accountID string
+		var p models.ThreadPartDelivery
+		if err := rows.Scan(&platform, &accountID, &p.PartIndex, &p.Status, &p.RemotePostID, &p.Permalink, &p.LastError, &p.PostedAt); err != nil {
+			return fmt.Errorf("failed to scan thread part: %v", err)
+		}
+		key := deliveryKey(platform, &accountID)
+		parts[key] = append(parts[key], p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		deliveries[i].Parts = parts[deliveryKey(deliveries[i].Platform, deliveries[i].SocialAccountID)]
+	}
+	return nil
+}
+
+// EncodeThread returns the thread column value of a scheduled post; no parts store NULL
+func EncodeThread(thread []models.ThreadPart) (*string, error) {
+	if len(thread) == 0 {
+		return nil, nil
+	}
+	for i, part := range thread {
+		if part.Content == "" && len(part.MediaURLs) == 0 {
+			return nil, fmt.Errorf("thread part %d is empty", i+1)
+		}
+	}
+	b, err := json.Marshal(thread)
+	if err != nil {
+		return nil, fmt.Errorf("invalid thread: %v", err)
+	}
+	encoded := string(b)
+	return &encoded, nil
+}
+
+// DecodeThread reads the thread column of a scheduled post
+func DecodeThread(raw []byte) []models.ThreadPart {
+	if len(raw) == 0 {
+		return nil
+	}
+	var thread []models.ThreadPart
+	if err := json.Unmarshal(raw, &thread); err != nil {
+		log.Printf("Failed to decode scheduled post thread: %v", err)
+		return nil
+	}
+	return thread
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"social-sync-backend/models"
+)
+
+// UnpublishScheduledPost deletes every posted delivery of a post from its platform using the
+// stored remote post IDs. Thread parts are deleted last-to-first before the main post. Every
+// deletion attempt is written to the unpublish log and returned, in order. A delivery whose
+// main post is gone is marked unpublished; once no delivery remains posted, so is the post.
+// ownerID is the user whose connected accounts published the post.
+func UnpublishScheduledPost(ctx context.Context, db *sql.DB, postID int, ownerID, requestedBy string) ([]models.UnpublishRecord, error) {
+	deliveries, err := GetScheduledPostDeliveries(db, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []models.UnpublishRecord{}
+	for _, d := range deliveries {
+		if d.Status != models.StatusPosted || d.RemotePostID == nil || d.SocialAccountID == nil {
+			continue
+		}
+		deliveryRecords, removed := unpublishDelivery(ctx, db, d, ownerID)
+		for _, rec := range deliveryRecords {
+			rec.RequestedBy = &requestedBy
+			records = append(records, logUnpublish(db, rec))
+		}
+		if !removed {
+			continue
+		}
+		if _, err := db.Exec(`
+			UPDATE scheduled_post_deliveries SET status = $1, updated_at = NOW() WHERE id = $2
+		`, models.StatusUnpublished, d.ID); err != nil {
+			log.Printf("Failed to mark delivery %d unpublished: %v", d.ID, err)
+		}
+	}
+	if len(records) == 0 {
+		return records, nil
+	}
+
+	_, err = db.Exec(`
+		UPDATE scheduled_posts SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND NOT EXISTS (
+			SELECT 1 FROM scheduled_post_deliveries WHERE scheduled_post_id = $2 AND status = $3
+		)
+	`, models.StatusUnpublished, postID, models.StatusPosted)
+	if err != nil {
+		return records, fmt.Errorf("failed to update post status: %v", err)
+	}
+	return records, nil
+}
+
+// unpublishDelivery deletes one delivery's thread parts and main post from its platform and
+// reports whether the main post is gone, all of its published parts included
+func unpublishDelivery(ctx context.Context, db *sql.DB, d models.ScheduledPostDelivery, ownerID string) ([]models.UnpublishRecord, bool) {
+	base := models.UnpublishRecord{
+		ScheduledPostID: d.ScheduledPostID,
+		DeliveryID:      &d.ID,
+		Platform:        d.Platform,
+		SocialAccountID: d.SocialAccountID,
+		AccountName:     d.AccountName,
+		RemotePostID:    d.RemotePostID,
+	}
+	fail := func(status string, err error) ([]models.UnpublishRecord, bool) {
+		rec := base
+		rec.Status = status
+		msg := err.Error()
+		rec.Error = &msg
+		return []models.UnpublishRecord{rec}, false
+	}
+
+	publisher, err := GetPublisher(d.Platform)
+	if err != nil {
+		return fail(models.UnpublishUnsupported, err)
+	}
+	deleter, ok := publisher.(DeletePublisher)
+	if !ok {
+		return fail(models.UnpublishUnsupported, fmt.Errorf("%s posts cannot be deleted through its API", d.Platform))
+	}
+	accounts, err := ResolvePublishAccounts(db, ownerID, d.Platform, []string{*d.SocialAccountID}, false)
+	if err != nil {
+		return fail(models.UnpublishFailed, err)
+	}
+	if len(accounts) == 0 {
+		return fail(models.UnpublishFailed, fmt.Errorf("the %s account is no longer connected", d.Platform))
+	}
+	account := accounts[0]
+
+	// Replies first, so a thread never points at a deleted parent
+	var records []models.UnpublishRecord
+	for i := len(d.Parts) - 1; i >= 0; i-- {
+		part := d.Parts[i]
+		if part.PartIndex == 0 || part.Status != models.StatusPosted || part.RemotePostID == nil {
+			continue
+		}
+		rec := base
+		rec.PartIndex = part.PartIndex
+		rec.RemotePostID = part.RemotePostID
+		records = append(records, deleteRemotePost(ctx, db, deleter, account, rec))
+	}
+
+	// A post published in several steps (albums, one post per video) is gone only once every
+	// one of its remote posts is; the first one, RemotePostID, is deleted last
+	remoteIDs := []string(d.PublishedParts)
+	if len(remoteIDs) == 0 {
+		remoteIDs = []string{*d.RemotePostID}
+	}
+	var main []models.UnpublishRecord
+	deleted := 0
+	for i := len(remoteIDs) - 1; i >= 0; i-- {
+		rec := base
+		rec.RemotePostID = &remoteIDs[i]
+		rec = deleteRemotePost(ctx, db, deleter, account, rec)
+		if rec.Status == models.UnpublishDeleted {
+			deleted++
+		}
+		main = append(main, rec)
+	}
+	if deleted > 0 && deleted < len(main) {
+		for i := range main {
+			if main[i].Status == models.UnpublishFailed {
+				main[i].Status = models.UnpublishPartial
+			}
+		}
+	}
+	return append(records, main...), deleted == len(main)
+}
+
+// deleteRemotePost deletes rec's remote post and returns rec with the outcome filled in.
+// A post that no longer exists on the platform counts as deleted.
+func deleteRemotePost(ctx context.Context, db *sql.DB, deleter DeletePublisher, account PublishAccount, rec models.UnpublishRecord) models.UnpublishRecord {
+	err := WaitForPublishSlot(ctx, rec.Platform, account.ID)
+	if err == nil {
+		err = deleter.DeletePost(ctx, account, *rec.RemotePostID)
+	}
+	if err != nil && strings.Contains(err.Error(), "status 404") {
+		log.Printf("Unpublish: %s post %s was already gone", rec.Platform, *rec.RemotePostID)
+		err = nil
+	}
+	if err != nil {
+		log.Printf("Unpublish: deleting %s post %s failed: %v", rec.Platform, *rec.RemotePostID, err)
+		rec.Status = models.UnpublishFailed
+		msg := err.Error()
+		rec.Error = &msg
+		return rec
+	}
+
+	rec.Status = models.UnpublishDeleted
+	if rec.PartIndex > 0 {
+		_, err = db.Exec(`
+			UPDATE scheduled_post_thread_parts SET status = $1, updated_at = NOW()
+			WHERE scheduled_post_id = $2 AND platform = $3 AND social_account_id = $4::uuid AND part_index = $5
+		`, models.StatusUnpublished, rec.ScheduledPostID, rec.Platform, account.ID, rec.PartIndex)
+		if err != nil {
+			log.Printf("Failed to mark part %d of %s thread for post %d unpublished: %v", rec.PartIndex, rec.Platform, rec.ScheduledPostID, err)
+		}
+	}
+	return rec
+}
+
+// logUnpublish appends rec to the unpublish log and returns it with its ID and timestamp
+func logUnpublish(db *sql.DB, rec models.UnpublishRecord) models.UnpublishRecord {
+	err := db.QueryRow(`
+		INSERT INTO scheduled_post_unpublish_log
+			(scheduled_post_id, delivery_id, platform, social_account_id, account_name, remote_post_id,
+			 part_index, status, error, requested_by)
+		VALUES ($1, $2, $3, $4::uuid, $5, $6, $7, $8, $9, $10::uuid)
+		RETURNING id, created_at
+	`, rec.ScheduledPostID, rec.DeliveryID, rec.Platform, rec.SocialAccountID, rec.AccountName, rec.RemotePostID,
+		rec.PartIndex, rec.Status, rec.Error, rec.RequestedBy).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to log unpublish of %s delivery for post %d: %v", rec.Platform, rec.ScheduledPostID, err)
+	}
+	return rec
+}
+
+// GetUnpublishLog returns the unpublish audit trail of a post, oldest first
+func GetUnpublishLog(db *sql.DB, postID int) ([]models.UnpublishRecord, error) {
+	rows, err := db.Query(`
+		SELECT id, scheduled_post_id, delivery_id, platform, social_account_id::text, account_name, remote_post_id,
+		       part_index, status, error, requested_by::text, created_at
+		FROM scheduled_post_unpublish_log
+		WHERE scheduled_post_id = $1
+		ORDER BY created_at, id
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unpublish log: %v", err)
+	}
+	defer rows.Close()
+
+	records := []models.UnpublishRecord{}
+	for rows.Next() {
+		var rec models.UnpublishRecord
+		if err := rows.Scan(&rec.ID, &rec.ScheduledPostID, &rec.DeliveryID, &rec.Platform, &rec.SocialAccountID,
+			&rec.AccountName, &rec.RemotePostID, &rec.PartIndex, &rec.Status, &rec.Error, &rec.RequestedBy, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan unpublish record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
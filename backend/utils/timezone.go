@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // container images may not ship a zoneinfo database
+
+	"social-sync-backend/models"
+)
+
+// DefaultTimeZone is used when neither the request, workspace nor user specify one
+const DefaultTimeZone = "UTC"
+
+// localTimeLayouts are the accepted wall-clock formats (no offset; the zone comes separately)
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// LoadTimeZone validates an IANA zone name such as "America/New_York"
+func LoadTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("invalid time zone: %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %q", name)
+	}
+	return loc, nil
+}
+
+// ResolveTimeZone picks the zone for a scheduling request: the requested zone, then the
+// workspace's, then the user's, falling back to UTC
+func ResolveTimeZone(db *sql.DB, userID string, workspaceID *string, requested string) (string, *time.Location, error) {
+	if requested != "" {
+		loc, err := LoadTimeZone(requested)
+		if err != nil {
+			return "", nil, err
+		}
+		return requested, loc, nil
+	}
+
+	var name string
+	if workspaceID != nil && *workspaceID != "" {
+		db.QueryRow(`SELECT COALESCE(timezone, '') FROM workspaces WHERE id = $1`, *workspaceID).Scan(&name)
+	}
+	if name == "" {
+		db.QueryRow(`SELECT COALESCE(timezone, '') FROM users WHERE id = $1`, userID).Scan(&name)
+	}
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	loc, err := LoadTimeZone(name)
+	if err != nil {
+		return DefaultTimeZone, time.UTC, nil
+	}
+	return name, loc, nil
+}
+
+// ParseLocalTime interprets a wall-clock time in loc. Times that fall in a DST gap are
+// moved forward by the length of the gap (02:30 on a spring-forward day becomes 03:30);
+// ambiguous times in the fall-back hour resolve to the first occurrence.
+func ParseLocalTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range localTimeLayouts {
+		wall, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+		if t.Hour() != wall.Hour() || t.Minute() != wall.Minute() {
+			// Nonexistent wall time: apply the offset in effect before the transition
+			_, before := t.Add(-12 * time.Hour).Zone()
+			t = wall.Add(-time.Duration(before) * time.Second).In(loc)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid local time %q; use YYYY-MM-DDTHH:MM", value)
+}
+
+// FormatLocalTime renders t as a wall-clock time with offset in the named zone
+func FormatLocalTime(t time.Time, zone string) string {
+	loc, err := LoadTimeZone(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// LocalizeScheduledPost fills LocalTime from the post's scheduled time and zone
+func LocalizeScheduledPost(post *models.ScheduledPost) {
+	if post.TimeZone != nil && *post.TimeZone != "" {
+		post.LocalTime = FormatLocalTime(post.ScheduledTime, *post.TimeZone)
+	}
+}
@@ -0,0 +1,572 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"social-sync-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// PlatformRules describes what a platform accepts in a single post. Zero values mean "no limit".
+type PlatformRules struct {
+	MaxChars        int  // limit on the post text
+	MaxCaptionChars int  // limit on the text when media is attached (Telegram captions)
+	WeightedLength  bool // count text the way Twitter does (URLs 23, wide characters 2)
+	RequiresMedia   bool // at least one image or video
+	RequiresVideo   bool // exactly one video is uploaded
+	AllowImages     bool
+	AllowVideos     bool
+	AllowMixed      bool // images and videos in the same post
+	IgnoresExtra    bool // unsupported or surplus media is skipped instead of failing the post
+	MaxMedia        int  // images + videos
+	MaxImages       int
+	MaxVideos       int
+	MaxImageBytes   int64
+	MaxVideoBytes   int64
+	MinImageAspect  float64 // width / height
+	MaxImageAspect  float64
+	MinVideoSeconds float64
+	MaxVideoSeconds float64
+	MaxHashtags     int
+	MaxCommentChars int // first comment limit; only platforms with a CommentPublisher
+}
+
+const (
+	kb = int64(1024)
+	mb = 1024 * kb
+	gb = 1024 * mb
+)
+
+// platformRules holds the publishing limits of every supported platform. They follow the
+// platforms' documented API limits for the way our publishers send media (by URL, in one post).
+var platformRules = map[string]PlatformRules{
+	"twitter": {
+		MaxChars: 280, WeightedLength: true,
+		AllowImages: true, AllowVideos: true,
+		MaxMedia: 4, MaxImages: 4, MaxVideos: 1,
+		MaxImageBytes: 5 * mb, MaxVideoBytes: 512 * mb,
+		MinVideoSeconds: 0.5, MaxVideoSeconds: 140,
+	},
+	"mastodon": {
+		MaxChars:    500,
+		AllowImages: true, AllowVideos: true,
+		MaxMedia: 4, MaxImages: 4, MaxVideos: 1,
+		MaxImageBytes: 16 * mb, MaxVideoBytes: 99 * mb,
+	},
+	"telegram": {
+		MaxChars: 4096, MaxCaptionChars: 1024,
+		AllowImages: true, AllowVideos: true, AllowMixed: true,
+		MaxImageBytes: 5 * mb, MaxVideoBytes: 20 * mb, // Bot API limits for files sent by URL
+	},
+	"instagram": {
+		MaxChars: 2200, MaxHashtags: 30, MaxCommentChars: 2200,
+		RequiresMedia: true, AllowImages: true, AllowVideos: true, AllowMixed: true,
+		MaxMedia:      10,
+		MaxImageBytes: 8 * mb, MaxVideoBytes: 300 * mb,
+		MinImageAspect: 0.8, MaxImageAspect: 1.91,
+		MinVideoSeconds: 3, MaxVideoSeconds: 900,
+	},
+	"facebook": {
+		MaxChars: 63206, MaxCommentChars: 8000,
+		AllowImages: true, AllowVideos: true, AllowMixed: true,
+		MaxImageBytes: 10 * mb, MaxVideoBytes: 10 * gb,
+		MaxVideoSeconds: 4 * 60 * 60,
+	},
+	"youtube": {
+		MaxChars:      5000, // the content becomes the video description
+		RequiresVideo: true, AllowVideos: true, IgnoresExtra: true,
+		MaxVideos:     1,
+		MaxVideoBytes: 256 * gb,
+	},
+}
+
+// RulesFor returns the publishing rules of a platform
+func RulesFor(platform string) (PlatformRules, bool) {
+	rules, ok := platformRules[platform]
+	return rules, ok
+}
+
+// MediaInfo is what validation knows about one media URL. Metadata comes from the media
+// library; URLs that were not uploaded through it are typed by their URL only.
+type MediaInfo struct {
+	URL      string
+	IsVideo  bool
+	Known    bool // found in the media table
+	MimeType string
+	Size     int64
+	Width    int
+	Height   int
+	Duration float64
+}
+
+// LoadMediaInfo looks up media metadata for the given URLs, preserving their order
+func LoadMediaInfo(db *sql.DB, mediaURLs []string) ([]MediaInfo, error) {
+	infos := make([]MediaInfo, len(mediaURLs))
+	for i, u := range mediaURLs {
+		infos[i] = MediaInfo{URL: u, IsVideo: isVideoMediaURL(u)}
+	}
+	if len(mediaURLs) == 0 || db == nil {
+		return infos, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT ON (file_url) file_url, file_type, mime_type, file_size, width, height, duration
+		FROM media
+		WHERE file_url = ANY($1)
+		ORDER BY file_url, created_at DESC
+	`, pq.Array(mediaURLs))
+	if err != nil {
+		return infos, fmt.Errorf("failed to load media metadata: %v", err)
+	}
+	defer rows.Close()
+
+	known := map[string]MediaInfo{}
+	for rows.Next() {
+		var m MediaInfo
+		var fileType string
+		var width, height sql.NullInt64
+		var duration sql.NullFloat64
+		if err := rows.Scan(&m.URL, &fileType, &m.MimeType, &m.Size, &width, &height, &duration); err != nil {
+			return infos, fmt.Errorf("failed to scan media metadata: %v", err)
+		}
+		m.Known = true
+		m.IsVideo = fileType == "video" || strings.HasPrefix(m.MimeType, "video/")
+		m.Width, m.Height = int(width.Int64), int(height.Int64)
+		m.Duration = duration.Float64
+		known[m.URL] = m
+	}
+	if err := rows.Err(); err != nil {
+		return infos, err
+	}
+
+	for i, u := range mediaURLs {
+		if m, ok := known[u]; ok {
+			infos[i] = m
+		}
+	}
+	return infos, nil
+}
+
+// ValidationInput is the post being checked
+type ValidationInput struct {
+	Content   string
+	MediaURLs []string
+	Platforms []string
+	Targets   map[string]interface{}
+	Thread    []models.ThreadPart
+}
+
+// ValidateScheduledPost loads media metadata and checks the post against the rules of each
+// platform. When the metadata can't be loaded, media is checked by URL only.
+func ValidateScheduledPost(db *sql.DB, in ValidationInput) models.ValidationResult {
+	urls := append([]string{}, in.MediaURLs...)
+	for _, part := range in.Thread {
+		urls = append(urls, part.MediaURLs...)
+	}
+	media, err := LoadMediaInfo(db, urls)
+	if err != nil {
+		log.Printf("Validation: %v", err)
+	}
+	return ValidatePost(in, media)
+}
+
+// ValidatePost checks the variant each platform (and each overridden account) publishes against
+// the platform's rules. It never fails: every problem is reported as an error (the publisher
+// would reject the post) or a warning (the post goes out, but not quite as written).
+func ValidatePost(in ValidationInput, media []MediaInfo) models.ValidationResult {
+	byURL := make(map[string]MediaInfo, len(media))
+	for _, m := range media {
+		byURL[m.URL] = m
+	}
+
+	result := models.ValidationResult{Valid: true, Targets: []models.TargetValidation{}}
+	add := func(target models.TargetValidation) {
+		result.Valid = result.Valid && target.Valid
+		result.Targets = append(result.Targets, target)
+	}
+	for _, platform := range in.Platforms {
+		target, err := parseTarget(in.Targets[platform], true)
+		if err == nil {
+			err = checkTarget(platform, target, in.MediaURLs)
+		}
+		if err != nil {
+			// Report the broken structure, then check what a lenient read would publish
+			target, _ = parseTarget(in.Targets[platform], false)
+		}
+
+		variant := ResolveVariant(in.Content, in.MediaURLs, target, platform, nil)
+		validation := validateTarget(platform, variant, variantMedia(variant, byURL))
+		if len(in.Thread) > 0 {
+			validateThread(&validation, in.Thread, byURL)
+		}
+		if err != nil {
+			validation.Errors = append(validation.Errors, models.ValidationIssue{
+				Code: "invalid_targets", Field: "targets." + platform, Message: err.Error(),
+			})
+			validation.Valid = false
+		}
+		add(validation)
+
+		accountIDs := make([]string, 0, len(target.Accounts))
+		for id := range target.Accounts {
+			accountIDs = append(accountIDs, id)
+		}
+		sort.Strings(accountIDs)
+		for _, id := range accountIDs {
+			id := id
+			variant := ResolveVariant(in.Content, in.MediaURLs, target, platform, &id)
+			add(validateTarget(platform, variant, variantMedia(variant, byURL)))
+		}
+	}
+	return result
+}
+
+// validateThread checks every follow-up part of a multi-part post against the platform's rules.
+// Platforms without threads only publish the main post, which is worth a warning.
+func validateThread(target *models.TargetValidation, thread []models.ThreadPart, byURL map[string]MediaInfo) {
+	rules, ok := RulesFor(target.Platform)
+	if !ok {
+		return
+	}
+	if !SupportsThreads(target.Platform) {
+		target.Warnings = append(target.Warnings, models.ValidationIssue{
+			Code:    "thread_unsupported",
+			Field:   "thread",
+			Message: fmt.Sprintf("%s has no threads; only the main post will be published", target.Platform),
+		})
+		return
+	}
+
+	for i, part := range thread {
+		variant := models.PostVariant{Platform: target.Platform, Content: part.Content, MediaURLs: part.MediaURLs}
+		v := &targetValidator{}
+		v.checkText(target.Platform, rules, part.Content, variantMedia(variant, byURL))
+		v.checkMedia(target.Platform, rules, variantMedia(variant, byURL))
+		prefix := fmt.Sprintf("thread[%d].", i)
+		for _, issue := range v.Errors {
+			issue.Field = prefix + issue.Field
+			target.Errors = append(target.Errors, issue)
+		}
+		for _, issue := range v.Warnings {
+			issue.Field = prefix + issue.Field
+			target.Warnings = append(target.Warnings, issue)
+		}
+	}
+	target.Valid = len(target.Errors) == 0
+}
+
+// variantMedia returns the metadata of the media a variant publishes
+func variantMedia(variant models.PostVariant, byURL map[string]MediaInfo) []MediaInfo {
+	media := make([]MediaInfo, 0, len(variant.MediaURLs))
+	for _, u := range variant.MediaURLs {
+		m, ok := byURL[u]
+		if !ok {
+			m = MediaInfo{URL: u, IsVideo: isVideoMediaURL(u)}
+		}
+		media = append(media, m)
+	}
+	return media
+}
+
+// targetValidator collects the issues of one target
+type targetValidator struct {
+	models.TargetValidation
+}
+
+func (v *targetValidator) fail(code, field, message string, limit, actual *int64) {
+	v.Errors = append(v.Errors, models.ValidationIssue{Code: code, Field: field, Message: message, Limit: limit, Actual: actual})
+}
+
+func (v *targetValidator) warn(code, field, message string, limit, actual *int64) {
+	v.Warnings = append(v.Warnings, models.ValidationIssue{Code: code, Field: field, Message: message, Limit: limit, Actual: actual})
+}
+
+func int64p(v int64) *int64 { return &v }
+
+func validateTarget(platform string, variant models.PostVariant, media []MediaInfo) models.TargetValidation {
+	v := &targetValidator{models.TargetValidation{
+		Platform:  platform,
+		AccountID: variant.AccountID,
+		Errors:    []models.ValidationIssue{},
+		Warnings:  []models.ValidationIssue{},
+	}}
+	rules, ok := RulesFor(platform)
+	if !ok || !IsSupportedPlatform(platform) {
+		v.fail("unsupported_platform", "platforms", "Unsupported platform: "+platform, nil, nil)
+		return v.finish()
+	}
+
+	text := variant.Content
+	if platform == "youtube" && variant.Description != "" {
+		text = variant.Description
+	}
+	v.checkText(platform, rules, text, media)
+	v.checkMedia(platform, rules, media)
+	if platform == "youtube" {
+		v.checkYouTubeMeta(variant)
+	}
+	if variant.FirstComment != "" {
+		v.checkFirstComment(platform, rules, variant.FirstComment)
+	}
+	return v.finish()
+}
+
+func (v *targetValidator) finish() models.TargetValidation {
+	v.Valid = len(v.Errors) == 0
+	return v.TargetValidation
+}
+
+var hashtagPattern = regexp.MustCompile(`(?:^|\s)#[\p{L}\p{N}_]+`)
+
+func (v *targetValidator) checkText(platform string, rules PlatformRules, text string, media []MediaInfo) {
+	if strings.TrimSpace(text) == "" && len(media) == 0 && !rules.RequiresVideo {
+		v.fail("empty_post", "content", "Post needs text or media", nil, nil)
+	}
+
+	if rules.WeightedLength {
+		v.CharacterCount = TwitterWeightedLength(text)
+	} else {
+		v.CharacterCount = utf8.RuneCountInString(text)
+	}
+	v.CharacterLimit = rules.MaxChars
+	if rules.MaxCaptionChars > 0 && len(media) > 0 {
+		v.CharacterLimit = rules.MaxCaptionChars
+	}
+	if v.CharacterLimit > 0 && v.CharacterCount > v.CharacterLimit {
+		what := "Text"
+		if v.CharacterLimit == rules.MaxCaptionChars {
+			what = "Caption"
+		}
+		v.fail("too_long", "content",
+			fmt.Sprintf("%s is %d characters; %s allows %d", what, v.CharacterCount, platform, v.CharacterLimit),
+			int64p(int64(v.CharacterLimit)), int64p(int64(v.CharacterCount)))
+	}
+
+	if rules.MaxHashtags > 0 {
+		if n := len(hashtagPattern.FindAllString(text, -1)); n > rules.MaxHashtags {
+			v.fail("too_many_hashtags", "content",
+				fmt.Sprintf("%s allows at most %d hashtags, found %d", platform, rules.MaxHashtags, n),
+				int64p(int64(rules.MaxHashtags)), int64p(int64(n)))
+		}
+	}
+}
+
+func (v *targetValidator) checkMedia(platform string, rules PlatformRules, media []MediaInfo) {
+	// Surplus media on platforms that skip it is only worth a warning
+	limitIssue := v.fail
+	if rules.IgnoresExtra {
+		limitIssue = v.warn
+	}
+
+	var images, videos int
+	for _, m := range media {
+		if m.IsVideo {
+			videos++
+		} else {
+			images++
+		}
+	}
+
+	if rules.RequiresMedia && len(media) == 0 {
+		v.fail("media_required", "media", platform+" posts need at least one image or video", nil, nil)
+	}
+	if rules.RequiresVideo && videos == 0 {
+		v.fail("video_required", "media", platform+" posts need a video", nil, nil)
+	}
+	if images > 0 && !rules.AllowImages {
+		limitIssue("unsupported_media", "media", platform+" does not publish images; they will not be posted", nil, nil)
+	}
+	if videos > 0 && !rules.AllowVideos {
+		limitIssue("unsupported_media", "media", platform+" does not publish videos", nil, nil)
+	}
+	if images > 0 && videos > 0 && rules.AllowImages && rules.AllowVideos && !rules.AllowMixed {
+		v.fail("mixed_media", "media", platform+" cannot combine images and videos in one post", nil, nil)
+	}
+	if rules.MaxMedia > 0 && len(media) > rules.MaxMedia {
+		limitIssue("too_many_media", "media",
+			fmt.Sprintf("%s allows at most %d media items, got %d", platform, rules.MaxMedia, len(media)),
+			int64p(int64(rules.MaxMedia)), int64p(int64(len(media))))
+	}
+	if rules.MaxImages > 0 && images > rules.MaxImages {
+		limitIssue("too_many_images", "media",
+			fmt.Sprintf("%s allows at most %d images, got %d", platform, rules.MaxImages, images),
+			int64p(int64(rules.MaxImages)), int64p(int64(images)))
+	}
+	if rules.MaxVideos > 0 && videos > rules.MaxVideos {
+		message := fmt.Sprintf("%s allows at most %d video(s), got %d", platform, rules.MaxVideos, videos)
+		if rules.IgnoresExtra {
+			message = fmt.Sprintf("%s only uploads the first video; %d more will be skipped", platform, videos-rules.MaxVideos)
+		}
+		limitIssue("too_many_videos", "media", message, int64p(int64(rules.MaxVideos)), int64p(int64(videos)))
+	}
+
+	// Publisher-specific behaviour the user should know about
+	switch platform {
+	case "telegram":
+		if len(media) > telegramMediaGroupLimit {
+			// Describe the albums the publisher will actually send
+			urls := make([]string, len(media))
+			for i, m := range media {
+				urls[i] = m.URL
+			}
+			albums := telegramAlbums(urls)
+			sizes := make([]string, len(albums))
+			for i, album := range albums {
+				sizes[i] = strconv.Itoa(len(album))
+			}
+			v.warn("split_media_group", "media",
+				fmt.Sprintf("Telegram albums hold 2 to %d items; this post will be sent as %d albums (%s items) with the caption on the first",
+					telegramMediaGroupLimit, len(albums), strings.Join(sizes, ", ")),
+				int64p(telegramMediaGroupLimit), int64p(int64(len(media))))
+		}
+	case "facebook":
+		if videos > 0 && (images > 0 || videos > 1) {
+			v.warn("split_post", "media", "Facebook publishes each video as its own post, separate from the images", nil, nil)
+		}
+	}
+
+	unknown := 0
+	for i, m := range media {
+		field := fmt.Sprintf("media[%d]", i)
+		if (m.IsVideo && !rules.AllowVideos) || (!m.IsVideo && !rules.AllowImages) {
+			continue
+		}
+		if !m.Known {
+			unknown++
+			continue
+		}
+		v.checkMediaItem(platform, rules, field, m)
+	}
+	if unknown > 0 && (rules.MaxImageBytes > 0 || rules.MaxVideoBytes > 0) {
+		v.warn("unverified_media", "media",
+			fmt.Sprintf("%d media item(s) are not in the media library, so their size, dimensions and duration could not be checked", unknown),
+			nil, nil)
+	}
+}
+
+func (v *targetValidator) checkMediaItem(platform string, rules PlatformRules, field string, m MediaInfo) {
+	maxBytes, kind := rules.MaxImageBytes, "image"
+	if m.IsVideo {
+		maxBytes, kind = rules.MaxVideoBytes, "video"
+	}
+	if maxBytes > 0 && m.Size > maxBytes {
+		v.fail("file_too_large", field,
+			fmt.Sprintf("%s %s is %s; the limit is %s", platform, kind, formatBytes(m.Size), formatBytes(maxBytes)),
+			int64p(maxBytes), int64p(m.Size))
+	}
+
+	if !m.IsVideo && m.Width > 0 && m.Height > 0 && (rules.MinImageAspect > 0 || rules.MaxImageAspect > 0) {
+		aspect := float64(m.Width) / float64(m.Height)
+		if (rules.MinImageAspect > 0 && aspect < rules.MinImageAspect) || (rules.MaxImageAspect > 0 && aspect > rules.MaxImageAspect) {
+			v.fail("aspect_ratio", field,
+				fmt.Sprintf("%s images must have an aspect ratio between %.2f and %.2f; this one is %dx%d (%.2f)",
+					platform, rules.MinImageAspect, rules.MaxImageAspect, m.Width, m.Height, aspect),
+				nil, nil)
+		}
+	}
+
+	if m.IsVideo && m.Duration > 0 {
+		if rules.MaxVideoSeconds > 0 && m.Duration > rules.MaxVideoSeconds {
+			v.fail("video_too_long", field,
+				fmt.Sprintf("%s videos can be at most %.0f seconds; this one is %.0f", platform, rules.MaxVideoSeconds, m.Duration),
+				int64p(int64(rules.MaxVideoSeconds)), int64p(int64(m.Duration)))
+		}
+		if rules.MinVideoSeconds > 0 && m.Duration < rules.MinVideoSeconds {
+			v.fail("video_too_short", field,
+				fmt.Sprintf("%s videos must be at least %.1f seconds; this one is %.1f", platform, rules.MinVideoSeconds, m.Duration),
+				nil, nil)
+		}
+	}
+}
+
+// checkFirstComment checks a target's first_comment; platforms that can't comment skip it
+func (v *targetValidator) checkFirstComment(platform string, rules PlatformRules, comment string) {
+	if !SupportsComments(platform) {
+		v.warn("first_comment_unsupported", "first_comment",
+			fmt.Sprintf("%s does not support first comments; it will not be posted", platform), nil, nil)
+		return
+	}
+	if n := utf8.RuneCountInString(comment); rules.MaxCommentChars > 0 && n > rules.MaxCommentChars {
+		v.fail("too_long", "first_comment",
+			fmt.Sprintf("First comment is %d characters; %s allows %d", n, platform, rules.MaxCommentChars),
+			int64p(int64(rules.MaxCommentChars)), int64p(int64(n)))
+	}
+	if rules.MaxHashtags > 0 {
+		if n := len(hashtagPattern.FindAllString(comment, -1)); n > rules.MaxHashtags {
+			v.fail("too_many_hashtags", "first_comment",
+				fmt.Sprintf("%s allows at most %d hashtags per comment, found %d", platform, rules.MaxHashtags, n),
+				int64p(int64(rules.MaxHashtags)), int64p(int64(n)))
+		}
+	}
+}
+
+// checkYouTubeMeta checks the title the YouTube publisher will use (the variant's title, else
+// the content) and the description, which YouTube rejects when it contains angle brackets
+func (v *targetValidator) checkYouTubeMeta(variant models.PostVariant) {
+	title, field := strings.TrimSpace(variant.Title), "title"
+	if title == "" {
+		title, field = strings.TrimSpace(variant.Content), "content"
+	}
+	if title == "" {
+		v.warn("missing_title", "title", "YouTube videos need a title; set a title or add content", nil, nil)
+	} else if n := utf8.RuneCountInString(title); n > youtubeMaxTitle {
+		v.warn("title_truncated", field,
+			fmt.Sprintf("YouTube titles are limited to %d characters; the title will be cut from %d", youtubeMaxTitle, n),
+			int64p(youtubeMaxTitle), int64p(int64(n)))
+	}
+	description, field := variant.Description, "description"
+	if description == "" {
+		description, field = variant.Content, "content"
+	}
+	if strings.ContainsAny(description, "<>") {
+		v.fail("invalid_characters", field, "YouTube descriptions cannot contain < or >", nil, nil)
+	}
+}
+
+var tweetURLPattern = regexp.MustCompile(`https?://\S+`)
+
+// twitterURLLength is the length every link counts for once t.co shortens it
+const twitterURLLength = 23
+
+// TwitterWeightedLength approximates twitter-text's weighted length: links count as 23,
+// Latin and common punctuation ranges count 1 and everything else (CJK, emoji) counts 2.
+// Zero-width joiners and variation selectors are free so an emoji sequence counts once.
+func TwitterWeightedLength(text string) int {
+	urls := len(tweetURLPattern.FindAllStringIndex(text, -1))
+	return urls*twitterURLLength + twitterWeightedText(tweetURLPattern.ReplaceAllString(text, ""))
+}
+
+func twitterWeightedText(text string) int {
+	length := 0
+	for _, r := range text {
+		switch {
+		case r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F):
+		case r <= 0x10FF, r >= 0x2000 && r <= 0x200D, r >= 0x2010 && r <= 0x201F, r >= 0x2032 && r <= 0x2037:
+			length++
+		default:
+			length += 2
+		}
+	}
+	return length
+}
+
+// formatBytes renders a size limit for messages
+func formatBytes(n int64) string {
+	switch {
+	case n >= gb:
+		return fmt.Sprintf("%.1f GB", float64(n)/float64(gb))
+	case n >= mb:
+		return fmt.Sprintf("%.1f MB", float64(n)/float64(mb))
+	case n >= kb:
+		return fmt.Sprintf("%.0f KB", float64(n)/float64(kb))
+	}
+	return fmt.Sprintf("%d B", n)
+}